@@ -0,0 +1,141 @@
+// Command import sends a CSV or JSON file of events to the /import endpoint
+// of a running calendar server and prints the report.
+//
+//	go run ./cmd/import -mapping mapping.json -dry-run events.csv
+package main
+
+import (
+	"bytes"
+	"calendar/internal/helpers"
+	"calendar/internal/importer"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type response struct {
+	Result string          `json:"result"`
+	Report importer.Report `json:"report"`
+}
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "Base URL of the calendar server")
+	mappingPath := flag.String("mapping", "", "Path of a JSON mapping of event fields to columns or keys")
+	format := flag.String("format", "", "Input format, csv or json; guessed from the file name when empty")
+	dryRun := flag.Bool("dry-run", false, "Only report what would be created or updated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	result, err := run(*server, flag.Arg(0), *mappingPath, *format, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		os.Exit(1)
+	}
+	printReport(os.Stdout, result)
+	if result.Report.Invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(server, path, mappingPath, format string, dryRun bool) (response, error) {
+	var mapping []byte
+	if mappingPath != "" {
+		data, err := os.ReadFile(mappingPath)
+		if err != nil {
+			return response{}, err
+		}
+		// Check the mapping here so a typo is reported before the upload.
+		if _, err := importer.ParseMapping(data); err != nil {
+			return response{}, err
+		}
+		mapping = data
+	}
+
+	body, contentType, err := form(path, mapping, format, dryRun)
+	if err != nil {
+		return response{}, err
+	}
+	resp, err := http.Post(strings.TrimRight(server, "/")+"/import", contentType, body)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr helpers.APIError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+			return response{}, fmt.Errorf("server responded %s", resp.Status)
+		}
+		return response{}, fmt.Errorf("server responded %s: %s", resp.Status, apiErr.Message)
+	}
+	var result response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return response{}, fmt.Errorf("invalid response: %w", err)
+	}
+	return result, nil
+}
+
+func form(path string, mapping []byte, format string, dryRun bool) (io.Reader, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, "", err
+	}
+	if mapping != nil {
+		writer.WriteField("mapping", string(mapping))
+	}
+	if format != "" {
+		writer.WriteField("format", format)
+	}
+	writer.WriteField("dry_run", strconv.FormatBool(dryRun))
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, writer.FormDataContentType(), nil
+}
+
+func printReport(w io.Writer, result response) {
+	report := result.Report
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(w, "%s: %d created, %d updated, %d unchanged, %d invalid\n", verb, report.Created, report.Updated, report.Unchanged, report.Invalid)
+	if report.Invalid == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tFIELD\tERROR")
+	for _, row := range report.Rows {
+		if row.Action == importer.Invalid && row.Error != nil {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", row.Row, row.Error.Field, row.Error.Message)
+		}
+	}
+	tw.Flush()
+}
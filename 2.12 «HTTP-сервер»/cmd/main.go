@@ -6,6 +6,6 @@ import (
 )
 
 func main() {
-	port := config.LoadConfig()
-	app.StartServer(port)
+	cfg := config.LoadConfig()
+	app.StartServer(cfg)
 }
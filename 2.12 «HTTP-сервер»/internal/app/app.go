@@ -1,41 +1,537 @@
 package app
 
 import (
+	"calendar/internal/archive"
+	"calendar/internal/attachment"
+	"calendar/internal/booking"
+	"calendar/internal/config"
+	"calendar/internal/digest"
 	"calendar/internal/handler"
+	"calendar/internal/holiday"
 	"calendar/internal/middleware"
+	"calendar/internal/openapi"
+	"calendar/internal/period"
+	"calendar/internal/preference"
+	"calendar/internal/replication"
 	"calendar/internal/service"
+	"calendar/internal/snapshot"
+	"calendar/internal/tlsconfig"
+	"calendar/internal/webhook"
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func StartServer(port string) {
+type App struct {
+	Storage     service.Storage
+	Webhooks    *webhook.Registry
+	Dispatcher  *webhook.Dispatcher
+	Bookings    *booking.Registry
+	Holidays    *holiday.Set
+	Preferences *preference.Registry
+	Attachments *attachment.Registry
+	Digests     *digest.Renderer
+	Archive     *archive.Archive
+	Retention   *archive.Job
+	Primary     *replication.Primary
+	Replica     *replication.Replica
+	WeekStart   time.Weekday
+	Handler     http.Handler
+
+	routes []string
+}
+
+func New(cfg config.Config, storage service.Storage) (*App, error) {
+	weekStart, err := period.ParseWeekStart(cfg.WeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := middleware.ParseUserTokens(cfg.UserTokens)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var primary *replication.Primary
+	var replica *replication.Replica
+	if cfg.ReplicationRole != "" && cfg.ReplicationSecret == "" {
+		return nil, errors.New("invalid configuration: replication needs a secret shared by the primary and its replicas")
+	}
+	switch cfg.ReplicationRole {
+	case "":
+	case "primary":
+		primary = replication.NewPrimary(storage, cfg.ReplicationLogSize)
+		storage = primary
+	case "replica":
+		if cfg.ReplicationPrimary == "" {
+			return nil, errors.New("invalid configuration: a replica needs the URL of its primary")
+		}
+		replica = replication.NewReplica(cfg.ReplicationPrimary, cfg.ReplicationSecret, storage)
+	default:
+		return nil, fmt.Errorf("invalid configuration: unknown replication role %q", cfg.ReplicationRole)
+	}
+
+	holidays, err := holiday.LoadDir(cfg.HolidaysDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holiday calendars: %w", err)
+	}
+
+	webhooks := webhook.NewRegistry()
+	dispatcher := webhook.NewDispatcher(webhooks, 5, time.Second)
+	dispatcher.Visible = func(user string, event service.Event) bool {
+		if event.CalendarID == "" {
+			return true
+		}
+		calendar, ok := storage.GetCalendar(event.CalendarID)
+		return ok && calendar.VisibleTo(user)
+	}
+	dispatcher.Start()
+	storage.Subscribe(dispatcher.HandleChange)
+
+	bookings := booking.NewRegistry()
+	preferences := preference.NewRegistry()
+
+	var blobs attachment.BlobStore = attachment.NewMemoryStore()
+	if cfg.AttachmentsDir != "" {
+		// A relative directory would depend on where the server starts, and
+		// two instances started side by side would share it.
+		if !filepath.IsAbs(cfg.AttachmentsDir) {
+			return nil, errors.New("invalid configuration: the attachments directory must be an absolute path")
+		}
+		if blobs, err = attachment.NewDirStore(cfg.AttachmentsDir); err != nil {
+			return nil, fmt.Errorf("failed to open attachments directory: %w", err)
+		}
+	}
+	attachments, err := attachment.NewRegistry(blobs, cfg.AttachmentLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+	// A replica only mirrors the primary, which owns the attachments: the
+	// deletions it applies, and the ones a checkpoint restore implies, must
+	// not remove blobs from a directory it may share with the primary.
+	if replica == nil {
+		storage.Subscribe(attachments.HandleChange)
+	}
+
+	var events *archive.Archive
+	if cfg.ArchiveDir != "" {
+		if events, err = archive.Open(cfg.ArchiveDir); err != nil {
+			return nil, fmt.Errorf("failed to open archive directory: %w", err)
+		}
+	}
+	policy := archive.Policy{ArchiveAfterMonths: cfg.ArchiveAfterMonths, PurgeAfterYears: cfg.PurgeAfterYears}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if policy.ArchiveAfterMonths > 0 && events == nil {
+		return nil, errors.New("invalid configuration: archiving needs an archive directory")
+	}
+	retention := &archive.Job{
+		Storage: storage,
+		Archive: events,
+		Policy:  policy,
+		OnPurge: func(purged []service.Event) {
+			for _, event := range purged {
+				if err := attachments.DeleteForEvent(event.ID); err != nil {
+					log.Printf("Failed to delete attachments of purged event %s: %v", event.ID, err)
+				}
+			}
+		},
+	}
+
+	digests, err := digest.NewRenderer(cfg.DigestTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load digest templates: %w", err)
+	}
+
+	a := &App{
+		Storage:     storage,
+		Webhooks:    webhooks,
+		Dispatcher:  dispatcher,
+		Bookings:    bookings,
+		Holidays:    holidays,
+		Preferences: preferences,
+		Attachments: attachments,
+		Digests:     digests,
+		Archive:     events,
+		Retention:   retention,
+		Primary:     primary,
+		Replica:     replica,
+		WeekStart:   weekStart,
+	}
+	registries := a.Registries()
 	mux := http.NewServeMux()
-	storage := service.NewInMemoryStorage()
 
-	mux.HandleFunc("/create_event", func(w http.ResponseWriter, r *http.Request) {
+	a.handle(mux, "/create_event", func(w http.ResponseWriter, r *http.Request) {
 		handler.CreateEventHandler(w, r, storage)
 	})
-	mux.HandleFunc("/update_event", func(w http.ResponseWriter, r *http.Request) {
+	a.handle(mux, "/quick_add", func(w http.ResponseWriter, r *http.Request) {
+		handler.QuickAddHandler(w, r, storage)
+	})
+	a.handle(mux, "/import", func(w http.ResponseWriter, r *http.Request) {
+		handler.ImportHandler(w, r, storage, events)
+	})
+	a.handle(mux, "/update_event", func(w http.ResponseWriter, r *http.Request) {
 		handler.UpdateEventHandler(w, r, storage)
 	})
-	mux.HandleFunc("/delete_event", func(w http.ResponseWriter, r *http.Request) {
+	a.handle(mux, "/delete_event", func(w http.ResponseWriter, r *http.Request) {
 		handler.DeleteEventHandler(w, r, storage)
 	})
-	mux.HandleFunc("/events_for_day", func(w http.ResponseWriter, r *http.Request) {
-		handler.EventsForDayHandler(w, r, storage)
+	a.handle(mux, "/events_for_day", func(w http.ResponseWriter, r *http.Request) {
+		handler.EventsForDayHandler(w, r, storage, holidays, weekStart, preferences)
 	})
-	mux.HandleFunc("/events_for_week", func(w http.ResponseWriter, r *http.Request) {
-		handler.EventsForWeekHandler(w, r, storage)
+	a.handle(mux, "/events_for_week", func(w http.ResponseWriter, r *http.Request) {
+		handler.EventsForWeekHandler(w, r, storage, holidays, weekStart, preferences)
 	})
-	mux.HandleFunc("/events_for_month", func(w http.ResponseWriter, r *http.Request) {
-		handler.EventsForMonthHandler(w, r, storage)
+	a.handle(mux, "/events_for_month", func(w http.ResponseWriter, r *http.Request) {
+		handler.EventsForMonthHandler(w, r, storage, holidays, weekStart, preferences)
 	})
-	mux.HandleFunc("/get_events", func(w http.ResponseWriter, r *http.Request) {
+	a.handle(mux, "/get_events", func(w http.ResponseWriter, r *http.Request) {
 		handler.GetEventsHandler(w, r, storage)
 	})
+	a.handle(mux, "/events_for_range", func(w http.ResponseWriter, r *http.Request) {
+		handler.EventsForRangeHandler(w, r, storage, events, holidays, weekStart, preferences)
+	})
+	a.handle(mux, "/create_calendar", func(w http.ResponseWriter, r *http.Request) {
+		handler.CreateCalendarHandler(w, r, storage)
+	})
+	a.handle(mux, "/update_calendar", func(w http.ResponseWriter, r *http.Request) {
+		handler.UpdateCalendarHandler(w, r, storage)
+	})
+	a.handle(mux, "/delete_calendar", func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteCalendarHandler(w, r, storage)
+	})
+	a.handle(mux, "/calendars", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetCalendarsHandler(w, r, storage)
+	})
+
+	a.handle(mux, "/user_preferences", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetPreferencesHandler(w, r, preferences)
+	})
+	a.handle(mux, "/update_user_preferences", func(w http.ResponseWriter, r *http.Request) {
+		handler.UpdatePreferencesHandler(w, r, preferences)
+	})
+
+	a.handle(mux, "/upload_attachment", func(w http.ResponseWriter, r *http.Request) {
+		handler.UploadAttachmentHandler(w, r, attachments, storage)
+	})
+	a.handle(mux, "/attachments", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetAttachmentsHandler(w, r, attachments, storage, events)
+	})
+	a.handle(mux, "/download_attachment", func(w http.ResponseWriter, r *http.Request) {
+		handler.DownloadAttachmentHandler(w, r, attachments, storage, events)
+	})
+	a.handle(mux, "/delete_attachment", func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteAttachmentHandler(w, r, attachments, storage, events)
+	})
+
+	a.handle(mux, "/digest_preview", func(w http.ResponseWriter, r *http.Request) {
+		handler.DigestPreviewHandler(w, r, digests, storage, holidays, weekStart, preferences)
+	})
+
+	a.handle(mux, "/holiday_calendars", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetHolidayCalendarsHandler(w, r, holidays)
+	})
+	a.handle(mux, "/holidays", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetHolidaysHandler(w, r, holidays)
+	})
+
+	a.handle(mux, "/create_booking_page", func(w http.ResponseWriter, r *http.Request) {
+		handler.CreateBookingPageHandler(w, r, bookings, storage, holidays)
+	})
+	a.handle(mux, "/delete_booking_page", func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteBookingPageHandler(w, r, bookings)
+	})
+	a.handle(mux, "/booking_pages", func(w http.ResponseWriter, r *http.Request) {
+		handler.GetBookingPagesHandler(w, r, bookings)
+	})
+	a.handle(mux, "/booking_slots", func(w http.ResponseWriter, r *http.Request) {
+		handler.BookingSlotsHandler(w, r, bookings, storage, holidays)
+	})
+	a.handle(mux, "/book_slot", func(w http.ResponseWriter, r *http.Request) {
+		handler.BookSlotHandler(w, r, bookings, storage, holidays)
+	})
+
+	admin := func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+		return middleware.TokenMiddleware(handlerFunc, cfg.AdminToken).ServeHTTP
+	}
+	// Webhooks send requests to any URL they are given, so only the admin
+	// may register them.
+	a.handle(mux, "/create_webhook", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.CreateWebhookHandler(w, r, webhooks)
+	}))
+	a.handle(mux, "/delete_webhook", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteWebhookHandler(w, r, webhooks, dispatcher)
+	}))
+	a.handle(mux, "/webhooks", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.GetWebhooksHandler(w, r, webhooks)
+	}))
+	a.handle(mux, "/webhook_dead_letters", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.WebhookDeadLettersHandler(w, r, dispatcher)
+	}))
+
+	a.handle(mux, "/admin/snapshot", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.SnapshotHandler(w, r, storage, registries)
+	}))
+	a.handle(mux, "/admin/restore", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.RestoreHandler(w, r, storage, registries)
+	}))
+
+	a.handle(mux, "/admin/retention", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.RetentionHandler(w, r, retention)
+	}))
+	a.handle(mux, "/admin/archive", admin(func(w http.ResponseWriter, r *http.Request) {
+		handler.ArchiveHandler(w, r, events)
+	}))
+
+	replicas := func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+		return middleware.TokenMiddleware(handlerFunc, cfg.ReplicationSecret).ServeHTTP
+	}
+	a.handle(mux, "/replication/changes", replicas(func(w http.ResponseWriter, r *http.Request) {
+		handler.ReplicationChangesHandler(w, r, primary)
+	}))
+	a.handle(mux, "/replication/snapshot", replicas(func(w http.ResponseWriter, r *http.Request) {
+		handler.ReplicationSnapshotHandler(w, r, primary)
+	}))
+	a.handle(mux, "/replication/status", func(w http.ResponseWriter, r *http.Request) {
+		handler.ReplicationStatusHandler(w, r, primary, replica)
+	})
+
+	a.handle(mux, "/openapi.json", handler.OpenAPIHandler)
+	a.handle(mux, "/docs", func(w http.ResponseWriter, r *http.Request) {
+		handler.DocsHandler(w, r, spec)
+	})
+
+	var h http.Handler = middleware.IdentityMiddleware(spec.Middleware(mux), users)
+	if replica != nil {
+		h = middleware.ReadOnlyMiddleware(h, cfg.ReplicationPrimary)
+	}
+	a.Handler = middleware.LoggingMiddleware(h)
+	return a, nil
+}
+
+func (a *App) handle(mux *http.ServeMux, pattern string, handlerFunc http.HandlerFunc) {
+	a.routes = append(a.routes, pattern)
+	mux.HandleFunc(pattern, handlerFunc)
+}
+
+func (a *App) Routes() []string {
+	return a.routes
+}
+
+// Registries returns the state snapshots save beside the storage.
+func (a *App) Registries() snapshot.Registries {
+	return snapshot.Registries{
+		Bookings:    a.Bookings,
+		Preferences: a.Preferences,
+		Webhooks:    a.Webhooks,
+		Dispatcher:  a.Dispatcher,
+	}
+}
+
+func (a *App) Close() {
+	a.Dispatcher.Stop()
+}
+
+func StartServer(cfg config.Config) {
+	storage := service.NewInMemoryStorage()
+
+	// The storage is filled before New subscribes webhooks to it, so that
+	// restored events are not delivered as new ones.
+	var restored *snapshot.Snapshot
+	if cfg.SnapshotPath != "" && cfg.RestoreOnStart {
+		snap, err := snapshot.ReadFile(cfg.SnapshotPath)
+		switch {
+		case err == nil:
+			snap.RestoreStorage(storage)
+			restored = &snap
+		case errors.Is(err, os.ErrNotExist):
+			log.Printf("Snapshot %s not found, starting empty", cfg.SnapshotPath)
+		default:
+			log.Fatalf("Failed to restore snapshot %s: %v", cfg.SnapshotPath, err)
+		}
+	}
+
+	a, err := New(cfg, storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	if restored != nil {
+		if err := restored.RestoreRegistries(a.Registries()); err != nil {
+			log.Fatalf("Failed to restore snapshot %s: %v", cfg.SnapshotPath, err)
+		}
+		log.Printf("Restored %d events from snapshot %s (version %d)", len(restored.Events), cfg.SnapshotPath, restored.Version)
+	}
+
+	stop := make(chan struct{})
+	if cfg.SnapshotPath != "" && cfg.SnapshotInterval > 0 {
+		go snapshot.RunPeriodic(cfg.SnapshotPath, cfg.SnapshotInterval, storage, a.Registries(), stop)
+	}
+
+	if cfg.DigestAt != "" {
+		scheduler, err := newDigestScheduler(cfg, a)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go scheduler.Run(stop)
+	}
+
+	if a.Replica != nil {
+		go a.Replica.Run(stop)
+	} else if a.Retention.Policy.Enabled() && cfg.RetentionInterval > 0 {
+		go a.Retention.RunEvery(cfg.RetentionInterval, stop)
+	}
+
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: a.Handler}
+	var redirect *http.Server
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		reloader, err := newReloader(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.TLSConfig = reloader.TLSConfig()
+		if cfg.CertPollInterval > 0 {
+			go reloader.Watch(cfg.CertPollInterval, stop)
+		}
+		go reloadOnHangup(reloader, stop)
+
+		if cfg.RedirectPort != "" {
+			redirect = &http.Server{Addr: ":" + cfg.RedirectPort, Handler: tlsconfig.RedirectHandler(cfg.Port)}
+			go func() {
+				if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Printf("Redirect server stopped: %v", err)
+				}
+			}()
+		}
+	}
+
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		<-sigs
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if redirect != nil {
+			redirect.Shutdown(ctx)
+		}
+		server.Shutdown(ctx)
+	}()
+
+	if server.TLSConfig != nil {
+		fmt.Printf("Starting HTTPS server on port %s", cfg.Port)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		fmt.Printf("Starting server on port %s", cfg.Port)
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Server stopped: %v", err)
+	}
+	close(stop)
+
+	if cfg.SnapshotPath != "" {
+		if err := snapshot.SaveFile(cfg.SnapshotPath, storage, a.Registries()); err != nil {
+			log.Printf("Failed to write final snapshot %s: %v", cfg.SnapshotPath, err)
+		}
+	}
+}
+
+func newDigestScheduler(cfg config.Config, a *App) (*digest.Scheduler, error) {
+	at, err := digest.ParseTimeOfDay(cfg.DigestAt)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := digest.ParseKind(cfg.DigestPeriod)
+	if err != nil {
+		return nil, err
+	}
+	format, err := digest.ParseFormat(cfg.DigestFormat)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(cfg.DigestTimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest time zone: %w", err)
+	}
+	sender, err := digest.NewSender(cfg.DigestSender, cfg.DigestFrom, cfg.DigestTo)
+	if err != nil {
+		return nil, err
+	}
+	var calendarIDs []string
+	for _, id := range strings.Split(cfg.DigestCalendars, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			calendarIDs = append(calendarIDs, id)
+		}
+	}
+	var names []string
+	for _, name := range strings.Split(cfg.DigestOverlays, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	overlays, err := a.Holidays.Resolve(names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest overlays: %w", err)
+	}
+
+	return &digest.Scheduler{
+		Storage:     a.Storage,
+		Renderer:    a.Digests,
+		Sender:      sender,
+		Kind:        kind,
+		Format:      format,
+		At:          at,
+		Location:    location,
+		WeekStart:   a.WeekStart,
+		CalendarIDs: calendarIDs,
+		Overlays:    overlays,
+	}, nil
+}
+
+func newReloader(cfg config.Config) (*tlsconfig.Reloader, error) {
+	clientAuth, err := tlsconfig.ParseClientAuth(cfg.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	return tlsconfig.NewReloader(tlsconfig.Options{
+		CertFile:     cfg.TLSCert,
+		KeyFile:      cfg.TLSKey,
+		ClientCAFile: cfg.ClientCA,
+		ClientAuth:   clientAuth,
+	})
+}
 
-	loggedMux := middleware.LoggingMiddleware(mux)
+func reloadOnHangup(reloader *tlsconfig.Reloader, stop <-chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	defer signal.Stop(sigs)
 
-	fmt.Printf("Starting server on port %s", port)
-	http.ListenAndServe(":"+port, loggedMux)
+	for {
+		select {
+		case <-sigs:
+			if err := reloader.Reload(); err != nil {
+				log.Printf("Failed to reload TLS certificates: %v", err)
+			} else {
+				log.Printf("Reloaded TLS certificates")
+			}
+		case <-stop:
+			return
+		}
+	}
 }
@@ -0,0 +1,1343 @@
+package app
+
+import (
+	"bytes"
+	"calendar/internal/archive"
+	"calendar/internal/attachment"
+	"calendar/internal/config"
+	"calendar/internal/helpers"
+	"calendar/internal/importer"
+	"calendar/internal/openapi"
+	"calendar/internal/preference"
+	"calendar/internal/replication"
+	"calendar/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testAdminToken guards the admin endpoints of test apps; do sends it with
+// every request.
+const testAdminToken = "test-admin-token"
+
+// testUserTokens lets ann, bob and carl sign in to test apps; doAs sends
+// their tokens.
+const testUserTokens = "ann:test-token-ann,bob:test-token-bob,carl:test-token-carl"
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	a, err := New(config.Config{WeekStart: "sunday", AdminToken: testAdminToken, UserTokens: testUserTokens}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+	return a
+}
+
+func do(t *testing.T, a *App, method, target string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	return doAs(t, a, "", method, target, form)
+}
+
+// doAs sends the request on behalf of user, or anonymously with the admin
+// token when user is empty.
+func doAs(t *testing.T, a *App, user, method, target string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	token := testAdminToken
+	if user != "" {
+		token = "test-token-" + user
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("got status %d, want %d, body: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code, field string) {
+	t.Helper()
+
+	expectStatus(t, rec, status)
+	var apiErr helpers.APIError
+	decode(t, rec, &apiErr)
+	if apiErr.Code != code || apiErr.Field != field || apiErr.Message == "" {
+		t.Errorf("got error %+v, want code %q and field %q", apiErr, code, field)
+	}
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	a := newTestApp(t)
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documented []string
+	for path := range spec.Paths {
+		documented = append(documented, path)
+	}
+	routes := append([]string(nil), a.Routes()...)
+	sort.Strings(documented)
+	sort.Strings(routes)
+
+	if strings.Join(routes, " ") != strings.Join(documented, " ") {
+		t.Errorf("registered routes %v do not match documented paths %v", routes, documented)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	a := newTestApp(t)
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range a.Routes() {
+		method := http.MethodDelete
+		if _, ok := spec.Paths[route]["delete"]; ok {
+			t.Fatalf("route %s unexpectedly supports DELETE", route)
+		}
+		rec := do(t, a, method, route, nil)
+		expectError(t, rec, http.StatusMethodNotAllowed, helpers.CodeMethodNotAllowed, "")
+		if rec.Header().Get("Allow") == "" {
+			t.Errorf("%s: missing Allow header", route)
+		}
+	}
+}
+
+func TestEventRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	rec := do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"standup"}, "date": {"2024-10-14"}})
+	expectStatus(t, rec, http.StatusOK)
+	var created struct {
+		Event service.Event `json:"event"`
+	}
+	decode(t, rec, &created)
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"retro"}, "date": {"2024-10-20"}})
+
+	var events []service.Event
+	rec = do(t, a, http.MethodGet, "/get_events", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &events)
+	if len(events) != 2 || events[0].Title != "standup" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	id := events[0].ID
+	if created.Event.ID != id || created.Event.Title != "standup" {
+		t.Errorf("create_event returned %+v, stored %+v", created.Event, events[0])
+	}
+
+	rec = do(t, a, http.MethodGet, "/events_for_day?date=2024-10-14", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &events)
+	if len(events) != 1 || events[0].ID != id {
+		t.Errorf("unexpected events for day: %+v", events)
+	}
+
+	var week service.PeriodEvents
+	rec = do(t, a, http.MethodGet, "/events_for_week?date=2024-10-16", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &week)
+	if week.Start != "2024-10-13" || week.End != "2024-10-19" || week.WeekStart != "sunday" || len(week.Days) != 7 {
+		t.Errorf("unexpected sunday week: %+v", week)
+	}
+	if len(week.Days[1].Events) != 1 {
+		t.Errorf("expected standup on monday, got %+v", week.Days)
+	}
+
+	rec = do(t, a, http.MethodGet, "/events_for_week?week=2024-W42", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &week)
+	if week.Start != "2024-10-14" || week.ISOWeek != "2024-W42" || len(week.Days[0].Events) != 1 || len(week.Days[6].Events) != 1 {
+		t.Errorf("unexpected ISO week: %+v", week)
+	}
+
+	var month service.PeriodEvents
+	rec = do(t, a, http.MethodGet, "/events_for_month?date=2024-10-01&week_start=monday", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &month)
+	if month.Start != "2024-10-01" || month.End != "2024-10-31" || month.WeekStart != "monday" || len(month.Days) != 31 {
+		t.Errorf("unexpected month: %+v", month)
+	}
+
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {id}, "title": {"standup moved"}, "date": {"2024-10-15"}})
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {"missing"}, "title": {"x"}, "date": {"2024-10-15"}})
+	expectError(t, rec, http.StatusNotFound, helpers.CodeNotFound, "")
+
+	rec = do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"review"}, "date": {"2024-10-16T10:00:00Z"}, "end": {"2024-10-16T12:00:00+01:00"}})
+	expectStatus(t, rec, http.StatusOK)
+	timed := a.Storage.GetEventsForDay(mustParseDate(t, "2024-10-16"))[0]
+	if !timed.Timed() || !timed.End.Equal(time.Date(2024, 10, 16, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected timed event: %+v", timed)
+	}
+	expectError(t, do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"x"}, "date": {"2024-10-16T10:00:00Z"}, "end": {"2024-10-16T09:00:00Z"}}), http.StatusBadRequest, helpers.CodeInvalidField, "end")
+
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {timed.ID}, "title": {"design review"}, "date": {"2024-10-16T09:30:00Z"}})
+	expectStatus(t, rec, http.StatusOK)
+	if updated, _ := a.Storage.GetEventByID(timed.ID); !updated.Timed() || !updated.End.Equal(*timed.End) || updated.Title != "design review" {
+		t.Errorf("update without end lost the end: %+v", updated)
+	}
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {timed.ID}, "title": {"design review"}, "date": {"2024-10-17T09:30:00Z"}, "end": {"2024-10-17T10:30:00Z"}})
+	expectStatus(t, rec, http.StatusOK)
+	if updated, _ := a.Storage.GetEventByID(timed.ID); !updated.End.Equal(time.Date(2024, 10, 17, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("update did not move the end: %+v", updated)
+	}
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {timed.ID}, "title": {"design review"}, "date": {"2024-10-18T09:30:00Z"}})
+	expectError(t, rec, http.StatusBadRequest, helpers.CodeInvalidField, "end")
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {timed.ID}, "title": {"design review"}, "date": {"2024-10-18"}})
+	expectStatus(t, rec, http.StatusOK)
+	if updated, _ := a.Storage.GetEventByID(timed.ID); updated.Timed() || !updated.Date.Equal(mustParseDate(t, "2024-10-18")) {
+		t.Errorf("update with a date did not make the event all-day: %+v", updated)
+	}
+	rec = do(t, a, http.MethodPost, "/update_event", url.Values{"id": {timed.ID}, "title": {"design review"}, "date": {"2024-10-17T09:30:00Z"}, "end": {"soon"}})
+	expectError(t, rec, http.StatusBadRequest, helpers.CodeInvalidField, "end")
+
+	rec = do(t, a, http.MethodPost, "/delete_event", url.Values{"id": {id}})
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/delete_event", url.Values{"id": {id}})
+	expectError(t, rec, http.StatusNotFound, helpers.CodeNotFound, "")
+}
+
+func TestCalendarRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	create := func(user string, form url.Values) service.Calendar {
+		t.Helper()
+		rec := doAs(t, a, user, http.MethodPost, "/create_calendar", form)
+		expectStatus(t, rec, http.StatusOK)
+		var created struct {
+			Calendar service.Calendar `json:"calendar"`
+		}
+		decode(t, rec, &created)
+		return created.Calendar
+	}
+	personal := create("ann", url.Values{"name": {"personal"}, "owner": {"bob"}})
+	team := create("", url.Values{"name": {"team"}, "colour": {"#00AA00"}, "visibility": {"shared"}})
+	if personal.Owner != "ann" || team.Owner != "" {
+		t.Errorf("calendars not owned by their creators: %+v, %+v", personal, team)
+	}
+	if personal.Colour != helpers.DefaultCalendarColour || personal.Visibility != service.VisibilityPrivate {
+		t.Errorf("unexpected defaults: %+v", personal)
+	}
+	if team.Colour != "#00aa00" || team.Visibility != service.VisibilityShared {
+		t.Errorf("unexpected calendar: %+v", team)
+	}
+
+	bobs := create("bob", url.Values{"name": {"bob's team"}, "visibility": {"shared"}})
+
+	var calendars []service.Calendar
+	rec := doAs(t, a, "ann", http.MethodGet, "/calendars?owner=ann&user=bob", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &calendars)
+	if len(calendars) != 1 || calendars[0].ID != personal.ID {
+		t.Errorf("unexpected calendars of ann: %+v", calendars)
+	}
+	visible := map[string][]string{
+		"":     {team.ID},
+		"carl": {bobs.ID, team.ID},
+		"ann":  {bobs.ID, personal.ID, team.ID},
+	}
+	for user, want := range visible {
+		decode(t, doAs(t, a, user, http.MethodGet, "/calendars?user=ann", nil), &calendars)
+		var got []string
+		for _, calendar := range calendars {
+			got = append(got, calendar.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("user %q sees calendars %v, want %v", user, got, want)
+		}
+	}
+
+	rec = do(t, a, http.MethodPost, "/update_calendar", url.Values{"id": {team.ID}, "visibility": {"public"}})
+	expectStatus(t, rec, http.StatusOK)
+	if updated, _ := a.Storage.GetCalendar(team.ID); updated.Visibility != service.VisibilityPublic || updated.Name != "team" || updated.Colour != "#00aa00" {
+		t.Errorf("update changed omitted fields: %+v", updated)
+	}
+
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/update_calendar", url.Values{"id": {bobs.ID}, "visibility": {"public"}}), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/update_calendar", url.Values{"id": {personal.ID}, "visibility": {"public"}}), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/delete_calendar", url.Values{"id": {bobs.ID}}), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectStatus(t, doAs(t, a, "bob", http.MethodPost, "/update_calendar", url.Values{"id": {bobs.ID}, "owner": {"carl"}}), http.StatusOK)
+	if updated, _ := a.Storage.GetCalendar(bobs.ID); updated.Owner != "bob" || updated.Visibility != service.VisibilityShared {
+		t.Errorf("calendar changed hands or visibility: %+v", updated)
+	}
+
+	expectStatus(t, doAs(t, a, "ann", http.MethodPost, "/create_event", url.Values{"title": {"dentist"}, "date": {"2024-10-14"}, "calendar_id": {personal.ID}}), http.StatusOK)
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"sync"}, "date": {"2024-10-14"}, "calendar_id": {team.ID}})
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"lunch"}, "date": {"2024-10-15"}})
+	expectStatus(t, doAs(t, a, "bob", http.MethodPost, "/create_event", url.Values{"title": {"standup"}, "date": {"2024-10-21"}, "calendar_id": {bobs.ID}}), http.StatusOK)
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/create_event", url.Values{"title": {"x"}, "date": {"2024-10-14"}, "calendar_id": {personal.ID}}), http.StatusBadRequest, helpers.CodeInvalidField, "calendar_id")
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/create_event", url.Values{"title": {"x"}, "date": {"2024-10-14"}, "calendar_id": {bobs.ID}}), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/quick_add", url.Values{"text": {"x tomorrow"}, "calendar_id": {bobs.ID}}), http.StatusForbidden, helpers.CodeForbidden, "")
+
+	var events []service.Event
+	rec = do(t, a, http.MethodGet, "/events_for_day?date=2024-10-14&calendars="+team.ID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &events)
+	if len(events) != 1 || events[0].Title != "sync" {
+		t.Errorf("unexpected team events: %+v", events)
+	}
+
+	var week service.PeriodEvents
+	rec = doAs(t, a, "ann", http.MethodGet, "/events_for_week?date=2024-10-14&calendars="+personal.ID+","+team.ID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &week)
+	if len(week.Days[1].Events) != 2 || len(week.Days[2].Events) != 0 {
+		t.Errorf("unexpected filtered week: %+v", week.Days)
+	}
+
+	var month service.PeriodEvents
+	rec = doAs(t, a, "ann", http.MethodGet, "/events_for_month?date=2024-10-01&calendars="+personal.ID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &month)
+	total := 0
+	for _, day := range month.Days {
+		total += len(day.Events)
+	}
+	if total != 1 {
+		t.Errorf("expected 1 personal event in the month, got %d", total)
+	}
+
+	for _, test := range []struct {
+		user, query, want string
+	}{
+		{"", "/events_for_day?date=2024-10-14", "sync"},
+		{"", "/events_for_day?date=2024-10-14&user=ann", "sync"},
+		{"bob", "/events_for_day?date=2024-10-14", "sync"},
+		{"ann", "/events_for_day?date=2024-10-14", "dentist,sync"},
+		{"", "/get_events", "lunch,sync"},
+		{"carl", "/get_events", "lunch,standup,sync"},
+		{"ann", "/get_events", "dentist,lunch,standup,sync"},
+		{"", "/events_for_range?start=2024-10-01&end=2024-10-31", "lunch,sync"},
+		{"ann", "/events_for_range?start=2024-10-01&end=2024-10-31", "dentist,lunch,standup,sync"},
+	} {
+		rec = doAs(t, a, test.user, http.MethodGet, test.query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &events)
+		var got []string
+		for _, event := range events {
+			got = append(got, event.Title)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != test.want {
+			t.Errorf("%s for %q returned %v, want %s", test.query, test.user, got, test.want)
+		}
+	}
+	decode(t, do(t, a, http.MethodGet, "/events_for_week?date=2024-10-14", nil), &week)
+	if len(week.Days[1].Events) != 1 || week.Days[1].Events[0].Title != "sync" {
+		t.Errorf("week shows private events to anyone: %+v", week.Days[1].Events)
+	}
+	expectError(t, doAs(t, a, "bob", http.MethodGet, "/events_for_month?date=2024-10-01&calendars="+personal.ID, nil), http.StatusBadRequest, helpers.CodeInvalidField, "calendars")
+
+	byTitle := make(map[string]service.Event)
+	for _, event := range a.Storage.GetEvent() {
+		byTitle[event.Title] = event
+	}
+	dentist, standup := byTitle["dentist"], byTitle["standup"]
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/update_event", url.Values{"id": {dentist.ID}, "title": {"mine"}, "date": {"2024-10-16"}}), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/delete_event", url.Values{"id": {dentist.ID}}), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/update_event", url.Values{"id": {standup.ID}, "title": {"mine"}, "date": {"2024-10-21"}}), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectError(t, doAs(t, a, "carl", http.MethodPost, "/delete_event", url.Values{"id": {standup.ID}}), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/update_event", url.Values{"id": {standup.ID}, "title": {"standup"}, "date": {"2024-10-21"}, "calendar_id": {personal.ID}}), http.StatusBadRequest, helpers.CodeInvalidField, "calendar_id")
+	expectStatus(t, doAs(t, a, "ann", http.MethodPost, "/update_event", url.Values{"id": {dentist.ID}, "title": {"dentist"}, "date": {"2024-10-16"}}), http.StatusOK)
+	if moved, _ := a.Storage.GetEventByID(dentist.ID); moved.CalendarID != personal.ID {
+		t.Errorf("update without calendar_id moved the event out of its calendar: %+v", moved)
+	}
+
+	expectError(t, do(t, a, http.MethodGet, "/events_for_day?date=2024-10-14&calendars=missing", nil), http.StatusBadRequest, helpers.CodeInvalidField, "calendars")
+	expectError(t, do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"x"}, "date": {"2024-10-14"}, "calendar_id": {"missing"}}), http.StatusBadRequest, helpers.CodeInvalidField, "calendar_id")
+	expectError(t, do(t, a, http.MethodPost, "/create_calendar", url.Values{"name": {"x"}, "colour": {"red"}}), http.StatusBadRequest, helpers.CodeInvalidField, "colour")
+	expectError(t, do(t, a, http.MethodPost, "/create_calendar", url.Values{"name": {"x"}, "visibility": {"secret"}}), http.StatusBadRequest, helpers.CodeInvalidField, "visibility")
+	expectError(t, do(t, a, http.MethodPost, "/update_calendar", url.Values{"id": {"missing"}}), http.StatusNotFound, helpers.CodeNotFound, "")
+
+	rec = do(t, a, http.MethodPost, "/delete_calendar", url.Values{"id": {team.ID}})
+	expectStatus(t, rec, http.StatusOK)
+	if events := a.Storage.GetEvent(); len(events) != 3 {
+		t.Errorf("expected the team event to be deleted with its calendar, got %+v", events)
+	}
+	expectError(t, do(t, a, http.MethodPost, "/delete_calendar", url.Values{"id": {team.ID}}), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/delete_calendar", url.Values{"id": {personal.ID}}), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectStatus(t, doAs(t, a, "ann", http.MethodPost, "/delete_calendar", url.Values{"id": {personal.ID}}), http.StatusOK)
+}
+
+func TestPreferenceRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	var prefs preference.Preferences
+	decode(t, doAs(t, a, "ann", http.MethodGet, "/user_preferences?user=bob", nil), &prefs)
+	if prefs.User != "ann" || prefs.WeekStart != "" {
+		t.Errorf("unexpected preferences before update: %+v", prefs)
+	}
+	expectStatus(t, doAs(t, a, "ann", http.MethodPost, "/update_user_preferences", url.Values{"user": {"bob"}, "week_start": {"iso"}}), http.StatusOK)
+	decode(t, doAs(t, a, "ann", http.MethodGet, "/user_preferences", nil), &prefs)
+	if prefs.WeekStart != "monday" {
+		t.Errorf("unexpected preferences after update: %+v", prefs)
+	}
+
+	var bobs preference.Preferences
+	if decode(t, doAs(t, a, "bob", http.MethodGet, "/user_preferences", nil), &bobs); bobs.WeekStart != "" {
+		t.Errorf("ann changed the preferences of bob: %+v", bobs)
+	}
+
+	tests := []struct {
+		user      string
+		query     string
+		weekStart string
+		start     string
+	}{
+		{"ann", "date=2024-10-16", "monday", "2024-10-14"},
+		{"ann", "date=2024-10-16&week_start=saturday", "saturday", "2024-10-12"},
+		{"bob", "date=2024-10-16", "sunday", "2024-10-13"},
+		{"", "date=2024-10-16&user=ann", "sunday", "2024-10-13"},
+	}
+	for _, test := range tests {
+		var week service.PeriodEvents
+		rec := doAs(t, a, test.user, http.MethodGet, "/events_for_week?"+test.query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &week)
+		if week.WeekStart != test.weekStart || week.Start != test.start {
+			t.Errorf("%s: got a %s week from %s, want a %s week from %s", test.query, week.WeekStart, week.Start, test.weekStart, test.start)
+		}
+	}
+
+	expectError(t, doAs(t, a, "ann", http.MethodPost, "/update_user_preferences", url.Values{"week_start": {"friday"}}), http.StatusBadRequest, helpers.CodeInvalidField, "week_start")
+	expectError(t, do(t, a, http.MethodGet, "/user_preferences?user=ann", nil), http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+	expectError(t, do(t, a, http.MethodPost, "/update_user_preferences", url.Values{"user": {"ann"}, "week_start": {"iso"}}), http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+}
+
+func TestValidationErrors(t *testing.T) {
+	a := newTestApp(t)
+
+	tests := []struct {
+		method string
+		target string
+		form   url.Values
+		field  string
+		code   string
+	}{
+		{http.MethodPost, "/create_event", url.Values{"date": {"2024-10-14"}}, "title", helpers.CodeMissingField},
+		{http.MethodPost, "/create_event", url.Values{"title": {"x"}}, "date", helpers.CodeMissingField},
+		{http.MethodPost, "/create_event", url.Values{"title": {"x"}, "date": {"14.10.2024"}}, "date", helpers.CodeInvalidField},
+		{http.MethodPost, "/update_event", url.Values{"title": {"x"}, "date": {"2024-10-14"}}, "id", helpers.CodeMissingField},
+		{http.MethodPost, "/delete_event", url.Values{}, "id", helpers.CodeMissingField},
+		{http.MethodGet, "/events_for_day", nil, "date", helpers.CodeMissingField},
+		{http.MethodGet, "/events_for_day?date=tomorrow", nil, "date", helpers.CodeInvalidField},
+		{http.MethodGet, "/events_for_week", nil, "date", helpers.CodeMissingField},
+		{http.MethodGet, "/events_for_week?week=2024-42", nil, "week", helpers.CodeInvalidField},
+		{http.MethodGet, "/events_for_week?week=2024-W60", nil, "week", helpers.CodeInvalidField},
+		{http.MethodGet, "/events_for_week?date=2024-10-14&week_start=friday", nil, "week_start", helpers.CodeInvalidField},
+		{http.MethodGet, "/events_for_month", nil, "date", helpers.CodeMissingField},
+		{http.MethodPost, "/create_webhook", url.Values{}, "url", helpers.CodeMissingField},
+		{http.MethodPost, "/create_webhook", url.Values{"url": {"ftp://example.com"}}, "url", helpers.CodeInvalidField},
+		{http.MethodPost, "/create_webhook", url.Values{"url": {"http://example.com"}, "events": {"event.moved"}}, "events", helpers.CodeInvalidField},
+		{http.MethodPost, "/delete_webhook", url.Values{}, "id", helpers.CodeMissingField},
+		{http.MethodPost, "/create_booking_page", url.Values{"slug": {"Bad Slug"}, "title": {"x"}, "slot_minutes": {"30"}, "window": {"mon 09:00-10:00"}}, "slug", helpers.CodeInvalidField},
+		{http.MethodPost, "/create_booking_page", url.Values{"slug": {"x"}, "title": {"x"}, "slot_minutes": {"1"}, "window": {"mon 09:00-10:00"}}, "slot_minutes", helpers.CodeInvalidField},
+		{http.MethodPost, "/create_booking_page", url.Values{"slug": {"x"}, "title": {"x"}, "slot_minutes": {"30"}, "window": {"someday 09:00-10:00"}}, "window", helpers.CodeInvalidField},
+		{http.MethodGet, "/booking_slots", nil, "page", helpers.CodeMissingField},
+		{http.MethodPost, "/book_slot", url.Values{"page": {"x"}, "start": {"tomorrow"}, "name": {"Ana"}}, "start", helpers.CodeInvalidField},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			rec := do(t, a, test.method, test.target, test.form)
+			expectError(t, rec, http.StatusBadRequest, test.code, test.field)
+		})
+	}
+}
+
+func TestDigestRoutes(t *testing.T) {
+	a := newTestApp(t)
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"standup"}, "date": {"2024-10-14"}})
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"retro"}, "date": {"2024-10-18"}})
+
+	rec := do(t, a, http.MethodGet, "/digest_preview?date=2024-10-14", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") || !strings.Contains(rec.Body.String(), "standup") || strings.Contains(rec.Body.String(), "retro") {
+		t.Errorf("unexpected day digest %q: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = do(t, a, http.MethodGet, "/digest_preview?period=week&date=2024-10-16&format=html", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "<li><strong>all day</strong> retro</li>") {
+		t.Errorf("unexpected week digest: %s", rec.Body.String())
+	}
+
+	expectError(t, do(t, a, http.MethodGet, "/digest_preview?period=month", nil), http.StatusBadRequest, helpers.CodeInvalidField, "period")
+	expectError(t, do(t, a, http.MethodGet, "/digest_preview?format=pdf", nil), http.StatusBadRequest, helpers.CodeInvalidField, "format")
+}
+
+func TestQuickAddRoutes(t *testing.T) {
+	a := newTestApp(t)
+	phrase := url.Values{
+		"text":      {"Retro every Friday 15:00 for 1h"},
+		"reference": {"2024-10-16T10:00:00+03:00"},
+		"time_zone": {"Europe/Moscow"},
+	}
+
+	rec := do(t, a, http.MethodPost, "/quick_add", phrase)
+	expectStatus(t, rec, http.StatusOK)
+	var parsed struct {
+		Result string `json:"result"`
+		Parsed struct {
+			Title      string `json:"title"`
+			Recurrence struct {
+				Frequency string `json:"frequency"`
+			} `json:"recurrence"`
+			Event struct {
+				Date time.Time  `json:"date"`
+				End  *time.Time `json:"end"`
+			} `json:"event"`
+		} `json:"parsed"`
+	}
+	decode(t, rec, &parsed)
+	if parsed.Result != "event parsed" || parsed.Parsed.Title != "Retro" || parsed.Parsed.Recurrence.Frequency != "weekly" ||
+		!parsed.Parsed.Event.Date.Equal(time.Date(2024, 10, 18, 12, 0, 0, 0, time.UTC)) || parsed.Parsed.Event.End == nil {
+		t.Fatalf("unexpected parse response: %s", rec.Body.String())
+	}
+	if events := a.Storage.GetEventsForDay(time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC)); len(events) != 0 {
+		t.Fatalf("parsing without confirm stored %d event(s)", len(events))
+	}
+
+	// Recurring phrases are only parsed, even with confirm.
+	phrase.Set("confirm", "true")
+	rec = do(t, a, http.MethodPost, "/quick_add", phrase)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &parsed)
+	if parsed.Result != "event parsed" || len(a.Storage.GetEvent()) != 0 {
+		t.Fatalf("confirming a recurring phrase returned %s and stored %d event(s)", rec.Body.String(), len(a.Storage.GetEvent()))
+	}
+
+	// Like /create_event, confirming a phrase saves the event even when it overlaps another one.
+	phrase.Set("text", "Retro Friday 15:00 for 1h")
+	var ids []string
+	for i := 0; i < 2; i++ {
+		rec = do(t, a, http.MethodPost, "/quick_add", phrase)
+		expectStatus(t, rec, http.StatusOK)
+		var confirmed struct {
+			Result string        `json:"result"`
+			Event  service.Event `json:"event"`
+		}
+		decode(t, rec, &confirmed)
+		if confirmed.Result != "event created" || confirmed.Event.ID == "" || confirmed.Event.Title != "Retro" {
+			t.Errorf("unexpected confirm response: %s", rec.Body.String())
+		}
+		ids = append(ids, confirmed.Event.ID)
+	}
+	events := a.Storage.GetEventsForDay(time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC))
+	if len(events) != 2 {
+		t.Fatalf("expected two stored events, got %d", len(events))
+	}
+	for _, id := range ids {
+		if _, ok := a.Storage.GetEventByID(id); !ok {
+			t.Errorf("quick_add returned unknown event ID %q", id)
+		}
+	}
+
+	expectError(t, do(t, a, http.MethodPost, "/quick_add", url.Values{}), http.StatusBadRequest, helpers.CodeMissingField, "text")
+	expectError(t, do(t, a, http.MethodPost, "/quick_add", url.Values{"text": {"tomorrow at 10"}}), http.StatusBadRequest, helpers.CodeInvalidField, "text")
+	expectError(t, do(t, a, http.MethodPost, "/quick_add", url.Values{"text": {"Lunch"}, "time_zone": {"Mars/Olympus"}}), http.StatusBadRequest, helpers.CodeInvalidField, "time_zone")
+	expectError(t, do(t, a, http.MethodPost, "/quick_add", url.Values{"text": {"Lunch"}, "calendar_id": {"nope"}}), http.StatusBadRequest, helpers.CodeInvalidField, "calendar_id")
+}
+
+func importFile(t *testing.T, a *App, name, data string, fields url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for key, values := range fields {
+		mw.WriteField(key, values[0])
+	}
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(data))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImportRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	mapping := url.Values{"mapping": {`{"fields": {"title": "Subject", "date": "Start", "external_id": "UID"}, "date_layout": "02.01.2006"}`}}
+	csv := "UID,Subject,Start\nx-1,standup,14.10.2024\nx-2,,15.10.2024\n"
+
+	var response struct {
+		Result string
+		Report importer.Report
+	}
+	rec := importFile(t, a, "events.csv", csv, url.Values{"mapping": mapping["mapping"], "dry_run": {"true"}})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &response)
+	if response.Result != "import checked" || response.Report.Created != 1 || response.Report.Invalid != 1 || len(a.Storage.GetEvent()) != 0 {
+		t.Fatalf("unexpected dry run %+v, storage holds %d events", response, len(a.Storage.GetEvent()))
+	}
+
+	for _, want := range []importer.Action{importer.Create, importer.Unchanged} {
+		rec = importFile(t, a, "events.csv", csv, mapping)
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &response)
+		if response.Report.Rows[0].Action != want || response.Report.Rows[1].Error == nil || response.Report.Rows[1].Error.Field != "title" {
+			t.Errorf("unexpected report %+v, want %s", response.Report, want)
+		}
+	}
+	events := a.Storage.GetEvent()
+	if len(events) != 1 || events[0].ExternalID != "x-1" || events[0].Title != "standup" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	// Updating the event through the API keeps its external ID.
+	do(t, a, http.MethodPost, "/update_event", url.Values{"id": {events[0].ID}, "title": {"daily"}, "date": {"2024-10-14"}})
+	rec = importFile(t, a, "events.json", `[{"UID": "x-1", "Subject": "standup", "Start": "14.10.2024"}]`, mapping)
+	decode(t, rec, &response)
+	if response.Report.Updated != 1 || len(a.Storage.GetEvent()) != 1 {
+		t.Errorf("unexpected report %+v", response.Report)
+	}
+
+	expectError(t, importFile(t, a, "events.txt", csv, nil), http.StatusBadRequest, helpers.CodeMissingField, "format")
+	expectError(t, importFile(t, a, "events.txt", csv, url.Values{"format": {"xml"}}), http.StatusBadRequest, helpers.CodeInvalidField, "format")
+	expectError(t, importFile(t, a, "events.csv", csv, url.Values{"mapping": {`{"fields": {"place": "Where"}}`}}), http.StatusBadRequest, helpers.CodeInvalidField, "mapping")
+	expectError(t, importFile(t, a, "events.json", csv, nil), http.StatusBadRequest, helpers.CodeInvalidField, "file")
+	expectError(t, importFile(t, a, "events.csv", csv, url.Values{"dry_run": {"maybe"}}), http.StatusBadRequest, helpers.CodeInvalidField, "dry_run")
+	expectError(t, do(t, a, http.MethodPost, "/import", url.Values{"format": {"csv"}}), http.StatusBadRequest, helpers.CodeInvalidRequest, "")
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	rec := do(t, a, http.MethodPost, "/create_webhook", url.Values{"url": {"http://127.0.0.1:1/hook"}, "events": {"event.created"}})
+	expectStatus(t, rec, http.StatusOK)
+	var created struct {
+		Webhook struct {
+			ID string `json:"id"`
+		} `json:"webhook"`
+		Secret string `json:"secret"`
+	}
+	decode(t, rec, &created)
+	if created.Webhook.ID == "" || created.Secret == "" {
+		t.Fatalf("unexpected create response: %s", rec.Body.String())
+	}
+
+	var webhooks []map[string]interface{}
+	rec = do(t, a, http.MethodGet, "/webhooks", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &webhooks)
+	if len(webhooks) != 1 || webhooks[0]["secret"] != nil {
+		t.Errorf("unexpected webhooks: %v", webhooks)
+	}
+
+	var deadLetters []interface{}
+	rec = do(t, a, http.MethodGet, "/webhook_dead_letters", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &deadLetters)
+
+	// Webhooks post to arbitrary URLs, so users need the admin token.
+	for _, target := range []string{"/create_webhook", "/delete_webhook"} {
+		rec = doAs(t, a, "ann", http.MethodPost, target, url.Values{"id": {created.Webhook.ID}, "url": {"http://127.0.0.1:1/hook"}})
+		expectError(t, rec, http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+	}
+	for _, target := range []string{"/webhooks", "/webhook_dead_letters"} {
+		expectError(t, doAs(t, a, "ann", http.MethodGet, target, nil), http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+	}
+
+	rec = do(t, a, http.MethodPost, "/delete_webhook", url.Values{"id": {created.Webhook.ID}})
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/delete_webhook", url.Values{"id": {created.Webhook.ID}})
+	expectError(t, rec, http.StatusNotFound, helpers.CodeNotFound, "")
+}
+
+func upload(t *testing.T, a *App, eventID, name string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	return uploadAs(t, a, "", eventID, name, data)
+}
+
+// uploadAs uploads on behalf of user, or without a token when user is empty.
+func uploadAs(t *testing.T, a *App, user, eventID, name string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("event_id", eventID)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload_attachment", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if user != "" {
+		req.Header.Set("Authorization", "Bearer test-token-"+user)
+	}
+	rec := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAttachmentRoutes(t *testing.T) {
+	dir := t.TempDir()
+	a, err := New(config.Config{WeekStart: "sunday", AttachmentsDir: dir, AttachmentLimit: 1024}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"planning"}, "date": {"2024-10-14"}})
+	eventID := a.Storage.GetEvent()[0].ID
+
+	pdf := []byte("%PDF-1.4 agenda")
+	rec := upload(t, a, eventID, "../agenda.txt", pdf)
+	expectStatus(t, rec, http.StatusOK)
+	var uploaded struct {
+		Attachment attachment.Attachment `json:"attachment"`
+	}
+	decode(t, rec, &uploaded)
+	if uploaded.Attachment.ContentType != "application/pdf" || uploaded.Attachment.Name != "agenda.txt" || uploaded.Attachment.Size != int64(len(pdf)) {
+		t.Errorf("unexpected attachment: %+v", uploaded.Attachment)
+	}
+	upload(t, a, eventID, "notes.txt", []byte("plain notes"))
+
+	var listed []attachment.Attachment
+	rec = do(t, a, http.MethodGet, "/attachments?event_id="+eventID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &listed)
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 attachments, got %+v", listed)
+	}
+
+	rec = do(t, a, http.MethodGet, "/download_attachment?id="+uploaded.Attachment.ID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != string(pdf) || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("unexpected download %q with type %q", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=agenda.txt` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	expectError(t, upload(t, a, eventID, "big.bin", make([]byte, 2048)), http.StatusRequestEntityTooLarge, helpers.CodeTooLarge, "file")
+	expectError(t, upload(t, a, eventID, "empty.txt", nil), http.StatusBadRequest, helpers.CodeInvalidField, "file")
+	expectError(t, upload(t, a, "missing", "notes.txt", []byte("x")), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, do(t, a, http.MethodGet, "/download_attachment?id=missing", nil), http.StatusNotFound, helpers.CodeNotFound, "")
+
+	rec = do(t, a, http.MethodPost, "/delete_attachment", url.Values{"id": {listed[1].ID}})
+	expectStatus(t, rec, http.StatusOK)
+	expectError(t, do(t, a, http.MethodPost, "/delete_attachment", url.Values{"id": {listed[1].ID}}), http.StatusNotFound, helpers.CodeNotFound, "")
+
+	do(t, a, http.MethodPost, "/delete_event", url.Values{"id": {eventID}})
+	rec = do(t, a, http.MethodGet, "/attachments?event_id="+eventID, nil)
+	decode(t, rec, &listed)
+	if len(listed) != 0 {
+		t.Errorf("attachments survived their event: %+v", listed)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "index.json" {
+		t.Errorf("blobs survived their event: %v", files)
+	}
+}
+
+func TestAttachmentVisibility(t *testing.T) {
+	a := newTestApp(t)
+
+	calendarOf := func(user string, form url.Values) string {
+		t.Helper()
+		var created struct {
+			Calendar service.Calendar `json:"calendar"`
+		}
+		decode(t, doAs(t, a, user, http.MethodPost, "/create_calendar", form), &created)
+		return created.Calendar.ID
+	}
+	eventIn := func(calendarID string) string {
+		t.Helper()
+		var created struct {
+			Event service.Event `json:"event"`
+		}
+		decode(t, doAs(t, a, "ann", http.MethodPost, "/create_event", url.Values{"title": {"review"}, "date": {"2024-10-14"}, "calendar_id": {calendarID}}), &created)
+		return created.Event.ID
+	}
+	private := eventIn(calendarOf("ann", url.Values{"name": {"private"}}))
+	shared := eventIn(calendarOf("ann", url.Values{"name": {"team"}, "visibility": {"shared"}}))
+
+	var uploaded struct {
+		Attachment attachment.Attachment `json:"attachment"`
+	}
+	decode(t, uploadAs(t, a, "ann", private, "notes.txt", []byte("notes")), &uploaded)
+	hidden := uploaded.Attachment.ID
+	decode(t, uploadAs(t, a, "ann", shared, "notes.txt", []byte("notes")), &uploaded)
+	readOnly := uploaded.Attachment.ID
+
+	expectError(t, uploadAs(t, a, "bob", private, "x.txt", []byte("x")), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "bob", http.MethodGet, "/attachments?event_id="+private, nil), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "bob", http.MethodGet, "/download_attachment?id="+hidden, nil), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/delete_attachment", url.Values{"id": {hidden}}), http.StatusNotFound, helpers.CodeNotFound, "")
+
+	expectStatus(t, doAs(t, a, "bob", http.MethodGet, "/attachments?event_id="+shared, nil), http.StatusOK)
+	expectStatus(t, doAs(t, a, "bob", http.MethodGet, "/download_attachment?id="+readOnly, nil), http.StatusOK)
+	expectError(t, uploadAs(t, a, "bob", shared, "x.txt", []byte("x")), http.StatusForbidden, helpers.CodeForbidden, "")
+	expectError(t, doAs(t, a, "bob", http.MethodPost, "/delete_attachment", url.Values{"id": {readOnly}}), http.StatusForbidden, helpers.CodeForbidden, "")
+
+	expectStatus(t, doAs(t, a, "ann", http.MethodPost, "/delete_attachment", url.Values{"id": {hidden}}), http.StatusOK)
+}
+
+func TestRelativeAttachmentsDir(t *testing.T) {
+	if _, err := New(config.Config{WeekStart: "sunday", AttachmentsDir: "attachments"}, service.NewInMemoryStorage()); err == nil {
+		t.Error("expected a relative attachments directory to be rejected")
+	}
+}
+
+func TestBookingRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	rec := do(t, a, http.MethodPost, "/create_booking_page", url.Values{
+		"slug": {"intro"}, "title": {"Intro call"}, "slot_minutes": {"30"},
+		"time_zone": {"Europe/Moscow"}, "window": {"mon-fri 09:00-10:00", "sat 12:00-12:30"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/create_booking_page", url.Values{
+		"slug": {"intro"}, "title": {"Other call"}, "slot_minutes": {"60"}, "window": {"mon 09:00-10:00"},
+	})
+	expectError(t, rec, http.StatusConflict, helpers.CodeConflict, "slug")
+
+	var created struct {
+		Calendar service.Calendar `json:"calendar"`
+	}
+	decode(t, doAs(t, a, "bob", http.MethodPost, "/create_calendar", url.Values{"name": {"bob's"}, "visibility": {"public"}}), &created)
+	rec = doAs(t, a, "ann", http.MethodPost, "/create_booking_page", url.Values{
+		"slug": {"ann"}, "title": {"Ann"}, "slot_minutes": {"30"}, "window": {"mon 09:00-10:00"}, "calendar_id": {created.Calendar.ID},
+	})
+	expectError(t, rec, http.StatusForbidden, helpers.CodeForbidden, "")
+
+	var pages []map[string]interface{}
+	rec = do(t, a, http.MethodGet, "/booking_pages", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &pages)
+	if len(pages) != 1 || pages[0]["slug"] != "intro" {
+		t.Fatalf("unexpected booking pages: %v", pages)
+	}
+
+	var slots struct {
+		Slots []struct {
+			Start string `json:"start"`
+		} `json:"slots"`
+	}
+	// 2099-01-05 is a Monday.
+	rec = do(t, a, http.MethodGet, "/booking_slots?page=intro&from=2099-01-05&days=7", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &slots)
+	if len(slots.Slots) != 11 || slots.Slots[0].Start != "2099-01-05T09:00:00+03:00" {
+		t.Fatalf("unexpected slots: %+v", slots.Slots)
+	}
+
+	booking := url.Values{"page": {"intro"}, "start": {"2099-01-05T09:30:00+03:00"}, "name": {"Ana"}}
+	rec = do(t, a, http.MethodPost, "/book_slot", booking)
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/book_slot", booking)
+	expectError(t, rec, http.StatusConflict, helpers.CodeConflict, "start")
+
+	rec = do(t, a, http.MethodGet, "/booking_slots?page=intro&from=2099-01-05&days=1", nil)
+	decode(t, rec, &slots)
+	if len(slots.Slots) != 1 || slots.Slots[0].Start != "2099-01-05T09:00:00+03:00" {
+		t.Errorf("booked slot is still listed: %+v", slots.Slots)
+	}
+
+	rec = do(t, a, http.MethodPost, "/book_slot", url.Values{"page": {"intro"}, "start": {"2099-01-05T09:10:00+03:00"}, "name": {"Ana"}})
+	expectError(t, rec, http.StatusBadRequest, helpers.CodeInvalidField, "start")
+	rec = do(t, a, http.MethodGet, "/booking_slots?page=missing", nil)
+	expectError(t, rec, http.StatusNotFound, helpers.CodeNotFound, "")
+
+	rec = do(t, a, http.MethodPost, "/delete_booking_page", url.Values{"slug": {"intro"}})
+	expectStatus(t, rec, http.StatusOK)
+	rec = do(t, a, http.MethodPost, "/delete_booking_page", url.Values{"slug": {"intro"}})
+	expectError(t, rec, http.StatusNotFound, helpers.CodeNotFound, "")
+}
+
+func TestHolidayRoutes(t *testing.T) {
+	dir := t.TempDir()
+	holidays := `{"holidays": [{"date": "2099-01-06", "name": "Team day"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "team.json"), []byte(holidays), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(config.Config{WeekStart: "monday", HolidaysDir: dir}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var calendars []map[string]interface{}
+	rec := do(t, a, http.MethodGet, "/holiday_calendars", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &calendars)
+	if len(calendars) != 1 || calendars[0]["name"] != "team" {
+		t.Fatalf("unexpected calendars: %v", calendars)
+	}
+
+	var list []map[string]interface{}
+	rec = do(t, a, http.MethodGet, "/holidays?calendar=team&from=2099-01-01&to=2099-01-31", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &list)
+	if len(list) != 1 || list[0]["name"] != "Team day" {
+		t.Errorf("unexpected holidays: %v", list)
+	}
+
+	var week service.PeriodEvents
+	rec = do(t, a, http.MethodGet, "/events_for_week?date=2099-01-06&overlays=team", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &week)
+	if len(week.Days[1].Holidays) != 1 || !week.Days[1].NonWorking || week.Days[0].NonWorking || !week.Days[5].NonWorking {
+		t.Errorf("unexpected overlay: %+v", week.Days)
+	}
+	rec = do(t, a, http.MethodGet, "/events_for_week?date=2099-01-06&overlays=missing", nil)
+	expectError(t, rec, http.StatusBadRequest, helpers.CodeInvalidField, "overlays")
+
+	do(t, a, http.MethodPost, "/create_event", url.Values{"title": {"offsite"}, "date": {"2099-01-06"}})
+	var day service.PeriodEvents
+	rec = do(t, a, http.MethodGet, "/events_for_day?date=2099-01-06&overlays=team", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &day)
+	if len(day.Days) != 1 || len(day.Days[0].Events) != 1 || len(day.Days[0].Holidays) != 1 || !day.Days[0].NonWorking {
+		t.Errorf("unexpected day overlay: %+v", day)
+	}
+	var span service.PeriodEvents
+	rec = do(t, a, http.MethodGet, "/events_for_range?start=2099-01-05&end=2099-01-07&overlays=team", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &span)
+	if span.Start != "2099-01-05" || span.End != "2099-01-07" || len(span.Days) != 3 || len(span.Days[1].Events) != 1 || len(span.Days[1].Holidays) != 1 || span.Days[0].NonWorking {
+		t.Errorf("unexpected range overlay: %+v", span)
+	}
+	expectError(t, do(t, a, http.MethodGet, "/events_for_range?start=2099-01-05&end=2099-01-07&overlays=missing", nil), http.StatusBadRequest, helpers.CodeInvalidField, "overlays")
+
+	rec = do(t, a, http.MethodPost, "/create_booking_page", url.Values{
+		"slug": {"intro"}, "title": {"Intro"}, "slot_minutes": {"60"},
+		"window": {"mon-sun 09:00-10:00"}, "holiday_calendar": {"team"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	var slots struct {
+		Slots []struct {
+			Start string `json:"start"`
+		} `json:"slots"`
+	}
+	// 2099-01-05 is a Monday, the 6th is a team holiday and the 10th and 11th are a weekend.
+	rec = do(t, a, http.MethodGet, "/booking_slots?page=intro&from=2099-01-05&days=7", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &slots)
+	if len(slots.Slots) != 4 {
+		t.Errorf("expected 4 slots on working days, got %+v", slots.Slots)
+	}
+}
+
+func restore(t *testing.T, a *App, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/restore", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSnapshotRoutes(t *testing.T) {
+	source := newTestApp(t)
+	do(t, source, http.MethodPost, "/create_event", url.Values{"title": {"standup"}, "date": {"2024-10-14"}})
+	expectStatus(t, do(t, source, http.MethodPost, "/create_booking_page", url.Values{"slug": {"intro"}, "title": {"Intro call"}, "slot_minutes": {"30"}, "window": {"mon 09:00-10:00"}}), http.StatusOK)
+	expectStatus(t, doAs(t, source, "ann", http.MethodPost, "/update_user_preferences", url.Values{"week_start": {"iso"}}), http.StatusOK)
+	expectStatus(t, do(t, source, http.MethodPost, "/create_webhook", url.Values{"url": {"http://127.0.0.1:1/hook"}, "events": {"event.archived"}, "secret": {"s3cret"}}), http.StatusOK)
+
+	rec := do(t, source, http.MethodGet, "/admin/snapshot", nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	target := newTestApp(t)
+	do(t, target, http.MethodPost, "/create_event", url.Values{"title": {"replaced"}, "date": {"2024-10-15"}})
+	replaced := target.Storage.GetEvent()[0].ID
+	expectStatus(t, upload(t, target, replaced, "notes.txt", []byte("notes")), http.StatusOK)
+
+	expectStatus(t, restore(t, target, rec.Body), http.StatusOK)
+	if events := target.Storage.GetEvent(); len(events) != 1 || events[0].Title != "standup" {
+		t.Errorf("unexpected restored events: %+v", events)
+	}
+	if listed := target.Attachments.List(replaced); len(listed) != 0 {
+		t.Errorf("event dropped by the restore kept its attachments: %+v", listed)
+	}
+	if _, ok := target.Bookings.Get("intro"); !ok {
+		t.Error("booking page was not restored")
+	}
+	if weekStart, ok := target.Preferences.WeekStart("ann"); !ok || weekStart != time.Monday {
+		t.Errorf("restored week start %v, %t", weekStart, ok)
+	}
+	if webhooks := target.Webhooks.List(); len(webhooks) != 1 || webhooks[0].Secret != "s3cret" {
+		t.Errorf("unexpected restored webhooks: %+v", webhooks)
+	}
+
+	legacy := `{"version": 1, "events": [{"id": "e1", "title": "old", "date": "2023-01-02T00:00:00Z"}]}`
+	expectStatus(t, restore(t, target, strings.NewReader(legacy)), http.StatusOK)
+	if events := target.Storage.GetEvent(); len(events) != 1 || events[0].ID != "e1" || len(target.Storage.GetCalendars()) != 0 {
+		t.Errorf("unexpected events after restoring a version 1 snapshot: %+v", events)
+	}
+
+	expectError(t, restore(t, target, strings.NewReader(`{"events": []}`)), http.StatusBadRequest, helpers.CodeInvalidRequest, "")
+
+	huge := io.MultiReader(strings.NewReader(`{"version": 2, "events": [], "padding": "`), io.LimitReader(zeros{}, 65<<20), strings.NewReader(`"}`))
+	expectError(t, restore(t, target, huge), http.StatusRequestEntityTooLarge, helpers.CodeTooLarge, "")
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = '0'
+	}
+	return len(p), nil
+}
+
+func TestAdminToken(t *testing.T) {
+	a := newTestApp(t)
+	closed, err := New(config.Config{WeekStart: "sunday"}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closed.Close)
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testAdminToken, testAdminToken, "Bearer test-token-ann"} {
+		for _, route := range []struct{ method, target string }{
+			{http.MethodGet, "/admin/snapshot"},
+			{http.MethodPost, "/admin/retention"},
+			{http.MethodGet, "/admin/archive"},
+		} {
+			req := httptest.NewRequest(route.method, route.target, nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			a.Handler.ServeHTTP(rec, req)
+			expectError(t, rec, http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader(`{"version": 2, "events": []}`))
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	closed.Handler.ServeHTTP(rec, req)
+	expectError(t, rec, http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+
+	for _, tokens := range []string{"ann", "ann:", "ann:same,bob:same"} {
+		if _, err := New(config.Config{WeekStart: "sunday", UserTokens: tokens}, service.NewInMemoryStorage()); err == nil {
+			t.Errorf("expected an error for user tokens %q", tokens)
+		}
+	}
+}
+
+func TestRetentionRoutes(t *testing.T) {
+	a, err := New(config.Config{WeekStart: "sunday", ArchiveDir: t.TempDir(), ArchiveAfterMonths: 6, PurgeAfterYears: 5, AdminToken: testAdminToken}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+
+	today := time.Now().UTC()
+	dates := map[string]string{
+		"ancient": today.AddDate(-10, 0, 0).Format("2006-01-02"),
+		"old":     today.AddDate(-2, 0, 0).Format("2006-01-02"),
+		"current": today.Format("2006-01-02"),
+	}
+	ids := map[string]string{}
+	for title, date := range dates {
+		do(t, a, http.MethodPost, "/create_event", url.Values{"title": {title}, "date": {date}})
+		ids[title] = a.Storage.GetEventsForDay(mustParseDate(t, date))[0].ID
+	}
+	expectStatus(t, upload(t, a, ids["old"], "minutes.txt", []byte("minutes")), http.StatusOK)
+	expectStatus(t, upload(t, a, ids["ancient"], "scan.txt", []byte("scan")), http.StatusOK)
+
+	rec := do(t, a, http.MethodPost, "/admin/retention", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var applied struct {
+		Report archive.Report `json:"report"`
+	}
+	decode(t, rec, &applied)
+	if applied.Report.Archived != 2 || applied.Report.Purged != 1 {
+		t.Errorf("unexpected report %+v", applied.Report)
+	}
+	if events := a.Storage.GetEvent(); len(events) != 1 || events[0].Title != "current" {
+		t.Errorf("storage keeps %+v", events)
+	}
+
+	var found []service.Event
+	rec = do(t, a, http.MethodGet, "/events_for_range?start="+today.AddDate(-11, 0, 0).Format("2006-01-02")+"&end="+dates["current"], nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &found)
+	if len(found) != 2 || found[0].Title != "old" || found[0].ID != ids["old"] || found[1].Title != "current" {
+		t.Errorf("events_for_range returned %+v", found)
+	}
+
+	var listed []attachment.Attachment
+	decode(t, do(t, a, http.MethodGet, "/attachments?event_id="+ids["old"], nil), &listed)
+	if len(listed) != 1 {
+		t.Errorf("archived event lost its attachments: %+v", listed)
+	}
+	decode(t, do(t, a, http.MethodGet, "/attachments?event_id="+ids["ancient"], nil), &listed)
+	if len(listed) != 0 {
+		t.Errorf("purged event kept its attachments: %+v", listed)
+	}
+
+	var status struct {
+		Enabled bool            `json:"enabled"`
+		Months  []archive.Month `json:"months"`
+	}
+	decode(t, do(t, a, http.MethodGet, "/admin/archive", nil), &status)
+	if !status.Enabled || len(status.Months) != 1 || status.Months[0].Events != 1 {
+		t.Errorf("unexpected archive status %+v", status)
+	}
+
+	expectError(t, do(t, a, http.MethodGet, "/events_for_range?start=2024-10-14", nil), http.StatusBadRequest, helpers.CodeMissingField, "end")
+	expectError(t, do(t, a, http.MethodGet, "/events_for_range?start=2024-10-14&end=2024-10-01", nil), http.StatusBadRequest, helpers.CodeInvalidField, "end")
+
+	if _, err := New(config.Config{WeekStart: "sunday", ArchiveAfterMonths: 6}, service.NewInMemoryStorage()); err == nil {
+		t.Error("archiving without an archive directory should be rejected")
+	}
+}
+
+func mustParseDate(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func startReplica(t *testing.T, replica *App) func() {
+	t.Helper()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		replica.Replica.Run(stop)
+		close(done)
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func waitInSync(t *testing.T, primary, replica *App) {
+	t.Helper()
+
+	want := snapshotJSON(primary.Storage)
+	deadline := time.Now().Add(5 * time.Second)
+	for snapshotJSON(replica.Storage) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica did not catch up:\nprimary %s\nreplica %s\nstatus %+v", want, snapshotJSON(replica.Storage), replica.Replica.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func snapshotJSON(storage service.Storage) string {
+	data, _ := json.Marshal(map[string]interface{}{"calendars": storage.GetCalendars(), "events": storage.GetEvent()})
+	return string(data)
+}
+
+func TestReplication(t *testing.T) {
+	const secret = "test-replication-secret"
+	if _, err := New(config.Config{WeekStart: "sunday", ReplicationRole: "primary"}, service.NewInMemoryStorage()); err == nil {
+		t.Error("a primary without a replication secret was accepted")
+	}
+	primary, err := New(config.Config{WeekStart: "sunday", ReplicationRole: "primary", ReplicationLogSize: 4, ReplicationSecret: secret, AdminToken: testAdminToken}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(primary.Close)
+
+	var checkpoints atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/replication/snapshot" {
+			checkpoints.Add(1)
+		}
+		primary.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	replica, err := New(config.Config{WeekStart: "sunday", ReplicationRole: "replica", ReplicationPrimary: server.URL, ReplicationSecret: secret}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(replica.Close)
+	replica.Replica.Wait = time.Second
+	replica.Replica.Retry = 10 * time.Millisecond
+
+	var calendar struct {
+		Calendar service.Calendar `json:"calendar"`
+	}
+	decode(t, do(t, primary, http.MethodPost, "/create_calendar", url.Values{"name": {"team"}}), &calendar)
+	do(t, primary, http.MethodPost, "/create_event", url.Values{"title": {"standup"}, "date": {"2024-10-14"}, "calendar_id": {calendar.Calendar.ID}})
+
+	stopReplica := startReplica(t, replica)
+	waitInSync(t, primary, replica)
+
+	event := replica.Storage.GetEvent()[0]
+	do(t, primary, http.MethodPost, "/update_event", url.Values{"id": {event.ID}, "title": {"daily standup"}, "date": {"2024-10-15"}})
+	do(t, primary, http.MethodPost, "/create_event", url.Values{"title": {"retro"}, "date": {"2024-10-18"}})
+	waitInSync(t, primary, replica)
+
+	expectError(t, do(t, replica, http.MethodPost, "/create_event", url.Values{"title": {"local"}, "date": {"2024-10-14"}}), http.StatusForbidden, helpers.CodeReadOnly, "")
+	rec := do(t, replica, http.MethodGet, "/events_for_day?date=2024-10-15", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "daily standup") {
+		t.Errorf("replica does not serve replicated events: %s", rec.Body.String())
+	}
+
+	// Changes made while the replica is away are caught up from the log.
+	stopReplica()
+	do(t, primary, http.MethodPost, "/delete_event", url.Values{"id": {event.ID}})
+	do(t, primary, http.MethodPost, "/update_calendar", url.Values{"id": {calendar.Calendar.ID}, "colour": {"#00aa00"}})
+	stopReplica = startReplica(t, replica)
+	waitInSync(t, primary, replica)
+	if got := checkpoints.Load(); got != 1 {
+		t.Errorf("catch-up within the log loaded %d checkpoints, want 1", got)
+	}
+
+	// More changes than the log keeps force a new checkpoint.
+	stopReplica()
+	for i := 0; i < 6; i++ {
+		do(t, primary, http.MethodPost, "/create_event", url.Values{"title": {fmt.Sprintf("event %d", i)}, "date": {"2024-11-01"}})
+	}
+	stopReplica = startReplica(t, replica)
+	waitInSync(t, primary, replica)
+	if got := checkpoints.Load(); got != 2 {
+		t.Errorf("catch-up past the log loaded %d checkpoints, want 2", got)
+	}
+
+	// So does restoring the primary.
+	do(t, primary, http.MethodPost, "/delete_calendar", url.Values{"id": {calendar.Calendar.ID}})
+	req := httptest.NewRequest(http.MethodPost, "/admin/restore", strings.NewReader(`{"version": 2, "calendars": [], "events": [{"id": "e1", "title": "restored", "date": "2024-12-01T00:00:00Z"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec = httptest.NewRecorder()
+	primary.Handler.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusOK)
+	waitInSync(t, primary, replica)
+	stopReplica()
+
+	var status struct {
+		Role    string             `json:"role"`
+		Replica replication.Status `json:"replica"`
+	}
+	decode(t, do(t, replica, http.MethodGet, "/replication/status", nil), &status)
+	logID, seq := primary.Primary.Log().Position()
+	if status.Role != "replica" || status.Replica.Log != logID || status.Replica.Seq != seq || status.Replica.Primary != server.URL {
+		t.Errorf("unexpected replica status %+v, primary at %s/%d", status, logID, seq)
+	}
+
+	changes := func(a *App, query, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/replication/changes?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		a.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+	expectError(t, changes(primary, "log="+logID+"&since=0", secret), http.StatusGone, helpers.CodeLogTruncated, "")
+	expectError(t, changes(replica, "log="+logID+"&since=0", secret), http.StatusNotFound, helpers.CodeNotFound, "")
+	expectError(t, changes(primary, "log="+logID+"&since=0&wait=120", secret), http.StatusBadRequest, helpers.CodeInvalidField, "wait")
+	expectError(t, changes(primary, "log="+logID+"&since=0", testAdminToken), http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+	expectError(t, do(t, primary, http.MethodGet, "/replication/snapshot", nil), http.StatusUnauthorized, helpers.CodeUnauthorized, "")
+}
+
+func TestReplicaKeepsSharedAttachments(t *testing.T) {
+	const secret = "test-replication-secret"
+	dir := t.TempDir()
+	primary, err := New(config.Config{WeekStart: "sunday", ReplicationRole: "primary", ReplicationSecret: secret, AttachmentsDir: dir, AttachmentLimit: 1024}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(primary.Close)
+	do(t, primary, http.MethodPost, "/create_event", url.Values{"title": {"planning"}, "date": {"2024-10-14"}})
+	event := primary.Storage.GetEvent()[0]
+	var uploaded struct {
+		Attachment attachment.Attachment `json:"attachment"`
+	}
+	decode(t, upload(t, primary, event.ID, "agenda.txt", []byte("agenda")), &uploaded)
+
+	replica, err := New(config.Config{WeekStart: "sunday", ReplicationRole: "replica", ReplicationPrimary: "http://127.0.0.1:1", ReplicationSecret: secret, AttachmentsDir: dir, AttachmentLimit: 1024}, service.NewInMemoryStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(replica.Close)
+
+	// A checkpoint without the event, e.g. after the primary archived it,
+	// drops it from the replica.
+	replica.Storage.PutEvent(event)
+	replica.Storage.Restore(nil)
+
+	rec := do(t, primary, http.MethodGet, "/download_attachment?id="+uploaded.Attachment.ID, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "agenda" {
+		t.Errorf("unexpected download %q", rec.Body.String())
+	}
+}
+
+func TestDocsRoutes(t *testing.T) {
+	a := newTestApp(t)
+
+	rec := do(t, a, http.MethodGet, "/openapi.json", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var document map[string]interface{}
+	decode(t, rec, &document)
+	if document["openapi"] == nil || document["paths"] == nil {
+		t.Errorf("unexpected OpenAPI document: %v", document)
+	}
+
+	rec = do(t, a, http.MethodGet, "/docs", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "/create_event") {
+		t.Error("docs page does not list /create_event")
+	}
+}
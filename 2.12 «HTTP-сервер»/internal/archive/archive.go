@@ -0,0 +1,350 @@
+package archive
+
+import (
+	"bufio"
+	"calendar/internal/service"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix  = "events-"
+	fileSuffix  = ".jsonl.gz"
+	monthLayout = "2006-01"
+)
+
+// Archive keeps events that left the storage, one gzip-compressed JSON
+// Lines file per month of the event date (UTC). Every Append adds a gzip
+// member to the end of the file, which gzip readers treat as one stream.
+type Archive struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+type Month struct {
+	Month  string `json:"month"`
+	Events int    `json:"events"`
+	Bytes  int64  `json:"bytes"`
+}
+
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Archive{dir: dir}, nil
+}
+
+func monthStart(date time.Time) time.Time {
+	date = date.UTC()
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (a *Archive) path(month time.Time) string {
+	return filepath.Join(a.dir, filePrefix+month.Format(monthLayout)+fileSuffix)
+}
+
+func (a *Archive) Append(events []service.Event) error {
+	byMonth := make(map[time.Time][]service.Event)
+	for _, event := range events {
+		month := monthStart(event.Date)
+		byMonth[month] = append(byMonth[month], event)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for month, monthEvents := range byMonth {
+		if err := a.appendMonth(month, monthEvents); err != nil {
+			return fmt.Errorf("archive %s: %w", month.Format(monthLayout), err)
+		}
+	}
+	return nil
+}
+
+// appendMonth truncates the file back to its previous size when writing
+// fails, so a half-written gzip member never makes the month unreadable.
+func (a *Archive) appendMonth(month time.Time, events []service.Event) error {
+	file, err := os.OpenFile(a.path(month), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := writeMember(file, events); err != nil {
+		file.Truncate(info.Size())
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeMember(file *os.File, events []service.Event) error {
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func (a *Archive) readMonth(month time.Time) ([]service.Event, error) {
+	// An event archived again after a failed run appears twice; the later
+	// line wins.
+	byID := make(map[string]int)
+	var events []service.Event
+	err := a.scanMonth(month, func(event service.Event) {
+		if i, ok := byID[event.ID]; ok && event.ID != "" {
+			events[i] = event
+			return
+		}
+		byID[event.ID] = len(events)
+		events = append(events, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// scanMonth calls fn for every line of the month file in order, without
+// keeping the events in memory.
+func (a *Archive) scanMonth(month time.Time, fn func(service.Event)) error {
+	file, err := os.Open(a.path(month))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", month.Format(monthLayout), err)
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var event service.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("archive %s: %w", month.Format(monthLayout), err)
+		}
+		fn(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("archive %s: %w", month.Format(monthLayout), err)
+	}
+	return nil
+}
+
+// Range returns the archived events that start in [start, end), sorted
+// like the storage sorts them.
+func (a *Archive) Range(start, end time.Time) ([]service.Event, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	months, err := a.months()
+	if err != nil {
+		return nil, err
+	}
+	var events []service.Event
+	for _, month := range months {
+		if !month.AddDate(0, 1, 0).After(start) || !month.Before(end) {
+			continue
+		}
+		monthEvents, err := a.readMonth(month)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range monthEvents {
+			if !event.Date.Before(start) && event.Date.Before(end) {
+				events = append(events, event)
+			}
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// Events returns every archived event, oldest month first.
+func (a *Archive) Events() ([]service.Event, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	months, err := a.months()
+	if err != nil {
+		return nil, err
+	}
+	var events []service.Event
+	for _, month := range months {
+		monthEvents, err := a.readMonth(month)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, monthEvents...)
+	}
+	return events, nil
+}
+
+// Event returns the archived event with the given ID.
+func (a *Archive) Event(id string) (service.Event, bool, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	months, err := a.months()
+	if err != nil {
+		return service.Event{}, false, err
+	}
+	var found service.Event
+	ok := false
+	for _, month := range months {
+		err := a.scanMonth(month, func(event service.Event) {
+			if event.ID == id {
+				found, ok = event, true
+			}
+		})
+		if err != nil {
+			return service.Event{}, false, err
+		}
+	}
+	return found, ok, nil
+}
+
+// ByExternalID returns the archived events with one of the external IDs,
+// oldest month first. Only the matching events are held in memory.
+func (a *Archive) ByExternalID(ids []string) ([]service.Event, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = id != ""
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	months, err := a.months()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]int)
+	var events []service.Event
+	for _, month := range months {
+		err := a.scanMonth(month, func(event service.Event) {
+			if !wanted[event.ExternalID] {
+				return
+			}
+			if i, ok := byID[event.ExternalID]; ok {
+				events[i] = event
+				return
+			}
+			byID[event.ExternalID] = len(events)
+			events = append(events, event)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func (a *Archive) months() ([]time.Time, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil, err
+	}
+	var months []time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		month, err := time.Parse(monthLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months, nil
+}
+
+// Months lists the archived months, oldest first.
+func (a *Archive) Months() ([]Month, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	months, err := a.months()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Month, 0, len(months))
+	for _, month := range months {
+		events, err := a.readMonth(month)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(a.path(month))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Month{Month: month.Format(monthLayout), Events: len(events), Bytes: info.Size()})
+	}
+	return result, nil
+}
+
+// PurgeBefore deletes the archived months that end on or before the given
+// time and returns the events they held.
+func (a *Archive) PurgeBefore(before time.Time) ([]service.Event, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	months, err := a.months()
+	if err != nil {
+		return nil, err
+	}
+	var purged []service.Event
+	for _, month := range months {
+		if month.AddDate(0, 1, 0).After(before) {
+			break
+		}
+		events, err := a.readMonth(month)
+		if err != nil {
+			return purged, err
+		}
+		if err := os.Remove(a.path(month)); err != nil {
+			return purged, err
+		}
+		purged = append(purged, events...)
+	}
+	return purged, nil
+}
+
+func sortEvents(events []service.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date.Equal(events[j].Date) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Date.Before(events[j].Date)
+	})
+}
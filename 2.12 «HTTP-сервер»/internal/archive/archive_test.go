@@ -0,0 +1,256 @@
+package archive
+
+import (
+	"calendar/internal/service"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func titles(events []service.Event) string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Title)
+	}
+	return fmt.Sprint(result)
+}
+
+func TestArchiveAppendAndRange(t *testing.T) {
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := day(2023, 3, 5).Add(time.Hour)
+	if err := a.Append([]service.Event{
+		{ID: "1", Title: "kickoff", Date: day(2023, 3, 5), End: &end},
+		{ID: "2", Title: "retro", Date: day(2023, 3, 31)},
+		{ID: "3", Title: "planning", Date: day(2023, 4, 1)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// A second append to the same month adds a gzip member; a repeated ID
+	// replaces the earlier copy.
+	if err := a.Append([]service.Event{
+		{ID: "2", Title: "retrospective", Date: day(2023, 3, 31)},
+		{ID: "4", Title: "review", Date: day(2023, 3, 10)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		start, end time.Time
+		want       string
+	}{
+		{day(2023, 3, 1), day(2023, 4, 1), "[kickoff review retrospective]"},
+		{day(2023, 3, 6), day(2023, 4, 2), "[review retrospective planning]"},
+		{time.Time{}, day(2100, 1, 1), "[kickoff review retrospective planning]"},
+		{day(2023, 5, 1), day(2023, 6, 1), "[]"},
+	}
+	for _, tt := range tests {
+		events, err := a.Range(tt.start, tt.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(events); got != tt.want {
+			t.Errorf("Range(%s, %s) = %s, want %s", tt.start.Format("2006-01-02"), tt.end.Format("2006-01-02"), got, tt.want)
+		}
+	}
+
+	events, _ := a.Range(day(2023, 3, 5), day(2023, 3, 6))
+	if len(events) != 1 || events[0].End == nil || !events[0].End.Equal(end) {
+		t.Errorf("timed event did not round-trip: %+v", events)
+	}
+
+	months, err := a.Months()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 2 || months[0].Month != "2023-03" || months[0].Events != 3 || months[1].Month != "2023-04" || months[0].Bytes == 0 {
+		t.Errorf("unexpected months %+v", months)
+	}
+}
+
+func TestArchivePurgeBefore(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Append([]service.Event{
+		{ID: "1", Title: "old", Date: day(2020, 1, 15)},
+		{ID: "2", Title: "older", Date: day(2019, 12, 1)},
+		{ID: "3", Title: "kept", Date: day(2020, 2, 1)},
+	})
+
+	purged, err := a.PurgeBefore(day(2020, 2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(purged); got != "[older old]" {
+		t.Errorf("purged %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "events-2020-01.jsonl.gz")); !os.IsNotExist(err) {
+		t.Errorf("purged month file still exists: %v", err)
+	}
+	if events, _ := a.Range(time.Time{}, day(2100, 1, 1)); titles(events) != "[kept]" {
+		t.Errorf("remaining %s", titles(events))
+	}
+
+	// A month is only purged once it has ended.
+	if purged, _ := a.PurgeBefore(day(2020, 2, 20)); len(purged) != 0 {
+		t.Errorf("purged a month that is not over: %s", titles(purged))
+	}
+}
+
+func TestArchiveByExternalID(t *testing.T) {
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Append([]service.Event{
+		{ID: "1", Title: "retro", Date: day(2020, 1, 15), ExternalID: "x-1"},
+		{ID: "2", Title: "planning", Date: day(2020, 2, 1), ExternalID: "x-2"},
+		{ID: "3", Title: "no id", Date: day(2020, 2, 3)},
+	})
+	a.Append([]service.Event{{ID: "1", Title: "retrospective", Date: day(2020, 1, 15), ExternalID: "x-1"}})
+
+	events, err := a.ByExternalID([]string{"x-1", "x-9", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(events); got != "[retrospective]" {
+		t.Errorf("found %s", got)
+	}
+	if events, _ := a.ByExternalID(nil); len(events) != 0 {
+		t.Errorf("no IDs found %s", titles(events))
+	}
+}
+
+func TestArchiveEvent(t *testing.T) {
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Append([]service.Event{{ID: "1", Title: "retro", Date: day(2020, 1, 15)}})
+	a.Append([]service.Event{{ID: "1", Title: "retrospective", Date: day(2020, 1, 15)}})
+
+	if event, ok, err := a.Event("1"); err != nil || !ok || event.Title != "retrospective" {
+		t.Errorf("Event(1) = %+v, %t, %v", event, ok, err)
+	}
+	if _, ok, err := a.Event("2"); err != nil || ok {
+		t.Errorf("Event(2) found: %t, %v", ok, err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		wantErr bool
+	}{
+		{Policy{}, false},
+		{Policy{ArchiveAfterMonths: 6}, false},
+		{Policy{PurgeAfterYears: 3}, false},
+		{Policy{ArchiveAfterMonths: 6, PurgeAfterYears: 2}, false},
+		{Policy{ArchiveAfterMonths: 24, PurgeAfterYears: 2}, true},
+		{Policy{ArchiveAfterMonths: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: error = %v, wantErr %t", tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJobRun(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	for _, event := range []service.Event{
+		{Title: "ancient", Date: day(2021, 6, 1)},
+		{Title: "last year", Date: day(2023, 11, 30)},
+		{Title: "cutoff month", Date: day(2024, 4, 1)},
+		{Title: "recent", Date: day(2024, 9, 1)},
+	} {
+		storage.CreateEvent(event)
+	}
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var purged []service.Event
+	job := &Job{
+		Storage: storage,
+		Archive: a,
+		Policy:  Policy{ArchiveAfterMonths: 6, PurgeAfterYears: 2},
+		OnPurge: func(events []service.Event) { purged = append(purged, events...) },
+	}
+	report, err := job.Run(time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.ArchiveCutoff.Equal(day(2024, 4, 1)) || !report.PurgeCutoff.Equal(day(2022, 10, 1)) || report.Archived != 2 || report.Purged != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if got := titles(storage.GetEvent()); got != "[cutoff month recent]" {
+		t.Errorf("storage keeps %s", got)
+	}
+	if events, _ := a.Range(time.Time{}, day(2100, 1, 1)); titles(events) != "[last year]" {
+		t.Errorf("archive holds %s", titles(events))
+	}
+	if titles(purged) != "[ancient]" || purged[0].ID == "" {
+		t.Errorf("OnPurge got %+v", purged)
+	}
+}
+
+func TestJobRollsBackFailedArchive(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	storage.CreateEvent(service.Event{Title: "old", Date: day(2020, 1, 1)})
+	dir := filepath.Join(t.TempDir(), "archive")
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(dir)
+
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) { changes = append(changes, change.Type) })
+
+	job := &Job{Storage: storage, Archive: a, Policy: Policy{ArchiveAfterMonths: 1}}
+	if _, err := job.Run(day(2024, 1, 1)); err == nil {
+		t.Fatal("expected an error when the archive cannot be written")
+	}
+	if got := titles(storage.GetEvent()); got != "[old]" {
+		t.Errorf("event was lost, storage keeps %s", got)
+	}
+	if len(changes) != 0 {
+		t.Errorf("failed archiving notified %v", changes)
+	}
+}
+
+func TestJobPurgesWithoutArchive(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	storage.CreateEvent(service.Event{Title: "old", Date: day(2020, 1, 1)})
+	storage.CreateEvent(service.Event{Title: "new", Date: day(2023, 6, 1)})
+
+	var deleted []string
+	storage.Subscribe(func(change service.Change) {
+		if change.Type == service.EventDeleted {
+			deleted = append(deleted, change.Event.Title)
+		}
+	})
+
+	job := &Job{Storage: storage, Policy: Policy{PurgeAfterYears: 1}}
+	report, err := job.Run(day(2024, 1, 15))
+	if err != nil || report.Purged != 1 || report.ArchiveCutoff != nil {
+		t.Fatalf("unexpected report %+v, %v", report, err)
+	}
+	if titles(storage.GetEvent()) != "[new]" || fmt.Sprint(deleted) != "[old]" {
+		t.Errorf("storage keeps %s, deleted %v", titles(storage.GetEvent()), deleted)
+	}
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"calendar/internal/service"
+	"errors"
+	"log"
+	"reflect"
+	"time"
+)
+
+// Policy moves events out of the storage once they are ArchiveAfterMonths
+// old and drops them for good once they are PurgeAfterYears old. Ages are
+// counted in whole calendar months, so an archive file is only ever purged
+// as a whole. Zero disables a step.
+type Policy struct {
+	ArchiveAfterMonths int
+	PurgeAfterYears    int
+}
+
+func (p Policy) Validate() error {
+	if p.ArchiveAfterMonths < 0 || p.PurgeAfterYears < 0 {
+		return errors.New("retention ages must not be negative")
+	}
+	if p.ArchiveAfterMonths > 0 && p.PurgeAfterYears > 0 && p.PurgeAfterYears*12 <= p.ArchiveAfterMonths {
+		return errors.New("events must be archived before they are purged")
+	}
+	return nil
+}
+
+func (p Policy) Enabled() bool {
+	return p.ArchiveAfterMonths > 0 || p.PurgeAfterYears > 0
+}
+
+func (p Policy) ArchiveCutoff(now time.Time) time.Time {
+	return monthStart(now).AddDate(0, -p.ArchiveAfterMonths, 0)
+}
+
+func (p Policy) PurgeCutoff(now time.Time) time.Time {
+	return monthStart(now).AddDate(-p.PurgeAfterYears, 0, 0)
+}
+
+type Report struct {
+	ArchiveCutoff *time.Time `json:"archive_cutoff,omitempty"`
+	PurgeCutoff   *time.Time `json:"purge_cutoff,omitempty"`
+	Archived      int        `json:"archived"`
+	Purged        int        `json:"purged"`
+}
+
+// Job applies a Policy. Without an Archive it cannot archive, and purging
+// deletes old events straight from the storage. OnPurge is called with the
+// events dropped from the archive, whose storage listeners never hear of it.
+type Job struct {
+	Storage service.Storage
+	Archive *Archive
+	Policy  Policy
+	OnPurge func(events []service.Event)
+}
+
+func (j *Job) Run(now time.Time) (Report, error) {
+	var report Report
+
+	if j.Policy.ArchiveAfterMonths > 0 && j.Archive != nil {
+		cutoff := j.Policy.ArchiveCutoff(now)
+		report.ArchiveCutoff = &cutoff
+
+		// Events are written to the archive before they leave the storage,
+		// so a failed write changes nothing and no listener hears of it.
+		written := make(map[string]service.Event)
+		pending := j.Storage.GetEventsForRange(time.Time{}, cutoff)
+		if err := j.Archive.Append(pending); err != nil {
+			return report, err
+		}
+		for _, event := range pending {
+			written[event.ID] = event
+		}
+
+		// Events created or changed in the meantime still need writing; the
+		// archive keeps the later copy of an event archived twice.
+		archived := j.Storage.ArchiveEventsBefore(cutoff)
+		var changed []service.Event
+		for _, event := range archived {
+			if !reflect.DeepEqual(written[event.ID], event) {
+				changed = append(changed, event)
+			}
+		}
+		if err := j.Archive.Append(changed); err != nil {
+			for _, event := range changed {
+				j.Storage.PutEvent(event)
+			}
+			return report, err
+		}
+		report.Archived = len(archived)
+	}
+
+	if j.Policy.PurgeAfterYears > 0 {
+		cutoff := j.Policy.PurgeCutoff(now)
+		report.PurgeCutoff = &cutoff
+
+		for _, event := range j.Storage.GetEventsForRange(time.Time{}, cutoff) {
+			if j.Storage.DeleteEvent(event.ID) {
+				report.Purged++
+			}
+		}
+		if j.Archive != nil {
+			purged, err := j.Archive.PurgeBefore(cutoff)
+			report.Purged += len(purged)
+			if len(purged) > 0 && j.OnPurge != nil {
+				j.OnPurge(purged)
+			}
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// RunEvery runs the job now and then every interval until stop is closed.
+func (j *Job) RunEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.Run(time.Now())
+		if err != nil {
+			log.Printf("Retention run failed: %v", err)
+		} else if report.Archived > 0 || report.Purged > 0 {
+			log.Printf("Retention archived %d and purged %d events", report.Archived, report.Purged)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package attachment
+
+import (
+	"bytes"
+	"calendar/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const DefaultMaxSize = 10 << 20
+
+var ErrTooLarge = errors.New("attachment is too large")
+var ErrEmpty = errors.New("attachment is empty")
+
+type Attachment struct {
+	ID          string    `json:"id"`
+	EventID     string    `json:"event_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Registry struct {
+	blobs   BlobStore
+	maxSize int64
+
+	mu          sync.Mutex
+	attachments map[string]Attachment
+}
+
+// indexKey names the blob that keeps the metadata of all attachments next
+// to their contents, so a DirStore survives restarts. Attachment IDs are
+// UUIDs and never collide with it.
+const indexKey = "index.json"
+
+// NewRegistry returns a registry of the attachments whose metadata blobs
+// already holds.
+func NewRegistry(blobs BlobStore, maxSize int64) (*Registry, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	rg := &Registry{
+		blobs:       blobs,
+		maxSize:     maxSize,
+		attachments: make(map[string]Attachment),
+	}
+
+	index, err := blobs.Open(indexKey)
+	if errors.Is(err, ErrBlobNotFound) {
+		return rg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	var attachments []Attachment
+	if err := json.NewDecoder(index).Decode(&attachments); err != nil {
+		return nil, fmt.Errorf("invalid attachment index: %w", err)
+	}
+	for _, a := range attachments {
+		rg.attachments[a.ID] = a
+	}
+	return rg, nil
+}
+
+// saveIndex writes the metadata of all attachments. The caller holds rg.mu,
+// so indexes are written in the order of the changes.
+func (rg *Registry) saveIndex() error {
+	attachments := make([]Attachment, 0, len(rg.attachments))
+	for _, a := range rg.attachments {
+		attachments = append(attachments, a)
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID < attachments[j].ID })
+
+	data, err := json.Marshal(attachments)
+	if err != nil {
+		return err
+	}
+	return rg.blobs.Put(indexKey, bytes.NewReader(data))
+}
+
+func (rg *Registry) MaxSize() int64 {
+	return rg.maxSize
+}
+
+// Add stores the contents of r as an attachment of eventID. The content
+// type is sniffed from the data rather than trusted from the client.
+func (rg *Registry) Add(eventID, name string, r io.Reader) (Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, rg.maxSize+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if int64(len(data)) > rg.maxSize {
+		return Attachment{}, ErrTooLarge
+	}
+	if len(data) == 0 {
+		return Attachment{}, ErrEmpty
+	}
+
+	a := Attachment{
+		ID:          uuid.New().String(),
+		EventID:     eventID,
+		Name:        filepath.Base(name),
+		ContentType: http.DetectContentType(data),
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := rg.blobs.Put(a.ID, bytes.NewReader(data)); err != nil {
+		return Attachment{}, err
+	}
+
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.attachments[a.ID] = a
+	if err := rg.saveIndex(); err != nil {
+		delete(rg.attachments, a.ID)
+		rg.blobs.Delete(a.ID)
+		return Attachment{}, err
+	}
+	return a, nil
+}
+
+func (rg *Registry) Get(id string) (Attachment, bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	a, ok := rg.attachments[id]
+	return a, ok
+}
+
+func (rg *Registry) Open(id string) (Attachment, io.ReadCloser, error) {
+	a, ok := rg.Get(id)
+	if !ok {
+		return Attachment{}, nil, ErrBlobNotFound
+	}
+	body, err := rg.blobs.Open(id)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	return a, body, nil
+}
+
+func (rg *Registry) List(eventID string) []Attachment {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	attachments := make([]Attachment, 0)
+	for _, a := range rg.attachments {
+		if a.EventID == eventID {
+			attachments = append(attachments, a)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		if !attachments[i].CreatedAt.Equal(attachments[j].CreatedAt) {
+			return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+		}
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments
+}
+
+func (rg *Registry) Delete(id string) (bool, error) {
+	rg.mu.Lock()
+	a, exists := rg.attachments[id]
+	if !exists {
+		rg.mu.Unlock()
+		return false, nil
+	}
+	delete(rg.attachments, id)
+	if err := rg.saveIndex(); err != nil {
+		rg.attachments[id] = a
+		rg.mu.Unlock()
+		return true, err
+	}
+	rg.mu.Unlock()
+
+	return true, rg.blobs.Delete(id)
+}
+
+func (rg *Registry) DeleteForEvent(eventID string) error {
+	var errs []error
+	for _, a := range rg.List(eventID) {
+		if _, err := rg.Delete(a.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandleChange removes the attachments of deleted events. Subscribe it to
+// the event storage.
+func (rg *Registry) HandleChange(change service.Change) {
+	if change.Type != service.EventDeleted {
+		return
+	}
+	if err := rg.DeleteForEvent(change.Event.ID); err != nil {
+		log.Printf("Failed to delete attachments of event %s: %v", change.Event.ID, err)
+	}
+}
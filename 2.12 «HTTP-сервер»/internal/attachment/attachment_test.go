@@ -0,0 +1,133 @@
+package attachment
+
+import (
+	"bytes"
+	"calendar/internal/service"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRegistry(t *testing.T, blobs BlobStore, maxSize int64) *Registry {
+	t.Helper()
+
+	rg, err := NewRegistry(blobs, maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rg
+}
+
+func TestAddSizeLimit(t *testing.T) {
+	rg := newRegistry(t, NewMemoryStore(), 8)
+
+	if _, err := rg.Add("e1", "full.txt", strings.NewReader("12345678")); err != nil {
+		t.Errorf("attachment of exactly the limit was rejected: %v", err)
+	}
+	if _, err := rg.Add("e1", "big.txt", strings.NewReader("123456789")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("attachment over the limit returned %v", err)
+	}
+	if _, err := rg.Add("e1", "empty.txt", strings.NewReader("")); !errors.Is(err, ErrEmpty) {
+		t.Errorf("empty attachment returned %v", err)
+	}
+	if listed := rg.List("e1"); len(listed) != 1 || listed[0].Name != "full.txt" {
+		t.Errorf("rejected attachments were kept: %+v", listed)
+	}
+
+	if rg := newRegistry(t, NewMemoryStore(), 0); rg.MaxSize() != DefaultMaxSize {
+		t.Errorf("default limit is %d", rg.MaxSize())
+	}
+}
+
+func TestAddSniffsContentType(t *testing.T) {
+	rg := newRegistry(t, NewMemoryStore(), DefaultMaxSize)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+	}{
+		// The name and its extension never decide the type.
+		{"report.txt", []byte("%PDF-1.4 report"), "application/pdf"},
+		{"photo.pdf", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"page.png", []byte("<!DOCTYPE html><html></html>"), "text/html; charset=utf-8"},
+		{"notes", []byte("plain notes"), "text/plain; charset=utf-8"},
+		{"blob.txt", []byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+	for _, test := range tests {
+		a, err := rg.Add("e1", "../"+test.name, bytes.NewReader(test.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.ContentType != test.contentType || a.Name != test.name || a.Size != int64(len(test.data)) {
+			t.Errorf("%s: got %+v, want type %s", test.name, a, test.contentType)
+		}
+	}
+}
+
+func TestDeletedEventsCascade(t *testing.T) {
+	blobs := NewMemoryStore()
+	rg := newRegistry(t, blobs, DefaultMaxSize)
+	storage := service.NewInMemoryStorage()
+	storage.Subscribe(rg.HandleChange)
+
+	storage.PutEvent(service.Event{ID: "e1", Title: "planning"})
+	storage.PutEvent(service.Event{ID: "e2", Title: "retro"})
+	first, _ := rg.Add("e1", "agenda.txt", strings.NewReader("agenda"))
+	rg.Add("e1", "notes.txt", strings.NewReader("notes"))
+	kept, _ := rg.Add("e2", "actions.txt", strings.NewReader("actions"))
+
+	storage.UpdateEvent(service.Event{ID: "e1", Title: "planning moved"})
+	if listed := rg.List("e1"); len(listed) != 2 {
+		t.Fatalf("updating the event removed attachments: %+v", listed)
+	}
+
+	storage.DeleteEvent("e1")
+	if listed := rg.List("e1"); len(listed) != 0 {
+		t.Errorf("attachments survived their event: %+v", listed)
+	}
+	if _, err := blobs.Open(first.ID); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("blob survived its event: %v", err)
+	}
+	if listed := rg.List("e2"); len(listed) != 1 || listed[0].ID != kept.ID {
+		t.Errorf("attachments of another event were deleted: %+v", listed)
+	}
+}
+
+func TestMetadataSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	blobs, err := NewDirStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rg := newRegistry(t, blobs, DefaultMaxSize)
+	kept, _ := rg.Add("e1", "agenda.txt", strings.NewReader("agenda"))
+	deleted, _ := rg.Add("e1", "draft.txt", strings.NewReader("draft"))
+	if _, err := rg.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newRegistry(t, blobs, DefaultMaxSize)
+	listed := reopened.List("e1")
+	if len(listed) != 1 || listed[0].ID != kept.ID || listed[0].Name != "agenda.txt" || listed[0].ContentType != kept.ContentType {
+		t.Fatalf("reopened registry lists %+v", listed)
+	}
+	_, body, err := reopened.Open(kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "agenda" {
+		t.Errorf("reopened attachment holds %q", data)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, indexKey), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRegistry(blobs, DefaultMaxSize); err == nil {
+		t.Error("a corrupt index was accepted")
+	}
+}
@@ -0,0 +1,300 @@
+package booking
+
+import (
+	"calendar/internal/holiday"
+	"calendar/internal/service"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSlotTaken = errors.New("slot is already taken")
+var ErrInvalidSlot = errors.New("not an available slot")
+var ErrSlugTaken = errors.New("a booking page with this slug already exists")
+
+type Window struct {
+	Weekday time.Weekday `json:"weekday"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+type Page struct {
+	Slug        string   `json:"slug"`
+	Title       string   `json:"title"`
+	Owner       string   `json:"owner"`
+	SlotMinutes int      `json:"slot_minutes"`
+	TimeZone    string   `json:"time_zone"`
+	Windows     []Window `json:"windows"`
+	// HolidayCalendar names the holiday overlay whose non-working days
+	// offer no slots.
+	HolidayCalendar string `json:"holiday_calendar,omitempty"`
+	// CalendarID names the calendar booked events are put into.
+	CalendarID string `json:"calendar_id,omitempty"`
+}
+
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type Registry struct {
+	mu    sync.Mutex
+	pages map[string]Page
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		pages: make(map[string]Page),
+	}
+}
+
+// Save adds the page unless another page already uses its slug.
+func (rg *Registry) Save(page Page) error {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if _, exists := rg.pages[page.Slug]; exists {
+		return ErrSlugTaken
+	}
+	rg.pages[page.Slug] = page
+	return nil
+}
+
+func (rg *Registry) Get(slug string) (Page, bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	page, ok := rg.pages[slug]
+	return page, ok
+}
+
+func (rg *Registry) Delete(slug string) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	_, exists := rg.pages[slug]
+	if exists {
+		delete(rg.pages, slug)
+	}
+	return exists
+}
+
+func (rg *Registry) List() []Page {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	pages := make([]Page, 0, len(rg.pages))
+	for _, page := range rg.pages {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// Restore replaces all pages.
+func (rg *Registry) Restore(pages []Page) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.pages = make(map[string]Page, len(pages))
+	for _, page := range pages {
+		rg.pages[page.Slug] = page
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWindows parses availability such as "mon-fri 09:00-17:00" or
+// "sat 10:00-12:00" into one Window per weekday.
+func ParseWindows(value string) ([]Window, error) {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid window %q, expected e.g. mon-fri 09:00-17:00", value)
+	}
+
+	days := strings.SplitN(fields[0], "-", 2)
+	first, ok := weekdays[days[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid weekday %q", days[0])
+	}
+	last := first
+	if len(days) == 2 {
+		if last, ok = weekdays[days[1]]; !ok {
+			return nil, fmt.Errorf("invalid weekday %q", days[1])
+		}
+	}
+
+	hours := strings.SplitN(fields[1], "-", 2)
+	if len(hours) != 2 {
+		return nil, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", fields[1])
+	}
+	start, err := parseClock(hours[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(hours[1])
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("window %q ends before it starts", fields[1])
+	}
+
+	var windows []Window
+	for day := first; ; day = (day + 1) % 7 {
+		windows = append(windows, Window{Weekday: day, Start: hours[0], End: hours[1]})
+		if day == last {
+			break
+		}
+	}
+	return windows, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// atClock returns the wall-clock time of a window bound on the given day.
+// Unlike adding the offset to midnight it stays right on days when the
+// clocks change.
+func atClock(day time.Time, value string) time.Time {
+	clock, _ := parseClock(value)
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+func (p Page) Location() *time.Location {
+	location, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func (p Page) slotLength() time.Duration {
+	return time.Duration(p.SlotMinutes) * time.Minute
+}
+
+// candidateSlots returns every slot of the availability windows that
+// start within [from, to), ignoring existing events. Days that workdays
+// marks as non-working are skipped.
+func (p Page) candidateSlots(from, to time.Time, workdays *holiday.Calendar) []Slot {
+	location := p.Location()
+	length := p.slotLength()
+	if length <= 0 {
+		return nil
+	}
+
+	var slots []Slot
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location).AddDate(0, 0, -1)
+	for day := first; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, window := range p.Windows {
+			if window.Weekday != day.Weekday() {
+				continue
+			}
+			if workdays != nil && !workdays.IsWorkingDay(day) {
+				continue
+			}
+			windowEnd := atClock(day, window.End)
+			for slotStart := atClock(day, window.Start); !slotStart.Add(length).After(windowEnd); slotStart = slotStart.Add(length) {
+				if !slotStart.Before(from) && slotStart.Before(to) {
+					slots = append(slots, Slot{Start: slotStart, End: slotStart.Add(length)})
+				}
+			}
+		}
+	}
+	return slots
+}
+
+// busyCalendars returns the IDs of the calendars whose events take the
+// time of the page owner: every calendar of the owner and the page
+// calendar. Without a page calendar bookings land outside any calendar, so
+// such events count as well. Events of other people's calendars never
+// block a slot.
+func (p Page) busyCalendars(storage service.Storage) map[string]bool {
+	busy := map[string]bool{p.CalendarID: true}
+	if p.Owner != "" {
+		for _, calendar := range storage.GetCalendars() {
+			if calendar.Owner == p.Owner {
+				busy[calendar.ID] = true
+			}
+		}
+	}
+	return busy
+}
+
+func (p Page) OpenSlots(storage service.Storage, workdays *holiday.Calendar, from, to, now time.Time) []Slot {
+	candidates := p.candidateSlots(from, to, workdays)
+	calendars := p.busyCalendars(storage)
+	var busy []service.Event
+	for _, event := range storage.GetEventsForRange(from.AddDate(0, 0, -1), to) {
+		if calendars[event.CalendarID] {
+			busy = append(busy, event)
+		}
+	}
+
+	location := p.Location()
+	var open []Slot
+	for _, slot := range candidates {
+		if slot.Start.Before(now) {
+			continue
+		}
+		free := true
+		for _, event := range busy {
+			free = free && !blocks(slot, event, location)
+		}
+		if free {
+			open = append(open, slot)
+		}
+	}
+	return open
+}
+
+// blocks reports whether the event overlaps the slot. An all-day event
+// takes its whole day in location, the time zone of the page.
+func blocks(slot Slot, event service.Event, location *time.Location) bool {
+	if event.Timed() {
+		return event.Overlaps(slot.Start, slot.End)
+	}
+	year, month, day := event.Date.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, location)
+	return start.Before(slot.End) && start.AddDate(0, 0, 1).After(slot.Start)
+}
+
+func (p Page) Book(storage service.Storage, workdays *holiday.Calendar, start time.Time, name, email string, now time.Time) (service.Event, error) {
+	if start.Before(now) {
+		return service.Event{}, ErrInvalidSlot
+	}
+
+	var slot *Slot
+	for _, candidate := range p.candidateSlots(start, start.Add(time.Minute), workdays) {
+		if candidate.Start.Equal(start) {
+			slot = &candidate
+			break
+		}
+	}
+	if slot == nil {
+		return service.Event{}, ErrInvalidSlot
+	}
+
+	title := p.Title + ": " + name
+	if email != "" {
+		title += " <" + email + ">"
+	}
+	end := slot.End.UTC()
+	calendars, location := p.busyCalendars(storage), p.Location()
+	event, ok := storage.CreateEventIfFree(service.Event{Title: title, Date: slot.Start.UTC(), End: &end, CalendarID: p.CalendarID}, func(existing service.Event) bool {
+		return calendars[existing.CalendarID] && blocks(*slot, existing, location)
+	})
+	if !ok {
+		return service.Event{}, ErrSlotTaken
+	}
+	return event, nil
+}
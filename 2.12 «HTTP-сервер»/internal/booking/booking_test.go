@@ -0,0 +1,112 @@
+package booking
+
+import (
+	"calendar/internal/service"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testPage(t *testing.T, timeZone, windows string) Page {
+	t.Helper()
+
+	parsed, err := ParseWindows(windows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Page{Slug: "intro", Title: "Intro", SlotMinutes: 30, TimeZone: timeZone, Windows: parsed}
+}
+
+func TestSlotsOnDSTDays(t *testing.T) {
+	page := testPage(t, "Europe/Berlin", "sun 09:00-10:00")
+	location := page.Location()
+	if location == time.UTC {
+		t.Skip("time zone database is not available")
+	}
+
+	// Clocks go forward on 31 March 2024 and back on 27 October 2024.
+	for _, day := range []time.Time{
+		time.Date(2024, 3, 31, 0, 0, 0, 0, location),
+		time.Date(2024, 10, 27, 0, 0, 0, 0, location),
+	} {
+		slots := page.OpenSlots(service.NewInMemoryStorage(), nil, day, day.AddDate(0, 0, 1), day.AddDate(0, 0, -1))
+		if len(slots) != 2 {
+			t.Fatalf("%s: got slots %v", day.Format("2006-01-02"), slots)
+		}
+		for i, clock := range []string{"09:00", "09:30"} {
+			if got := slots[i].Start.Format("15:04"); got != clock {
+				t.Errorf("%s: slot %d starts at %s, want %s", day.Format("2006-01-02"), i, got, clock)
+			}
+		}
+	}
+}
+
+func TestAllDayEventsBlockSlots(t *testing.T) {
+	page := testPage(t, "Asia/Tokyo", "mon-tue 09:00-10:00")
+	location := page.Location()
+	storage := service.NewInMemoryStorage()
+	// 2099-01-05 is a Monday; all-day events are stored at UTC midnight.
+	storage.CreateEvent(service.Event{Title: "day off", Date: time.Date(2099, 1, 5, 0, 0, 0, 0, time.UTC)})
+
+	monday := time.Date(2099, 1, 5, 0, 0, 0, 0, location)
+	now := monday.AddDate(0, 0, -1)
+	slots := page.OpenSlots(storage, nil, monday, monday.AddDate(0, 0, 2), now)
+	if len(slots) != 2 || slots[0].Start.Day() != 6 {
+		t.Errorf("all-day event left slots %v", slots)
+	}
+
+	if _, err := page.Book(storage, nil, monday.Add(9*time.Hour), "Ana", "", now); !errors.Is(err, ErrSlotTaken) {
+		t.Errorf("booking during an all-day event returned %v", err)
+	}
+	if _, err := page.Book(storage, nil, monday.AddDate(0, 0, 1).Add(9*time.Hour), "Ana", "", now); err != nil {
+		t.Errorf("booking the next day returned %v", err)
+	}
+}
+
+func TestOnlyOwnerCalendarsBlockSlots(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	bookings := storage.CreateCalendar(service.Calendar{Name: "bookings", Owner: "ann"})
+	private := storage.CreateCalendar(service.Calendar{Name: "private", Owner: "ann"})
+	bobs := storage.CreateCalendar(service.Calendar{Name: "bob's", Owner: "bob"})
+	page := testPage(t, "UTC", "mon 09:00-11:00")
+	page.Owner, page.CalendarID = "ann", bookings.ID
+
+	// 2099-01-05 is a Monday.
+	monday := time.Date(2099, 1, 5, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		tm := monday.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &tm
+	}
+	storage.CreateEvent(service.Event{Title: "bob's meeting", Date: *at(9, 0), End: at(10, 0), CalendarID: bobs.ID})
+	storage.CreateEvent(service.Event{Title: "dentist", Date: *at(10, 0), End: at(10, 30), CalendarID: private.ID})
+
+	now := monday.AddDate(0, 0, -1)
+	slots := page.OpenSlots(storage, nil, monday, monday.AddDate(0, 0, 1), now)
+	if len(slots) != 3 || !slots[0].Start.Equal(*at(9, 0)) || !slots[2].Start.Equal(*at(10, 30)) {
+		t.Errorf("unexpected slots %v", slots)
+	}
+
+	event, err := page.Book(storage, nil, *at(9, 0), "Ana", "", now)
+	if err != nil || event.CalendarID != bookings.ID {
+		t.Fatalf("booking next to bob's meeting returned %+v, %v", event, err)
+	}
+	if _, err := page.Book(storage, nil, *at(9, 0), "Ben", "", now); !errors.Is(err, ErrSlotTaken) {
+		t.Errorf("booking a booked slot returned %v", err)
+	}
+	if _, err := page.Book(storage, nil, *at(10, 0), "Ben", "", now); !errors.Is(err, ErrSlotTaken) {
+		t.Errorf("booking during an event of another calendar of the owner returned %v", err)
+	}
+}
+
+func TestRegistrySaveRejectsTakenSlug(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Save(Page{Slug: "intro", Title: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Save(Page{Slug: "intro", Title: "second"}); !errors.Is(err, ErrSlugTaken) {
+		t.Errorf("saving a taken slug returned %v", err)
+	}
+	if page, _ := registry.Get("intro"); page.Title != "first" {
+		t.Errorf("taken slug was overwritten by %+v", page)
+	}
+}
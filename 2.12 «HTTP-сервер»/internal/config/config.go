@@ -1,11 +1,152 @@
 package config
 
-import "os"
+import (
+	"flag"
+	"os"
+	"strconv"
+	"time"
+)
 
-func LoadConfig() string {
-	port := os.Getenv("PORT")
-	if port == "" {
-		return "8080"
+type Config struct {
+	Port             string
+	SnapshotPath     string
+	SnapshotInterval time.Duration
+	RestoreOnStart   bool
+	WeekStart        string
+	HolidaysDir      string
+	TLSCert          string
+	TLSKey           string
+	ClientCA         string
+	ClientAuth       string
+	RedirectPort     string
+	CertPollInterval time.Duration
+	AttachmentsDir   string
+	AttachmentLimit  int64
+	DigestTemplates  string
+	DigestAt         string
+	DigestPeriod     string
+	DigestFormat     string
+	DigestTimeZone   string
+	DigestSender     string
+	DigestFrom       string
+	DigestTo         string
+	DigestCalendars  string
+	DigestOverlays   string
+
+	ReplicationRole    string
+	ReplicationPrimary string
+	ReplicationLogSize int
+	ReplicationSecret  string
+
+	ArchiveDir         string
+	ArchiveAfterMonths int
+	PurgeAfterYears    int
+	RetentionInterval  time.Duration
+
+	AdminToken string
+	UserTokens string
+}
+
+func LoadConfig() Config {
+	cfg := Config{
+		Port:             getEnv("PORT", "8080"),
+		SnapshotPath:     getEnv("SNAPSHOT_PATH", ""),
+		SnapshotInterval: getEnvDuration("SNAPSHOT_INTERVAL", 5*time.Minute),
+		RestoreOnStart:   getEnv("RESTORE_ON_START", "true") == "true",
+		WeekStart:        getEnv("WEEK_START", "sunday"),
+		HolidaysDir:      getEnv("HOLIDAYS_DIR", ""),
+		TLSCert:          getEnv("TLS_CERT", ""),
+		TLSKey:           getEnv("TLS_KEY", ""),
+		ClientCA:         getEnv("TLS_CLIENT_CA", ""),
+		ClientAuth:       getEnv("TLS_CLIENT_AUTH", "none"),
+		RedirectPort:     getEnv("HTTP_REDIRECT_PORT", ""),
+		CertPollInterval: getEnvDuration("TLS_POLL_INTERVAL", 30*time.Second),
+		AttachmentsDir:   getEnv("ATTACHMENTS_DIR", ""),
+		AttachmentLimit:  getEnvInt64("ATTACHMENT_LIMIT", 10<<20),
+		DigestTemplates:  getEnv("DIGEST_TEMPLATES", ""),
+		DigestAt:         getEnv("DIGEST_AT", ""),
+		DigestPeriod:     getEnv("DIGEST_PERIOD", "day"),
+		DigestFormat:     getEnv("DIGEST_FORMAT", "markdown"),
+		DigestTimeZone:   getEnv("DIGEST_TIME_ZONE", "UTC"),
+		DigestSender:     getEnv("DIGEST_SENDER", "log"),
+		DigestFrom:       getEnv("DIGEST_FROM", ""),
+		DigestTo:         getEnv("DIGEST_TO", ""),
+		DigestCalendars:  getEnv("DIGEST_CALENDARS", ""),
+		DigestOverlays:   getEnv("DIGEST_OVERLAYS", ""),
+
+		ReplicationRole:    getEnv("REPLICATION_ROLE", ""),
+		ReplicationPrimary: getEnv("REPLICATION_PRIMARY", ""),
+		ReplicationLogSize: int(getEnvInt64("REPLICATION_LOG_SIZE", 10000)),
+		ReplicationSecret:  getEnv("REPLICATION_SECRET", ""),
+
+		ArchiveDir:         getEnv("ARCHIVE_DIR", ""),
+		ArchiveAfterMonths: int(getEnvInt64("ARCHIVE_AFTER_MONTHS", 0)),
+		PurgeAfterYears:    int(getEnvInt64("PURGE_AFTER_YEARS", 0)),
+		RetentionInterval:  getEnvDuration("RETENTION_INTERVAL", 24*time.Hour),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+		UserTokens: getEnv("USER_TOKENS", ""),
+	}
+
+	flag.StringVar(&cfg.Port, "port", cfg.Port, "Port to listen on")
+	flag.StringVar(&cfg.SnapshotPath, "snapshot", cfg.SnapshotPath, "Path of the snapshot file, empty disables snapshots")
+	flag.DurationVar(&cfg.SnapshotInterval, "snapshot-interval", cfg.SnapshotInterval, "Interval between periodic snapshots, 0 disables them")
+	flag.BoolVar(&cfg.RestoreOnStart, "restore", cfg.RestoreOnStart, "Restore calendar data from the snapshot file on startup")
+	flag.StringVar(&cfg.WeekStart, "week-start", cfg.WeekStart, "Default first day of the week: monday, sunday or saturday")
+	flag.StringVar(&cfg.HolidaysDir, "holidays", cfg.HolidaysDir, "Directory with holiday calendars (.ics or .json), one per country or team")
+	flag.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "Path of the TLS certificate, enables HTTPS together with -tls-key")
+	flag.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "Path of the TLS private key")
+	flag.StringVar(&cfg.ClientCA, "tls-client-ca", cfg.ClientCA, "Path of the CA bundle used to verify client certificates")
+	flag.StringVar(&cfg.ClientAuth, "tls-client-auth", cfg.ClientAuth, "Client certificate policy: none, request or require")
+	flag.StringVar(&cfg.RedirectPort, "http-redirect-port", cfg.RedirectPort, "Port for plain HTTP that redirects to HTTPS, empty disables it")
+	flag.DurationVar(&cfg.CertPollInterval, "tls-poll-interval", cfg.CertPollInterval, "Interval between checks for changed certificate files, 0 disables them")
+	flag.StringVar(&cfg.AttachmentsDir, "attachments", cfg.AttachmentsDir, "Absolute path of the directory where event attachments are stored, empty keeps them in memory")
+	flag.Int64Var(&cfg.AttachmentLimit, "attachment-limit", cfg.AttachmentLimit, "Maximum size of a single attachment in bytes")
+	flag.StringVar(&cfg.DigestTemplates, "digest-templates", cfg.DigestTemplates, "Directory with digest.md.tmpl, digest.html.tmpl or digest.txt.tmpl overriding the shipped templates")
+	flag.StringVar(&cfg.DigestAt, "digest-at", cfg.DigestAt, "Time of day (HH:MM) to send the agenda digest, empty disables it")
+	flag.StringVar(&cfg.DigestPeriod, "digest-period", cfg.DigestPeriod, "Digest period: day, or week to send on the first day of the week")
+	flag.StringVar(&cfg.DigestFormat, "digest-format", cfg.DigestFormat, "Digest format: markdown, html or text")
+	flag.StringVar(&cfg.DigestTimeZone, "digest-time-zone", cfg.DigestTimeZone, "Time zone of -digest-at and of the days and times in the digest")
+	flag.StringVar(&cfg.DigestSender, "digest-sender", cfg.DigestSender, "Digest delivery: log, dir:PATH or smtp://[user:password@]host:port")
+	flag.StringVar(&cfg.DigestFrom, "digest-from", cfg.DigestFrom, "Sender address of digest emails")
+	flag.StringVar(&cfg.DigestTo, "digest-to", cfg.DigestTo, "Comma-separated recipients of digest emails")
+	flag.StringVar(&cfg.DigestCalendars, "digest-calendars", cfg.DigestCalendars, "Comma-separated calendar IDs the scheduled digest covers; empty means events outside calendars and calendars visible without signing in")
+	flag.StringVar(&cfg.DigestOverlays, "digest-overlays", cfg.DigestOverlays, "Comma-separated holiday calendars marked in the scheduled digest, like overlays of /digest_preview")
+	flag.StringVar(&cfg.ReplicationRole, "replication-role", cfg.ReplicationRole, "Replication role: primary, replica, or empty for a standalone instance")
+	flag.StringVar(&cfg.ReplicationPrimary, "replication-primary", cfg.ReplicationPrimary, "Base URL of the primary a replica follows")
+	flag.IntVar(&cfg.ReplicationLogSize, "replication-log-size", cfg.ReplicationLogSize, "Number of changes the primary keeps for replicas to catch up")
+	flag.StringVar(&cfg.ReplicationSecret, "replication-secret", cfg.ReplicationSecret, "Secret shared by a primary and its replicas, sent as a bearer token to /replication/changes and /replication/snapshot")
+	flag.StringVar(&cfg.ArchiveDir, "archive", cfg.ArchiveDir, "Directory of the compressed event archive, empty disables archiving")
+	flag.IntVar(&cfg.ArchiveAfterMonths, "archive-after-months", cfg.ArchiveAfterMonths, "Move events older than this many months to the archive, 0 keeps them")
+	flag.IntVar(&cfg.PurgeAfterYears, "purge-after-years", cfg.PurgeAfterYears, "Delete events older than this many years, also from the archive, 0 keeps them")
+	flag.DurationVar(&cfg.RetentionInterval, "retention-interval", cfg.RetentionInterval, "Interval between retention runs")
+	flag.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "Bearer token required by the /admin endpoints, empty disables them")
+	flag.StringVar(&cfg.UserTokens, "user-tokens", cfg.UserTokens, "Comma-separated user:token pairs; a request that sends a token as a bearer token acts as its user")
+	flag.Parse()
+
+	return cfg
+}
+
+func getEnv(key, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return fallback
 	}
-	return port
+	return value
 }
@@ -0,0 +1,252 @@
+package digest
+
+import (
+	"bytes"
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"calendar/internal/service"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+type Kind string
+
+const (
+	Day  Kind = "day"
+	Week Kind = "week"
+)
+
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	Text     Format = "text"
+)
+
+var files = map[Format]string{
+	Markdown: "digest.md.tmpl",
+	HTML:     "digest.html.tmpl",
+	Text:     "digest.txt.tmpl",
+}
+
+var contentTypes = map[Format]string{
+	Markdown: "text/markdown; charset=utf-8",
+	HTML:     "text/html; charset=utf-8",
+	Text:     "text/plain; charset=utf-8",
+}
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+type Digest struct {
+	Title     string
+	Kind      Kind
+	Start     string
+	End       string
+	Days      []service.DayEvents
+	Total     int
+	Generated time.Time
+}
+
+// Options select the digest. The days of the period, and the times of
+// timed events, are those of Location, UTC when nil.
+type Options struct {
+	Kind        Kind
+	Date        time.Time
+	WeekStart   time.Weekday
+	Location    *time.Location
+	CalendarIDs []string
+	Overlays    []*holiday.Calendar
+}
+
+func ParseKind(value string) (Kind, error) {
+	switch Kind(strings.ToLower(value)) {
+	case "", Day:
+		return Day, nil
+	case Week:
+		return Week, nil
+	}
+	return Day, fmt.Errorf("invalid digest period %q, expected day or week", value)
+}
+
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(value) {
+	case "", "markdown", "md":
+		return Markdown, nil
+	case "html":
+		return HTML, nil
+	case "text", "txt", "plain":
+		return Text, nil
+	}
+	return Markdown, fmt.Errorf("invalid digest format %q, expected markdown, html or text", value)
+}
+
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+func Build(storage service.Storage, options Options, now time.Time) Digest {
+	location := options.Location
+	if location == nil {
+		location = time.UTC
+	}
+	date := time.Date(options.Date.Year(), options.Date.Month(), options.Date.Day(), 0, 0, 0, 0, location)
+
+	var p period.Period
+	if options.Kind == Week {
+		p = period.Week(date, options.WeekStart)
+	} else {
+		p = period.Period{Start: date, End: date.AddDate(0, 0, 1), WeekStart: options.WeekStart}
+	}
+	events := service.FilterByCalendar(eventsIn(storage, p, location), options.CalendarIDs)
+
+	grouped := service.GroupByDay(p, events)
+	grouped.ApplyOverlays(options.Overlays)
+
+	d := Digest{
+		Kind:      options.Kind,
+		Start:     grouped.Start,
+		End:       grouped.End,
+		Days:      grouped.Days,
+		Total:     len(events),
+		Generated: now.In(location),
+	}
+	if options.Kind == Week {
+		d.Title = "Agenda for the week of " + d.Start
+	} else {
+		d.Title = "Agenda for " + p.Start.Weekday().String() + ", " + d.Start
+	}
+	return d
+}
+
+// eventsIn returns the events of a period of days in location, all-day
+// events first. Timed events count by their start and are moved into
+// location; all-day events are kept at midnight UTC and count by that date,
+// which is the same in every zone.
+func eventsIn(storage service.Storage, p period.Period, location *time.Location) []service.Event {
+	firstDay := time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(p.End.Year(), p.End.Month(), p.End.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := p.Start, p.End
+	if firstDay.Before(from) {
+		from = firstDay
+	}
+	if lastDay.After(to) {
+		to = lastDay
+	}
+
+	var events []service.Event
+	for _, event := range storage.GetEventsForRange(from, to) {
+		if event.Timed() {
+			if event.Date.Before(p.Start) || !event.Date.Before(p.End) {
+				continue
+			}
+			end := event.End.In(location)
+			event.Date, event.End = event.Date.In(location), &end
+		} else if event.Date.Before(firstDay) || !event.Date.Before(lastDay) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return !events[i].Timed() && events[j].Timed()
+	})
+	return events
+}
+
+var funcs = map[string]interface{}{
+	"eventTime": eventTime,
+	"weekday":   weekday,
+	"md":        escapeMarkdown,
+}
+
+func eventTime(event service.Event) string {
+	if !event.Timed() {
+		return "all day"
+	}
+	return event.Date.Format("15:04") + "–" + event.End.Format("15:04")
+}
+
+func weekday(date string) string {
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return parsed.Weekday().String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "#", `\#`, "<", `\<`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// executor is satisfied by both text/template and html/template.
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// Renderer renders digests with the shipped templates. A file with the
+// same name (digest.md.tmpl, digest.html.tmpl or digest.txt.tmpl) in the
+// override directory replaces the default for that format. HTML is
+// rendered with html/template, so event titles are escaped.
+type Renderer struct {
+	templates map[Format]executor
+}
+
+func NewRenderer(overrideDir string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[Format]executor)}
+	for format, name := range files {
+		source, err := defaults.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if overrideDir != "" {
+			custom, err := os.ReadFile(filepath.Join(overrideDir, name))
+			switch {
+			case err == nil:
+				source = custom
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, err
+			}
+		}
+
+		if format == HTML {
+			t, err := htmltemplate.New(name).Funcs(funcs).Parse(string(source))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			r.templates[format] = t
+		} else {
+			t, err := texttemplate.New(name).Funcs(funcs).Parse(string(source))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			r.templates[format] = t
+		}
+	}
+	return r, nil
+}
+
+func (r *Renderer) Render(format Format, d Digest) (string, error) {
+	t, ok := r.templates[format]
+	if !ok {
+		return "", fmt.Errorf("unknown digest format %q", format)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,107 @@
+package digest
+
+import (
+	"calendar/internal/holiday"
+	"calendar/internal/service"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Scheduler sends the digest every day (Kind Day) or on the first day of
+// every week (Kind Week) at the time of day At in Location, which also sets
+// the days and times of the digest. It covers the events of CalendarIDs,
+// or when there are none, the events anyone may see.
+type Scheduler struct {
+	Storage     service.Storage
+	Renderer    *Renderer
+	Sender      Sender
+	Kind        Kind
+	Format      Format
+	At          time.Duration
+	Location    *time.Location
+	WeekStart   time.Weekday
+	CalendarIDs []string
+	Overlays    []*holiday.Calendar
+}
+
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid digest time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (s *Scheduler) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// NextRun returns the first delivery time strictly after now.
+func (s *Scheduler) NextRun(now time.Time) time.Time {
+	local := now.In(s.location())
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location())
+	for {
+		run := time.Date(day.Year(), day.Month(), day.Day(), int(s.At/time.Hour), int(s.At%time.Hour/time.Minute), 0, 0, s.location())
+		if run.After(now) && (s.Kind != Week || day.Weekday() == s.WeekStart) {
+			return run
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+// calendarIDs returns the configured calendars, or the events outside any
+// calendar and the calendars visible without signing in.
+func (s *Scheduler) calendarIDs() []string {
+	if len(s.CalendarIDs) > 0 {
+		return s.CalendarIDs
+	}
+	ids := []string{""}
+	for _, calendar := range s.Storage.GetCalendars() {
+		if calendar.VisibleTo("") {
+			ids = append(ids, calendar.ID)
+		}
+	}
+	return ids
+}
+
+func (s *Scheduler) Send(now time.Time) error {
+	d := Build(s.Storage, Options{
+		Kind:        s.Kind,
+		Date:        now.In(s.location()),
+		WeekStart:   s.WeekStart,
+		Location:    s.location(),
+		CalendarIDs: s.calendarIDs(),
+		Overlays:    s.Overlays,
+	}, now)
+	body, err := s.Renderer.Render(s.Format, d)
+	if err != nil {
+		return err
+	}
+	return s.Sender.Send(Message{
+		Subject:     d.Title,
+		Format:      s.Format,
+		ContentType: s.Format.ContentType(),
+		Body:        body,
+		Digest:      d,
+	})
+}
+
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	for {
+		next := s.NextRun(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case now := <-timer.C:
+			if err := s.Send(now); err != nil {
+				log.Printf("Failed to send digest: %v", err)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
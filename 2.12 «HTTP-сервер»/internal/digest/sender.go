@@ -0,0 +1,115 @@
+package digest
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	Subject     string
+	Format      Format
+	ContentType string
+	Body        string
+	Digest      Digest
+}
+
+// Sender delivers a rendered digest. LogSender, DirSender and SMTPSender
+// are built in; NewSender picks one from a configuration string.
+type Sender interface {
+	Send(msg Message) error
+}
+
+type LogSender struct{}
+
+func (LogSender) Send(msg Message) error {
+	log.Printf("Digest %q:\n%s", msg.Subject, msg.Body)
+	return nil
+}
+
+type DirSender struct {
+	Dir string
+}
+
+var extensions = map[Format]string{Markdown: ".md", HTML: ".html", Text: ".txt"}
+
+func (s DirSender) Send(msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("digest-%s-%s%s", msg.Digest.Kind, msg.Digest.Start, extensions[msg.Format])
+	return os.WriteFile(filepath.Join(s.Dir, name), []byte(msg.Body), 0o644)
+}
+
+type SMTPSender struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+func (s SMTPSender) Send(msg Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s\r\n", msg.ContentType)
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return smtp.SendMail(s.Addr, s.Auth, s.From, s.To, []byte(b.String()))
+}
+
+// NewSender parses a sender configuration: "log", "dir:PATH" or
+// "smtp://[user:password@]host:port". SMTP needs from and at least one
+// recipient in to, separated by commas.
+func NewSender(spec, from, to string) (Sender, error) {
+	switch {
+	case spec == "" || spec == "log":
+		return LogSender{}, nil
+	case strings.HasPrefix(spec, "dir:"):
+		dir := strings.TrimPrefix(spec, "dir:")
+		if dir == "" {
+			return nil, fmt.Errorf("digest sender %q has no directory", spec)
+		}
+		return DirSender{Dir: dir}, nil
+	case strings.HasPrefix(spec, "smtp://"):
+		return newSMTPSender(strings.TrimPrefix(spec, "smtp://"), from, to)
+	}
+	return nil, fmt.Errorf("invalid digest sender %q, expected log, dir:PATH or smtp://host:port", spec)
+}
+
+func newSMTPSender(target, from, to string) (Sender, error) {
+	var recipients []string
+	for _, address := range strings.Split(to, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			recipients = append(recipients, address)
+		}
+	}
+	if from == "" || len(recipients) == 0 {
+		return nil, fmt.Errorf("SMTP digest sender needs a sender and at least one recipient")
+	}
+
+	sender := SMTPSender{Addr: target, From: from, To: recipients}
+	if credentials, addr, ok := strings.Cut(target, "@"); ok {
+		user, password, _ := strings.Cut(credentials, ":")
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+		}
+		sender.Addr = addr
+		sender.Auth = smtp.PlainAuth("", user, password, host)
+	}
+	if _, _, err := net.SplitHostPort(sender.Addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", sender.Addr, err)
+	}
+	return sender, nil
+}
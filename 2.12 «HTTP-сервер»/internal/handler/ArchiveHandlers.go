@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"calendar/internal/archive"
+	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"calendar/internal/preference"
+	"calendar/internal/service"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// EventsForRangeHandler returns the events between two dates, inclusive,
+// whether they are still in the storage or were moved to the archive.
+// With overlays, they come grouped by day with the holidays of each day.
+func EventsForRangeHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, events *archive.Archive, holidays *holiday.Set, defaultWeekStart time.Weekday, preferences *preference.Registry) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	overlays, err := helpers.ParseOverlays(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var dates [2]time.Time
+	for i, field := range []string{"start", "end"} {
+		value := query.Get(field)
+		if value == "" {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField(field))
+			return
+		}
+		if dates[i], err = time.Parse("2006-01-02", value); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField(field, "invalid date format, expected YYYY-MM-DD"))
+			return
+		}
+	}
+	start, end := dates[0], dates[1].AddDate(0, 0, 1)
+	if !end.After(start) {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("end", "end must not be before start"))
+		return
+	}
+
+	result := storage.GetEventsForRange(start, end)
+	if events != nil {
+		archived, err := events.Range(start, end)
+		if err != nil {
+			helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+			return
+		}
+		live := make(map[string]bool, len(result))
+		for _, event := range result {
+			live[event.ID] = true
+		}
+		for _, event := range archived {
+			if !live[event.ID] {
+				result = append(result, event)
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[i].Date.Equal(result[j].Date) {
+				return result[i].ID < result[j].ID
+			}
+			return result[i].Date.Before(result[j].Date)
+		})
+	}
+
+	result = service.FilterByCalendar(result, calendarIDs)
+	if overlays == nil {
+		helpers.WriteJSONResponse(w, http.StatusOK, result)
+		return
+	}
+	writeWithOverlays(w, r, period.Period{Start: start, End: end}, result, overlays, defaultWeekStart, preferences)
+}
+
+func RetentionHandler(w http.ResponseWriter, r *http.Request, job *archive.Job) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	report, err := job.Run(time.Now())
+	if err != nil {
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "retention applied", "report": report})
+}
+
+func ArchiveHandler(w http.ResponseWriter, r *http.Request, events *archive.Archive) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	months := []archive.Month{}
+	if events != nil {
+		var err error
+		if months, err = events.Months(); err != nil {
+			helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+			return
+		}
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"enabled": events != nil, "months": months})
+}
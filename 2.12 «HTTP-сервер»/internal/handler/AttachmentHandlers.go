@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"calendar/internal/archive"
+	"calendar/internal/attachment"
+	"calendar/internal/helpers"
+	"calendar/internal/service"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const multipartOverhead = 1 << 20
+
+func UploadAttachmentHandler(w http.ResponseWriter, r *http.Request, attachments *attachment.Registry, storage service.Storage) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, attachments.MaxSize()+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeTooLarge(w, attachments)
+			return
+		}
+		helpers.WriteError(w, http.StatusBadRequest, &helpers.APIError{Code: helpers.CodeInvalidRequest, Message: "invalid multipart form data"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	eventID := r.FormValue("event_id")
+	if eventID == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("event_id"))
+		return
+	}
+	event, ok := storage.GetEventByID(eventID)
+	if !ok || !helpers.EventVisible(storage, helpers.User(r), event) {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("event not found"))
+		return
+	}
+	if status, err := helpers.CheckEventWrite(storage, helpers.User(r), event); err != nil {
+		helpers.WriteError(w, status, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("file"))
+		return
+	}
+	defer file.Close()
+
+	created, err := attachments.Add(eventID, header.Filename, file)
+	switch {
+	case errors.Is(err, attachment.ErrTooLarge):
+		writeTooLarge(w, attachments)
+	case errors.Is(err, attachment.ErrEmpty):
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("file", "file is empty"))
+	case err != nil:
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: "failed to store attachment"})
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "attachment uploaded", "attachment": created})
+	}
+}
+
+func GetAttachmentsHandler(w http.ResponseWriter, r *http.Request, attachments *attachment.Registry, storage service.Storage, events *archive.Archive) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("event_id"))
+		return
+	}
+	listed := attachments.List(eventID)
+	if len(listed) > 0 {
+		if _, ok, err := attachmentEvent(r, storage, events, eventID); err != nil {
+			writeArchiveError(w, err)
+			return
+		} else if !ok {
+			helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("event not found"))
+			return
+		}
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, listed)
+}
+
+func DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request, attachments *attachment.Registry, storage service.Storage, events *archive.Archive) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("id"))
+		return
+	}
+
+	a, ok := attachments.Get(id)
+	if ok {
+		var err error
+		if _, ok, err = attachmentEvent(r, storage, events, a.EventID); err != nil {
+			writeArchiveError(w, err)
+			return
+		}
+	}
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("attachment not found"))
+		return
+	}
+	a, body, err := attachments.Open(id)
+	if err != nil {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("attachment not found"))
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, body)
+}
+
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request, attachments *attachment.Registry, storage service.Storage, events *archive.Archive) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("id"))
+		return
+	}
+
+	a, ok := attachments.Get(id)
+	var event service.Event
+	if ok {
+		var err error
+		if event, ok, err = attachmentEvent(r, storage, events, a.EventID); err != nil {
+			writeArchiveError(w, err)
+			return
+		}
+	}
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+		return
+	}
+	if status, err := helpers.CheckEventWrite(storage, helpers.User(r), event); err != nil {
+		helpers.WriteError(w, status, err)
+		return
+	}
+
+	found, err := attachments.Delete(id)
+	switch {
+	case err != nil:
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: "failed to delete attachment"})
+	case !found:
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "attachment deleted"})
+	}
+}
+
+// attachmentEvent returns the event attachments belong to, from the
+// storage or, once archived, from the archive, and whether the caller may
+// see it.
+func attachmentEvent(r *http.Request, storage service.Storage, events *archive.Archive, eventID string) (service.Event, bool, error) {
+	event, ok := storage.GetEventByID(eventID)
+	if !ok && events != nil {
+		var err error
+		if event, ok, err = events.Event(eventID); err != nil {
+			return service.Event{}, false, err
+		}
+	}
+	return event, ok && helpers.EventVisible(storage, helpers.User(r), event), nil
+}
+
+func writeArchiveError(w http.ResponseWriter, err error) {
+	helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+}
+
+func writeTooLarge(w http.ResponseWriter, attachments *attachment.Registry) {
+	helpers.WriteError(w, http.StatusRequestEntityTooLarge, &helpers.APIError{
+		Code:    helpers.CodeTooLarge,
+		Message: "attachment exceeds the limit of " + strconv.FormatInt(attachments.MaxSize(), 10) + " bytes",
+		Field:   "file",
+	})
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"calendar/internal/booking"
+	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/service"
+	"errors"
+	"net/http"
+	"time"
+)
+
+func CreateBookingPageHandler(w http.ResponseWriter, r *http.Request, pages *booking.Registry, storage service.Storage, holidays *holiday.Set) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateBookingPage(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if status, err := helpers.CheckCalendarWrite(storage, helpers.User(r), "calendar_id", params["calendar_id"].(string)); err != nil {
+		helpers.WriteError(w, status, err)
+		return
+	}
+
+	page := booking.Page{
+		Slug:        params["slug"].(string),
+		Title:       params["title"].(string),
+		Owner:       helpers.User(r),
+		SlotMinutes: params["slot_minutes"].(int),
+		TimeZone:    params["time_zone"].(string),
+		Windows:     params["windows"].([]booking.Window),
+
+		HolidayCalendar: params["holiday_calendar"].(string),
+		CalendarID:      params["calendar_id"].(string),
+	}
+	if err := pages.Save(page); err != nil {
+		helpers.WriteError(w, http.StatusConflict, &helpers.APIError{Code: helpers.CodeConflict, Message: err.Error(), Field: "slug"})
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "booking page saved", "page": page})
+}
+
+func DeleteBookingPageHandler(w http.ResponseWriter, r *http.Request, pages *booking.Registry) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	slug := r.FormValue("slug")
+	if slug == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("slug"))
+		return
+	}
+	page, ok := pages.Get(slug)
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("booking page not found"))
+		return
+	}
+	if page.Owner != "" && page.Owner != helpers.User(r) {
+		helpers.WriteError(w, http.StatusForbidden, helpers.Forbidden("only the owner may delete booking page "+slug))
+		return
+	}
+	if pages.Delete(slug) {
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "booking page deleted"})
+	} else {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("booking page not found"))
+	}
+}
+
+func GetBookingPagesHandler(w http.ResponseWriter, r *http.Request, pages *booking.Registry) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, pages.List())
+}
+
+func BookingSlotsHandler(w http.ResponseWriter, r *http.Request, pages *booking.Registry, storage service.Storage, holidays *holiday.Set) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	now := time.Now()
+	params, err := helpers.ParseAndValidateSlotsQuery(r, now)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, ok := pages.Get(params["page"].(string))
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("booking page not found"))
+		return
+	}
+
+	from := params["from"].(time.Time)
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, page.Location())
+	to := from.AddDate(0, 0, params["days"].(int))
+
+	slots := page.OpenSlots(storage, workdays(page, holidays), from, to, now)
+	if slots == nil {
+		slots = []booking.Slot{}
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"page":        page.Title,
+		"time_zone":   page.TimeZone,
+		"slot_length": page.SlotMinutes,
+		"slots":       slots,
+	})
+}
+
+func BookSlotHandler(w http.ResponseWriter, r *http.Request, pages *booking.Registry, storage service.Storage, holidays *holiday.Set) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateBooking(r)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, ok := pages.Get(params["page"].(string))
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("booking page not found"))
+		return
+	}
+
+	event, err := page.Book(storage, workdays(page, holidays), params["start"].(time.Time), params["name"].(string), params["email"].(string), time.Now())
+	switch {
+	case errors.Is(err, booking.ErrSlotTaken):
+		helpers.WriteError(w, http.StatusConflict, &helpers.APIError{Code: helpers.CodeConflict, Message: err.Error(), Field: "start"})
+	case err != nil:
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("start", err.Error()))
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "slot booked", "event": event})
+	}
+}
+
+func workdays(page booking.Page, holidays *holiday.Set) *holiday.Calendar {
+	if page.HolidayCalendar == "" {
+		return nil
+	}
+	calendar, _ := holidays.Get(page.HolidayCalendar)
+	return calendar
+}
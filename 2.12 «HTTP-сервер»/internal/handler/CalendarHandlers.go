@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/service"
+	"net/http"
+)
+
+func CreateCalendarHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateCalendar(r)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendar := service.Calendar{
+		Name:       params["name"].(string),
+		Colour:     params["colour"].(string),
+		Owner:      helpers.User(r),
+		Visibility: params["visibility"].(service.Visibility),
+	}
+
+	created := storage.CreateCalendar(calendar)
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "calendar created", "calendar": created})
+}
+
+func UpdateCalendarHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateUpdateCalendarParams(r)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendar, found := storage.GetCalendar(params["id"].(string))
+	if !found || !calendar.VisibleTo(helpers.User(r)) {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+		return
+	}
+	if !calendar.WritableBy(helpers.User(r)) {
+		helpers.WriteError(w, http.StatusForbidden, helpers.Forbidden("only the owner may change calendar "+calendar.ID))
+		return
+	}
+	if name, ok := params["name"].(string); ok {
+		calendar.Name = name
+	}
+	if colour, ok := params["colour"].(string); ok {
+		calendar.Colour = colour
+	}
+	if visibility, ok := params["visibility"].(service.Visibility); ok {
+		calendar.Visibility = visibility
+	}
+
+	if storage.UpdateCalendar(calendar) {
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "calendar updated", "calendar": calendar})
+	} else {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+	}
+}
+
+func DeleteCalendarHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("id"))
+		return
+	}
+	calendar, found := storage.GetCalendar(id)
+	if !found || !calendar.VisibleTo(helpers.User(r)) {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+		return
+	}
+	if !calendar.WritableBy(helpers.User(r)) {
+		helpers.WriteError(w, http.StatusForbidden, helpers.Forbidden("only the owner may delete calendar "+id))
+		return
+	}
+	if storage.DeleteCalendar(id) {
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "calendar deleted"})
+	} else {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+	}
+}
+
+func GetCalendarsHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	owner, user := r.URL.Query().Get("owner"), helpers.User(r)
+	calendars := make([]service.Calendar, 0)
+	for _, calendar := range storage.GetCalendars() {
+		if calendar.VisibleTo(user) && (owner == "" || calendar.Owner == owner) {
+			calendars = append(calendars, calendar)
+		}
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, calendars)
+}
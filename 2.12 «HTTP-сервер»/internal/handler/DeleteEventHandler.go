@@ -6,21 +6,27 @@ import (
 	"net/http"
 )
 
-func DeleteEventHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func DeleteEventHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
 		return
 	}
 
 	id := r.FormValue("id")
 	if id == "" {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "missing event ID"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("id"))
 		return
 	}
+	if existing, exists := storage.GetEventByID(id); exists {
+		if status, err := helpers.CheckEventWrite(storage, helpers.User(r), existing); err != nil {
+			helpers.WriteError(w, status, err)
+			return
+		}
+	}
 	found := storage.DeleteEvent(id)
 	if found {
 		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "event deleted"})
 	} else {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "id not found"})
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
 	}
 }
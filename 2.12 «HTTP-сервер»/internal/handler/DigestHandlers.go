@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"calendar/internal/digest"
+	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/preference"
+	"calendar/internal/service"
+	"net/http"
+	"time"
+)
+
+func DigestPreviewHandler(w http.ResponseWriter, r *http.Request, renderer *digest.Renderer, storage service.Storage, holidays *holiday.Set, defaultWeekStart time.Weekday, preferences *preference.Registry) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	kind, err := digest.ParseKind(query.Get("period"))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("period", err.Error()))
+		return
+	}
+	format, err := digest.ParseFormat(query.Get("format"))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("format", err.Error()))
+		return
+	}
+
+	now := time.Now().UTC()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if dateSTR := query.Get("date"); dateSTR != "" {
+		if date, err = time.Parse("2006-01-02", dateSTR); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("date", "invalid date format, expected YYYY-MM-DD"))
+			return
+		}
+	}
+
+	weekStart, err := helpers.ParseWeekStart(r, defaultWeekStart, preferences)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	overlays, err := helpers.ParseOverlays(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	d := digest.Build(storage, digest.Options{
+		Kind:        kind,
+		Date:        date,
+		WeekStart:   weekStart,
+		CalendarIDs: calendarIDs,
+		Overlays:    overlays,
+	}, now)
+	body, err := renderer.Render(format, d)
+	if err != nil {
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Write([]byte(body))
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/openapi"
+	"net/http"
+)
+
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Document())
+}
+
+func DocsHandler(w http.ResponseWriter, r *http.Request, spec *openapi.Spec) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := spec.RenderDocs(w); err != nil {
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+	}
+}
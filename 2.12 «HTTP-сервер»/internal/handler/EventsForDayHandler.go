@@ -2,30 +2,65 @@ package handler
 
 import (
 	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"calendar/internal/preference"
 	"calendar/internal/service"
 	"net/http"
 	"time"
 )
 
-func EventsForDayHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+// EventsForDayHandler returns the events of a day. With overlays, the day
+// comes grouped like in the week and month views, with its holidays.
+func EventsForDayHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, holidays *holiday.Set, defaultWeekStart time.Weekday, preferences *preference.Registry) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	overlays, err := helpers.ParseOverlays(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	dateSTR := r.URL.Query().Get("date")
 	if dateSTR == "" {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "missing event date"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("date"))
 		return
 	}
 
 	date, err := time.Parse("2006-01-02", dateSTR)
 	if err != nil {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid date format"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("date", "invalid date format, expected YYYY-MM-DD"))
 		return
 	}
 
-	events := storage.GetEventsForDay(date)
-	helpers.WriteJSONResponse(w, http.StatusOK, events)
+	events := service.FilterByCalendar(storage.GetEventsForDay(date), calendarIDs)
+	if overlays == nil {
+		helpers.WriteJSONResponse(w, http.StatusOK, events)
+		return
+	}
+	writeWithOverlays(w, r, period.Period{Start: date, End: date.AddDate(0, 0, 1)}, events, overlays, defaultWeekStart, preferences)
+}
+
+// writeWithOverlays groups the events of p by day and marks the holidays
+// and non-working days of the overlays.
+func writeWithOverlays(w http.ResponseWriter, r *http.Request, p period.Period, events []service.Event, overlays []*holiday.Calendar, defaultWeekStart time.Weekday, preferences *preference.Registry) {
+	weekStart, err := helpers.ParseWeekStart(r, defaultWeekStart, preferences)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	p.WeekStart = weekStart
 
+	grouped := service.GroupByDay(p, events)
+	grouped.ApplyOverlays(overlays)
+	helpers.WriteJSONResponse(w, http.StatusOK, grouped)
 }
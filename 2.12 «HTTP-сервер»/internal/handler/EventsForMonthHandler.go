@@ -2,30 +2,52 @@ package handler
 
 import (
 	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"calendar/internal/preference"
 	"calendar/internal/service"
 	"net/http"
 	"time"
 )
 
-func EventsForMonthHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func EventsForMonthHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, holidays *holiday.Set, defaultWeekStart time.Weekday, preferences *preference.Registry) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	overlays, err := helpers.ParseOverlays(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	dateSTR := r.URL.Query().Get("date")
 	if dateSTR == "" {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "missing event date"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("date"))
 		return
 	}
 
 	date, err := time.Parse("2006-01-02", dateSTR)
 	if err != nil {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid date format"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("date", "invalid date format, expected YYYY-MM-DD"))
 		return
 	}
 
-	events := storage.GetEventsForMonth(date)
-	helpers.WriteJSONResponse(w, http.StatusOK, events)
+	weekStart, err := helpers.ParseWeekStart(r, defaultWeekStart, preferences)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
 
+	month := period.Month(date, weekStart)
+	events := service.GroupByDay(month, service.FilterByCalendar(storage.GetEventsForMonth(date), calendarIDs))
+	events.ApplyOverlays(overlays)
+	helpers.WriteJSONResponse(w, http.StatusOK, events)
 }
@@ -2,30 +2,69 @@ package handler
 
 import (
 	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"calendar/internal/preference"
 	"calendar/internal/service"
 	"net/http"
 	"time"
 )
 
-func EventsForWeekHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func EventsForWeekHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, holidays *holiday.Set, defaultWeekStart time.Weekday, preferences *preference.Registry) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	overlays, err := helpers.ParseOverlays(r, holidays)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if isoWeek := r.URL.Query().Get("week"); isoWeek != "" {
+		week, err := period.ParseISOWeek(isoWeek)
+		if err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("week", err.Error()))
+			return
+		}
+
+		events := service.GroupByDay(week, service.FilterByCalendar(storage.GetEventsForRange(week.Start, week.End), calendarIDs))
+		events.ISOWeek = period.ISOWeekString(week.Start)
+		events.ApplyOverlays(overlays)
+		helpers.WriteJSONResponse(w, http.StatusOK, events)
 		return
 	}
 
 	dateSTR := r.URL.Query().Get("date")
 	if dateSTR == "" {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "missing event date"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("date"))
 		return
 	}
 
 	date, err := time.Parse("2006-01-02", dateSTR)
 	if err != nil {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid date format"})
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("date", "invalid date format, expected YYYY-MM-DD"))
 		return
 	}
 
-	events := storage.GetEventsForWeek(date)
-	helpers.WriteJSONResponse(w, http.StatusOK, events)
+	weekStart, err := helpers.ParseWeekStart(r, defaultWeekStart, preferences)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
 
+	week := period.Week(date, weekStart)
+	events := service.GroupByDay(week, service.FilterByCalendar(storage.GetEventsForWeek(date, weekStart), calendarIDs))
+	if weekStart == time.Monday {
+		events.ISOWeek = period.ISOWeekString(week.Start)
+	}
+	events.ApplyOverlays(overlays)
+	helpers.WriteJSONResponse(w, http.StatusOK, events)
 }
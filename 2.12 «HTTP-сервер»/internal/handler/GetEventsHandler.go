@@ -6,11 +6,17 @@ import (
 	"net/http"
 )
 
-func GetEventsHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func GetEventsHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
 		return
 	}
-	events := storage.GetEvent()
+
+	calendarIDs, err := helpers.ParseCalendarFilter(r, storage)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	events := service.FilterByCalendar(storage.GetEvent(), calendarIDs)
 	helpers.WriteJSONResponse(w, http.StatusOK, events)
 }
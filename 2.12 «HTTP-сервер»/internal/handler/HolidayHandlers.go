@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/holiday"
+	"net/http"
+	"time"
+)
+
+func GetHolidayCalendarsHandler(w http.ResponseWriter, r *http.Request, holidays *holiday.Set) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, holidays.List())
+}
+
+func GetHolidaysHandler(w http.ResponseWriter, r *http.Request, holidays *holiday.Set) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	name := query.Get("calendar")
+	if name == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("calendar"))
+		return
+	}
+	calendar, ok := holidays.Get(name)
+	if !ok {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("holiday calendar not found"))
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("from", "invalid date format, expected YYYY-MM-DD"))
+		return
+	}
+	to, err := time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("to", "invalid date format, expected YYYY-MM-DD"))
+		return
+	}
+
+	result := calendar.Holidays(from, to.AddDate(0, 0, 1))
+	if result == nil {
+		result = []holiday.Holiday{}
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, result)
+}
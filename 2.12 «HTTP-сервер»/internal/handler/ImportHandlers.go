@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"calendar/internal/archive"
+	"calendar/internal/helpers"
+	"calendar/internal/importer"
+	"calendar/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const maxImportSize = 10 << 20
+
+func ImportHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, events *archive.Archive) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			helpers.WriteError(w, http.StatusRequestEntityTooLarge, &helpers.APIError{Code: helpers.CodeTooLarge, Message: "import file is larger than " + strconv.Itoa(maxImportSize) + " bytes", Field: "file"})
+			return
+		}
+		helpers.WriteError(w, http.StatusBadRequest, &helpers.APIError{Code: helpers.CodeInvalidRequest, Message: "invalid multipart form data"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("file"))
+		return
+	}
+	defer file.Close()
+
+	format, ok := importer.FormatForFile(header.Filename)
+	if value := r.FormValue("format"); value != "" {
+		if format, err = importer.ParseFormat(value); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("format", err.Error()))
+			return
+		}
+	} else if !ok {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("format"))
+		return
+	}
+
+	mapping, err := importer.ParseMapping([]byte(r.FormValue("mapping")))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("mapping", err.Error()))
+		return
+	}
+
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("dry_run", "dry_run must be a boolean"))
+			return
+		}
+	}
+
+	rows, err := importer.Read(file, format, mapping)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("file", err.Error()))
+		return
+	}
+
+	var archived []service.Event
+	if events != nil {
+		if archived, err = events.ByExternalID(importer.ExternalIDs(rows)); err != nil {
+			helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: err.Error()})
+			return
+		}
+	}
+
+	report := importer.Import(storage, helpers.User(r), archived, rows, mapping, dryRun)
+	result := "events imported"
+	if dryRun {
+		result = "import checked"
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": result, "report": report})
+}
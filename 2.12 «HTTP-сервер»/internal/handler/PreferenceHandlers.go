@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/period"
+	"calendar/internal/preference"
+	"net/http"
+)
+
+func GetPreferencesHandler(w http.ResponseWriter, r *http.Request, preferences *preference.Registry) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	user := helpers.User(r)
+	if user == "" {
+		writeUnauthenticated(w)
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, preferences.Get(user))
+}
+
+func UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request, preferences *preference.Registry) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	user := helpers.User(r)
+	if user == "" {
+		writeUnauthenticated(w)
+		return
+	}
+	if value := r.FormValue("week_start"); value != "" {
+		weekStart, err := period.ParseWeekStart(value)
+		if err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("week_start", err.Error()))
+			return
+		}
+		preferences.SetWeekStart(user, weekStart)
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "preferences updated", "preferences": preferences.Get(user)})
+}
+
+// writeUnauthenticated answers requests that need to know who sent them
+// but carry neither a user token nor a client certificate.
+func writeUnauthenticated(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	helpers.WriteError(w, http.StatusUnauthorized, &helpers.APIError{Code: helpers.CodeUnauthorized, Message: "a user token or client certificate is required"})
+}
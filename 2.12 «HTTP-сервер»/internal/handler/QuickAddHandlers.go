@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/quickadd"
+	"calendar/internal/service"
+	"net/http"
+	"time"
+)
+
+func QuickAddHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateQuickAdd(r, time.Now())
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	parsed, err := quickadd.Parse(params["text"].(string), params["reference"].(time.Time))
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("text", err.Error()))
+		return
+	}
+	parsed.Event.CalendarID = params["calendar_id"].(string)
+	if status, err := helpers.CheckCalendarWrite(storage, helpers.User(r), "calendar_id", parsed.Event.CalendarID); err != nil {
+		helpers.WriteError(w, status, err)
+		return
+	}
+
+	// The storage keeps single events only, so recurring phrases are
+	// returned for review even with confirm.
+	if !params["confirm"].(bool) || parsed.Recurrence != nil {
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "event parsed", "parsed": parsed})
+		return
+	}
+
+	createdEvent := storage.CreateEvent(parsed.Event)
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "event created", "parsed": parsed, "event": createdEvent})
+}
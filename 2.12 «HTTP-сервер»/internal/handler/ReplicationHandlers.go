@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/replication"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxReplicationWait = 60
+
+func ReplicationChangesHandler(w http.ResponseWriter, r *http.Request, primary *replication.Primary) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if primary == nil {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("this instance is not a replication primary"))
+		return
+	}
+
+	query := r.URL.Query()
+	logID := query.Get("log")
+	if logID == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("log"))
+		return
+	}
+	since, err := strconv.ParseUint(query.Get("since"), 10, 64)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("since", "since must be a sequence number"))
+		return
+	}
+	wait := 0
+	if waitSTR := query.Get("wait"); waitSTR != "" {
+		if wait, err = strconv.Atoi(waitSTR); err != nil || wait < 0 || wait > maxReplicationWait {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("wait", "wait must be between 0 and 60 seconds"))
+			return
+		}
+	}
+
+	batch, err := primary.Changes(r.Context(), logID, since, time.Duration(wait)*time.Second)
+	switch {
+	case errors.Is(err, replication.ErrTruncated):
+		helpers.WriteError(w, http.StatusGone, &helpers.APIError{Code: helpers.CodeLogTruncated, Message: err.Error()})
+	case err != nil:
+		// The replica went away while waiting.
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, batch)
+	}
+}
+
+func ReplicationSnapshotHandler(w http.ResponseWriter, r *http.Request, primary *replication.Primary) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if primary == nil {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("this instance is not a replication primary"))
+		return
+	}
+
+	helpers.WriteJSONResponse(w, http.StatusOK, primary.Checkpoint())
+}
+
+func ReplicationStatusHandler(w http.ResponseWriter, r *http.Request, primary *replication.Primary, replica *replication.Replica) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	switch {
+	case primary != nil:
+		logID, seq := primary.Log().Position()
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"role": "primary", "log": logID, "seq": seq})
+	case replica != nil:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"role": "replica", "replica": replica.Status()})
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"role": "standalone"})
+	}
+}
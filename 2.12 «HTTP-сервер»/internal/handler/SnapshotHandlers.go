@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"bytes"
+	"calendar/internal/helpers"
+	"calendar/internal/service"
+	"calendar/internal/snapshot"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const maxSnapshotSize = 64 << 20
+
+func SnapshotHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, registries snapshot.Registries) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// Encode before writing so a failure still gets a proper error status.
+	var body bytes.Buffer
+	if err := snapshot.Write(&body, storage, registries); err != nil {
+		log.Printf("Failed to encode snapshot: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, &helpers.APIError{Code: helpers.CodeInternal, Message: "failed to encode snapshot"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar-snapshot.json"`)
+	if _, err := body.WriteTo(w); err != nil {
+		log.Printf("Failed to send snapshot: %v", err)
+	}
+}
+
+func RestoreHandler(w http.ResponseWriter, r *http.Request, storage service.Storage, registries snapshot.Registries) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSnapshotSize)
+	snap, err := snapshot.Restore(r.Body, storage, registries)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		helpers.WriteError(w, http.StatusRequestEntityTooLarge, &helpers.APIError{Code: helpers.CodeTooLarge, Message: "snapshot is larger than " + strconv.Itoa(maxSnapshotSize) + " bytes"})
+	case err != nil:
+		helpers.WriteError(w, http.StatusBadRequest, err)
+	default:
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "snapshot restored", "version": snap.Version, "events": len(snap.Events)})
+	}
+}
@@ -7,15 +7,15 @@ import (
 	"time"
 )
 
-func UpdateEventHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func UpdateEventHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
 		return
 	}
 
 	params, err := helpers.ParseAndValidateUpdateEventParams(r)
 	if err != nil {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		helpers.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -24,11 +24,37 @@ func UpdateEventHandler(w http.ResponseWriter, r *http.Request, storage *service
 		Title: params["title"].(string),
 		Date:  params["date"].(time.Time),
 	}
+	existing, exists := storage.GetEventByID(event.ID)
+	if exists {
+		if status, err := helpers.CheckEventWrite(storage, helpers.User(r), existing); err != nil {
+			helpers.WriteError(w, status, err)
+			return
+		}
+	}
+	event.ExternalID = existing.ExternalID
+	if end, ok := params["end"].(time.Time); ok {
+		event.End = &end
+	} else if exists && existing.Timed() && !params["all_day"].(bool) {
+		if !existing.End.After(event.Date) {
+			helpers.WriteError(w, http.StatusBadRequest, helpers.InvalidField("end", "the event would end before it starts, end is required"))
+			return
+		}
+		event.End = existing.End
+	}
+	if calendarID, ok := params["calendar_id"].(string); ok {
+		if status, err := helpers.CheckCalendarWrite(storage, helpers.User(r), "calendar_id", calendarID); err != nil {
+			helpers.WriteError(w, status, err)
+			return
+		}
+		event.CalendarID = calendarID
+	} else if exists {
+		event.CalendarID = existing.CalendarID
+	}
 
 	found := storage.UpdateEvent(event)
 	if found {
 		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "event updated"})
 	} else {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": "id not found"})
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
 	}
 }
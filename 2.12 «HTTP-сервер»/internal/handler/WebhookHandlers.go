@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/service"
+	"calendar/internal/webhook"
+	"net/http"
+)
+
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request, registry *webhook.Registry) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	params, err := helpers.ParseAndValidateWebhook(r)
+	if err != nil {
+		helpers.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	created := registry.Register(webhook.Webhook{
+		URL:        params["url"].(string),
+		Secret:     params["secret"].(string),
+		EventTypes: params["events"].([]service.ChangeType),
+		User:       params["user"].(string),
+	})
+	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "webhook created", "webhook": created, "secret": created.Secret})
+}
+
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request, registry *webhook.Registry, dispatcher *webhook.Dispatcher) {
+	if r.Method != http.MethodPost {
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		helpers.WriteError(w, http.StatusBadRequest, helpers.MissingField("id"))
+		return
+	}
+	if registry.Delete(id) {
+		dispatcher.Remove(id)
+		helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "webhook deleted"})
+	} else {
+		helpers.WriteError(w, http.StatusNotFound, helpers.NotFound("id not found"))
+	}
+}
+
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request, registry *webhook.Registry) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, registry.List())
+}
+
+func WebhookDeadLettersHandler(w http.ResponseWriter, r *http.Request, dispatcher *webhook.Dispatcher) {
+	if r.Method != http.MethodGet {
+		helpers.WriteMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	helpers.WriteJSONResponse(w, http.StatusOK, dispatcher.DeadLetters())
+}
@@ -7,15 +7,15 @@ import (
 	"time"
 )
 
-func CreateEventHandler(w http.ResponseWriter, r *http.Request, storage *service.InMemoryStorage) {
+func CreateEventHandler(w http.ResponseWriter, r *http.Request, storage service.Storage) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+		helpers.WriteMethodNotAllowed(w, http.MethodPost)
 		return
 	}
 
 	params, err := helpers.ParseAndValidateEvent(r)
 	if err != nil {
-		helpers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		helpers.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -23,6 +23,14 @@ func CreateEventHandler(w http.ResponseWriter, r *http.Request, storage *service
 		Title: params["title"].(string),
 		Date:  params["date"].(time.Time),
 	}
+	if end, ok := params["end"].(time.Time); ok {
+		event.End = &end
+	}
+	event.CalendarID, _ = params["calendar_id"].(string)
+	if status, err := helpers.CheckCalendarWrite(storage, helpers.User(r), "calendar_id", event.CalendarID); err != nil {
+		helpers.WriteError(w, status, err)
+		return
+	}
 
 	createdEvent := storage.CreateEvent(event)
 	helpers.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"result": "event created", "event": createdEvent})
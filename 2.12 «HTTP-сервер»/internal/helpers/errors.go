@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	CodeMissingField     = "missing_field"
+	CodeInvalidField     = "invalid_field"
+	CodeInvalidRequest   = "invalid_request"
+	CodeNotFound         = "not_found"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "too_large"
+	CodeReadOnly         = "read_only"
+	CodeLogTruncated     = "log_truncated"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func MissingField(field string) *APIError {
+	return &APIError{Code: CodeMissingField, Message: field + " is required", Field: field}
+}
+
+func InvalidField(field, message string) *APIError {
+	return &APIError{Code: CodeInvalidField, Message: message, Field: field}
+}
+
+func NotFound(message string) *APIError {
+	return &APIError{Code: CodeNotFound, Message: message}
+}
+
+func Forbidden(message string) *APIError {
+	return &APIError{Code: CodeForbidden, Message: message}
+}
+
+func WriteError(w http.ResponseWriter, status int, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = &APIError{Code: CodeInvalidRequest, Message: err.Error()}
+	}
+	WriteJSONResponse(w, status, apiErr)
+}
+
+func WriteMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	for _, method := range allowed {
+		w.Header().Add("Allow", method)
+	}
+	WriteJSONResponse(w, http.StatusMethodNotAllowed, &APIError{Code: CodeMethodNotAllowed, Message: "invalid method"})
+}
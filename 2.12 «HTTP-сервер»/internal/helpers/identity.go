@@ -0,0 +1,21 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+)
+
+type userKey struct{}
+
+// WithUser returns a copy of the request made on behalf of user.
+func WithUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+}
+
+// User returns the authenticated user of the request, or "" for an
+// anonymous one. Only the identity middleware sets it, so a client cannot
+// claim to be somebody else through a parameter.
+func User(r *http.Request) string {
+	user, _ := r.Context().Value(userKey{}).(string)
+	return user
+}
@@ -0,0 +1,144 @@
+package helpers
+
+import (
+	"calendar/internal/booking"
+	"calendar/internal/holiday"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+func ParseAndValidateBookingPage(r *http.Request, holidays *holiday.Set) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	slug := r.FormValue("slug")
+	if slug == "" {
+		return nil, MissingField("slug")
+	}
+	if !slugPattern.MatchString(slug) {
+		return nil, InvalidField("slug", "slug may only contain lowercase letters, digits and dashes")
+	}
+
+	title := r.FormValue("title")
+	if title == "" {
+		return nil, MissingField("title")
+	}
+
+	slotMinutes, err := strconv.Atoi(r.FormValue("slot_minutes"))
+	if err != nil || slotMinutes < 5 || slotMinutes > 24*60 {
+		return nil, InvalidField("slot_minutes", "slot_minutes must be a number of minutes between 5 and 1440")
+	}
+
+	timeZone := r.FormValue("time_zone")
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, InvalidField("time_zone", "unknown time zone "+timeZone)
+	}
+
+	if len(r.Form["window"]) == 0 {
+		return nil, MissingField("window")
+	}
+	var windows []booking.Window
+	for _, value := range r.Form["window"] {
+		parsed, err := booking.ParseWindows(value)
+		if err != nil {
+			return nil, InvalidField("window", err.Error())
+		}
+		windows = append(windows, parsed...)
+	}
+
+	holidayCalendar := r.FormValue("holiday_calendar")
+	if holidayCalendar != "" {
+		if _, ok := holidays.Get(holidayCalendar); !ok {
+			return nil, InvalidField("holiday_calendar", "unknown holiday calendar "+holidayCalendar)
+		}
+	}
+
+	params := map[string]interface{}{
+		"slug":             slug,
+		"holiday_calendar": holidayCalendar,
+		"calendar_id":      r.FormValue("calendar_id"),
+		"title":            title,
+		"slot_minutes":     slotMinutes,
+		"time_zone":        timeZone,
+		"windows":          windows,
+	}
+
+	return params, nil
+}
+
+func ParseAndValidateSlotsQuery(r *http.Request, now time.Time) (map[string]interface{}, error) {
+	query := r.URL.Query()
+
+	slug := query.Get("page")
+	if slug == "" {
+		return nil, MissingField("page")
+	}
+
+	from := now
+	if fromStr := query.Get("from"); fromStr != "" {
+		date, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return nil, InvalidField("from", "invalid date format, expected YYYY-MM-DD")
+		}
+		from = date
+	}
+
+	days := 7
+	if daysStr := query.Get("days"); daysStr != "" {
+		n, err := strconv.Atoi(daysStr)
+		if err != nil || n < 1 || n > 31 {
+			return nil, InvalidField("days", "days must be between 1 and 31")
+		}
+		days = n
+	}
+
+	params := map[string]interface{}{
+		"page": slug,
+		"from": from,
+		"days": days,
+	}
+
+	return params, nil
+}
+
+func ParseAndValidateBooking(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	slug := r.FormValue("page")
+	if slug == "" {
+		return nil, MissingField("page")
+	}
+
+	startStr := r.FormValue("start")
+	if startStr == "" {
+		return nil, MissingField("start")
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return nil, InvalidField("start", "invalid start format, expected RFC 3339")
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		return nil, MissingField("name")
+	}
+
+	params := map[string]interface{}{
+		"page":  slug,
+		"start": start,
+		"name":  name,
+		"email": r.FormValue("email"),
+	}
+
+	return params, nil
+}
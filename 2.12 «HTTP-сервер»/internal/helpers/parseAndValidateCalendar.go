@@ -0,0 +1,161 @@
+package helpers
+
+import (
+	"calendar/internal/service"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const DefaultCalendarColour = "#4285f4"
+
+var colourPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func ParseAndValidateCalendar(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		return nil, MissingField("name")
+	}
+
+	params, err := parseCalendarFields(r)
+	if err != nil {
+		return nil, err
+	}
+	params["name"] = name
+	if _, ok := params["colour"]; !ok {
+		params["colour"] = DefaultCalendarColour
+	}
+	if _, ok := params["visibility"]; !ok {
+		params["visibility"] = service.VisibilityPrivate
+	}
+	return params, nil
+}
+
+// ParseAndValidateUpdateCalendarParams returns the id and only the fields
+// present in the request, so that omitted fields keep their values.
+func ParseAndValidateUpdateCalendarParams(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		return nil, MissingField("id")
+	}
+
+	params, err := parseCalendarFields(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.Form["name"]; ok {
+		name := strings.TrimSpace(r.FormValue("name"))
+		if name == "" {
+			return nil, InvalidField("name", "name must not be empty")
+		}
+		params["name"] = name
+	}
+	params["id"] = id
+	return params, nil
+}
+
+func parseCalendarFields(r *http.Request) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+
+	if colour := r.FormValue("colour"); colour != "" {
+		if !colourPattern.MatchString(colour) {
+			return nil, InvalidField("colour", "invalid colour, expected #RRGGBB")
+		}
+		params["colour"] = strings.ToLower(colour)
+	}
+
+	if value := r.FormValue("visibility"); value != "" {
+		visibility := service.Visibility(strings.ToLower(value))
+		switch visibility {
+		case service.VisibilityPrivate, service.VisibilityShared, service.VisibilityPublic:
+			params["visibility"] = visibility
+		default:
+			return nil, InvalidField("visibility", "visibility must be one of: private, shared, public")
+		}
+	}
+	return params, nil
+}
+
+// ParseCalendarFilter returns the calendars whose events a request may
+// see: those listed in the calendars parameter, or else every calendar
+// visible to the user who sent it. In the latter case the list also holds ""
+// for events outside any calendar; nil means nothing is hidden. Calendars
+// the user may not see are reported as unknown.
+func ParseCalendarFilter(r *http.Request, storage service.Storage) ([]string, error) {
+	user := User(r)
+	value := r.URL.Query().Get("calendars")
+	if value == "" {
+		calendars := storage.GetCalendars()
+		ids := []string{""}
+		for _, calendar := range calendars {
+			if calendar.VisibleTo(user) {
+				ids = append(ids, calendar.ID)
+			}
+		}
+		if len(ids) == len(calendars)+1 {
+			return nil, nil
+		}
+		return ids, nil
+	}
+
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if calendar, ok := storage.GetCalendar(id); !ok || !calendar.VisibleTo(user) {
+			return nil, InvalidField("calendars", "unknown calendar "+id)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CheckCalendarWrite returns the status and error to answer with when the
+// user may not put events into the calendar with the given id, taken from
+// field. Events outside any calendar are open to everyone, and calendars
+// the user may not see are reported as unknown.
+func CheckCalendarWrite(storage service.Storage, user, field, id string) (int, error) {
+	if id == "" {
+		return 0, nil
+	}
+	calendar, ok := storage.GetCalendar(id)
+	if !ok || !calendar.VisibleTo(user) {
+		return http.StatusBadRequest, InvalidField(field, "unknown calendar "+id)
+	}
+	if !calendar.WritableBy(user) {
+		return http.StatusForbidden, Forbidden("only the owner may change calendar " + id)
+	}
+	return 0, nil
+}
+
+// CheckEventWrite is CheckCalendarWrite for an existing event: events of
+// calendars the user may not see are reported as not found.
+func CheckEventWrite(storage service.Storage, user string, event service.Event) (int, error) {
+	calendar, ok := storage.GetCalendar(event.CalendarID)
+	switch {
+	case !ok:
+		return 0, nil
+	case !calendar.VisibleTo(user):
+		return http.StatusNotFound, NotFound("id not found")
+	case !calendar.WritableBy(user):
+		return http.StatusForbidden, Forbidden("only the owner may change events of calendar " + calendar.ID)
+	}
+	return 0, nil
+}
+
+// EventVisible reports whether the user may see the event. Like
+// CheckEventWrite, it treats events without a calendar, or whose calendar
+// is gone, as open to everyone.
+func EventVisible(storage service.Storage, user string, event service.Event) bool {
+	calendar, ok := storage.GetCalendar(event.CalendarID)
+	return !ok || calendar.VisibleTo(user)
+}
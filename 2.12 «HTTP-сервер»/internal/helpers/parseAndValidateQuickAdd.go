@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func ParseAndValidateQuickAdd(r *http.Request, now time.Time) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	text := r.FormValue("text")
+	if text == "" {
+		return nil, MissingField("text")
+	}
+
+	timeZone := r.FormValue("time_zone")
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, InvalidField("time_zone", "unknown time zone "+timeZone)
+	}
+
+	reference := now
+	if referenceStr := r.FormValue("reference"); referenceStr != "" {
+		if reference, err = time.Parse(time.RFC3339, referenceStr); err != nil {
+			return nil, InvalidField("reference", "invalid reference format, expected RFC 3339")
+		}
+	}
+
+	confirm := false
+	if confirmStr := r.FormValue("confirm"); confirmStr != "" {
+		if confirm, err = strconv.ParseBool(confirmStr); err != nil {
+			return nil, InvalidField("confirm", "confirm must be true or false")
+		}
+	}
+
+	params := map[string]interface{}{
+		"text":        text,
+		"reference":   reference.In(location),
+		"calendar_id": r.FormValue("calendar_id"),
+		"confirm":     confirm,
+	}
+
+	return params, nil
+}
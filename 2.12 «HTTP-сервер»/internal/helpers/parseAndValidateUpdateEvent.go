@@ -2,8 +2,8 @@ package helpers
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -15,27 +15,51 @@ func WriteJSONResponse(w http.ResponseWriter, status int, result interface{}) {
 
 func ParseAndValidateEvent(r *http.Request) (map[string]interface{}, error) {
 	if err := r.ParseForm(); err != nil {
-		return nil, errors.New("invalid form date")
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
 	}
+	return ValidateEventValues(r.Form)
+}
 
-	title := r.FormValue("title")
+// ValidateEventValues applies the rules of ParseAndValidateEvent to values
+// that did not come from a request, such as rows of an import. all_day
+// tells whether date was given as a day rather than as a start time.
+func ValidateEventValues(form url.Values) (map[string]interface{}, error) {
+	title := form.Get("title")
 	if title == "" {
-		return nil, errors.New("title is required")
+		return nil, MissingField("title")
 	}
 
-	dateStr := r.FormValue("date")
+	dateStr := form.Get("date")
 	if dateStr == "" {
-		return nil, errors.New("date is required")
+		return nil, MissingField("date")
 	}
 
 	date, err := time.Parse("2006-01-02", dateStr)
+	allDay := err == nil
 	if err != nil {
-		return nil, errors.New("invalid date format, expected YYYY-MM-FF")
+		if date, err = time.Parse(time.RFC3339, dateStr); err != nil {
+			return nil, InvalidField("date", "invalid date format, expected YYYY-MM-DD or an RFC 3339 start time")
+		}
+		date = date.UTC()
 	}
 
 	params := map[string]interface{}{
-		"title": title,
-		"date":  date,
+		"title":   title,
+		"date":    date,
+		"all_day": allDay,
+	}
+	if endStr := form.Get("end"); endStr != "" {
+		end, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return nil, InvalidField("end", "invalid end format, expected RFC 3339")
+		}
+		if !end.After(date) {
+			return nil, InvalidField("end", "end must be after date")
+		}
+		params["end"] = end.UTC()
+	}
+	if _, ok := form["calendar_id"]; ok {
+		params["calendar_id"] = form.Get("calendar_id")
 	}
 
 	return params, nil
@@ -43,12 +67,12 @@ func ParseAndValidateEvent(r *http.Request) (map[string]interface{}, error) {
 
 func ParseAndValidateUpdateEventParams(r *http.Request) (map[string]interface{}, error) {
 	if err := r.ParseForm(); err != nil {
-		return nil, errors.New("invalid form date")
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
 	}
 
 	eventID := r.FormValue("id")
 	if eventID == "" {
-		return nil, errors.New("id is required")
+		return nil, MissingField("id")
 	}
 
 	params, err := ParseAndValidateEvent(r)
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"calendar/internal/service"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func ParseAndValidateWebhook(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &APIError{Code: CodeInvalidRequest, Message: "invalid form data"}
+	}
+
+	rawURL := r.FormValue("url")
+	if rawURL == "" {
+		return nil, MissingField("url")
+	}
+
+	target, err := url.ParseRequestURI(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, InvalidField("url", "invalid url, expected http(s)://host/path")
+	}
+
+	var eventTypes []service.ChangeType
+	if events := r.FormValue("events"); events != "" {
+		for _, name := range strings.Split(events, ",") {
+			changeType := service.ChangeType(strings.TrimSpace(name))
+			switch changeType {
+			case service.EventCreated, service.EventUpdated, service.EventDeleted, service.EventArchived:
+				eventTypes = append(eventTypes, changeType)
+			default:
+				return nil, InvalidField("events", "unknown event type: "+string(changeType))
+			}
+		}
+	}
+
+	secret := r.FormValue("secret")
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, &APIError{Code: CodeInternal, Message: "failed to generate secret"}
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	params := map[string]interface{}{
+		"url":    target.String(),
+		"events": eventTypes,
+		"secret": secret,
+		"user":   strings.TrimSpace(r.FormValue("user")),
+	}
+
+	return params, nil
+}
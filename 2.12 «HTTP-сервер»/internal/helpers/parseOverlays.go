@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"calendar/internal/holiday"
+	"net/http"
+	"strings"
+)
+
+func ParseOverlays(r *http.Request, holidays *holiday.Set) ([]*holiday.Calendar, error) {
+	value := r.URL.Query().Get("overlays")
+	if value == "" {
+		return nil, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	calendars, err := holidays.Resolve(names)
+	if err != nil {
+		return nil, InvalidField("overlays", err.Error())
+	}
+	return calendars, nil
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"calendar/internal/period"
+	"calendar/internal/preference"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ParseWeekStart picks the first day of the week from the week_start
+// parameter, then the preference of the user who sent the request, then
+// the locale or Accept-Language, then the fallback.
+func ParseWeekStart(r *http.Request, fallback time.Weekday, preferences *preference.Registry) (time.Weekday, error) {
+	query := r.URL.Query()
+	if value := query.Get("week_start"); value != "" {
+		weekStart, err := period.ParseWeekStart(value)
+		if err != nil {
+			return weekStart, InvalidField("week_start", err.Error())
+		}
+		return weekStart, nil
+	}
+
+	if user := User(r); user != "" && preferences != nil {
+		if weekStart, ok := preferences.WeekStart(user); ok {
+			return weekStart, nil
+		}
+	}
+
+	locale := query.Get("locale")
+	if locale == "" {
+		locale = strings.TrimSpace(strings.Split(strings.Split(r.Header.Get("Accept-Language"), ",")[0], ";")[0])
+	}
+	if weekStart, ok := period.WeekStartForLocale(locale); ok && locale != "*" && locale != "" {
+		return weekStart, nil
+	}
+	return fallback, nil
+}
@@ -0,0 +1,181 @@
+package holiday
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Holiday struct {
+	Date     string `json:"date"`
+	Name     string `json:"name"`
+	Calendar string `json:"calendar"`
+	Working  bool   `json:"working,omitempty"`
+}
+
+type Calendar struct {
+	Name    string         `json:"name"`
+	Title   string         `json:"title,omitempty"`
+	Weekend []time.Weekday `json:"weekend"`
+
+	days  map[string][]Holiday
+	rules []rule
+}
+
+type Set struct {
+	calendars map[string]*Calendar
+}
+
+func newCalendar(name string) *Calendar {
+	return &Calendar{
+		Name:    name,
+		Weekend: []time.Weekday{time.Saturday, time.Sunday},
+		days:    make(map[string][]Holiday),
+	}
+}
+
+func (c *Calendar) add(h Holiday) {
+	h.Calendar = c.Name
+	c.days[h.Date] = append(c.days[h.Date], h)
+}
+
+func (c *Calendar) HolidaysOn(date time.Time) []Holiday {
+	holidays := c.days[date.Format("2006-01-02")]
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for _, r := range c.rules {
+		if r.on(day) {
+			// Never append to the slice stored in c.days.
+			holidays = append(holidays[:len(holidays):len(holidays)], Holiday{Date: day.Format("2006-01-02"), Name: r.name, Calendar: c.Name})
+		}
+	}
+	return holidays
+}
+
+func (c *Calendar) Holidays(from, to time.Time) []Holiday {
+	var holidays []Holiday
+	for date, day := range c.days {
+		parsed, _ := time.Parse("2006-01-02", date)
+		if !parsed.Before(from) && parsed.Before(to) {
+			holidays = append(holidays, day...)
+		}
+	}
+	for _, r := range c.rules {
+		for _, h := range r.between(from, to) {
+			h.Calendar = c.Name
+			holidays = append(holidays, h)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+	return holidays
+}
+
+// IsWorkingDay reports whether date is a working day: weekends and
+// holidays are not, unless the calendar explicitly marks the date as a
+// working day (e.g. a Saturday worked in exchange for a bridge holiday).
+func (c *Calendar) IsWorkingDay(date time.Time) bool {
+	holidays := c.HolidaysOn(date)
+	for _, h := range holidays {
+		if h.Working {
+			return true
+		}
+	}
+	if len(holidays) > 0 {
+		return false
+	}
+	for _, weekend := range c.Weekend {
+		if date.Weekday() == weekend {
+			return false
+		}
+	}
+	return true
+}
+
+func NewSet(calendars ...*Calendar) *Set {
+	set := &Set{calendars: make(map[string]*Calendar)}
+	for _, c := range calendars {
+		set.calendars[c.Name] = c
+	}
+	return set
+}
+
+func (s *Set) Get(name string) (*Calendar, bool) {
+	c, ok := s.calendars[name]
+	return c, ok
+}
+
+func (s *Set) List() []*Calendar {
+	calendars := make([]*Calendar, 0, len(s.calendars))
+	for _, c := range s.calendars {
+		calendars = append(calendars, c)
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		return calendars[i].Name < calendars[j].Name
+	})
+	return calendars
+}
+
+func (s *Set) Resolve(names []string) ([]*Calendar, error) {
+	var calendars []*Calendar
+	for _, name := range names {
+		c, ok := s.calendars[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown holiday calendar %q", name)
+		}
+		calendars = append(calendars, c)
+	}
+	return calendars, nil
+}
+
+// LoadDir loads every .ics and .json file of dir as a calendar named
+// after the file, e.g. holidays/ru.json becomes the "ru" calendar.
+func LoadDir(dir string) (*Set, error) {
+	set := NewSet()
+	if dir == "" {
+		return set, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".ics" && ext != ".json") {
+			continue
+		}
+		c, err := LoadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		set.calendars[c.Name] = c
+	}
+	return set, nil
+}
+
+func LoadFile(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var c *Calendar
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics":
+		c, err = parseICS(file, name)
+	case ".json":
+		c, err = parseJSON(file, name)
+	default:
+		return nil, fmt.Errorf("unsupported holiday calendar format: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,170 @@
+package holiday
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"X-WR-CALNAME:Team Berlin\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261224\r\n" +
+	"DTEND;VALUE=DATE:20261227\r\n" +
+	"SUMMARY:Christmas\\, office \r\n" +
+	" closed\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20261003T000000Z\r\n" +
+	"SUMMARY:Unity Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+const testJSON = `{
+  "title": "Russia",
+  "weekend": ["saturday", "sunday"],
+  "holidays": [
+    {"date": "2026-11-04", "name": "Unity Day"},
+    {"date": "2026-11-07", "name": "Working Saturday", "working": true}
+  ]
+}`
+
+func date(value string) time.Time {
+	d, _ := time.Parse("2006-01-02", value)
+	return d
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "team.ics"), []byte(testICS), 0o644)
+	os.WriteFile(filepath.Join(dir, "ru.json"), []byte(testJSON), 0o644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644)
+
+	set, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.List()) != 2 {
+		t.Fatalf("expected 2 calendars, got %v", set.List())
+	}
+
+	team, _ := set.Get("team")
+	if team.Title != "Team Berlin" {
+		t.Errorf("unexpected ICS title %q", team.Title)
+	}
+	holidays := team.Holidays(date("2026-12-01"), date("2027-01-01"))
+	if len(holidays) != 3 || holidays[0].Name != "Christmas, office closed" || holidays[2].Date != "2026-12-26" {
+		t.Errorf("unexpected ICS holidays: %+v", holidays)
+	}
+	if len(team.HolidaysOn(date("2026-10-03"))) != 1 {
+		t.Error("date-time DTSTART was not loaded")
+	}
+
+	if _, err := set.Resolve([]string{"ru", "missing"}); err == nil {
+		t.Error("expected an error for an unknown calendar")
+	}
+}
+
+func TestIsWorkingDay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ru.json")
+	os.WriteFile(path, []byte(testJSON), 0o644)
+	ru, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date    string
+		working bool
+	}{
+		{"2026-11-03", true},
+		{"2026-11-04", false},
+		{"2026-11-07", true},
+		{"2026-11-08", false},
+	}
+	for _, test := range tests {
+		if got := ru.IsWorkingDay(date(test.date)); got != test.working {
+			t.Errorf("IsWorkingDay(%s) = %v, want %v", test.date, got, test.working)
+		}
+	}
+}
+
+const recurringICS = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20241231\r\n" +
+	"DTEND;VALUE=DATE:20250102\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"EXDATE;VALUE=DATE:20261231\r\n" +
+	"SUMMARY:New Year\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20241128\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;UNTIL=20271231\r\n" +
+	"SUMMARY:Thanksgiving\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240527\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO;COUNT=2\r\n" +
+	"SUMMARY:Memorial Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestRecurringHolidays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "us.ics")
+	os.WriteFile(path, []byte(recurringICS), 0o644)
+	us, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-12-31", "New Year"},
+		{"2025-01-01", "New Year"},
+		{"2030-01-01", "New Year"},
+		{"2026-12-31", ""},
+		{"2024-01-01", ""},
+		{"2026-11-26", "Thanksgiving"},
+		{"2027-11-25", "Thanksgiving"},
+		{"2028-11-23", ""},
+		{"2025-05-26", "Memorial Day"},
+		{"2026-05-25", ""},
+	}
+	for _, test := range tests {
+		holidays := us.HolidaysOn(date(test.date))
+		got := ""
+		if len(holidays) > 0 {
+			got = holidays[0].Name
+		}
+		if len(holidays) > 1 || got != test.want {
+			t.Errorf("HolidaysOn(%s) = %+v, want %q", test.date, holidays, test.want)
+		}
+	}
+
+	holidays := us.Holidays(date("2025-01-01"), date("2026-01-01"))
+	var got []string
+	for _, h := range holidays {
+		got = append(got, h.Date)
+	}
+	if want := "[2025-01-01 2025-05-26 2025-11-27 2025-12-31]"; fmt.Sprint(got) != want || holidays[0].Calendar != "us" {
+		t.Errorf("Holidays of 2025 = %v, want %s", got, want)
+	}
+	if us.IsWorkingDay(date("2025-11-27")) {
+		t.Error("a recurring holiday is a working day")
+	}
+}
+
+func TestUnsupportedRecurrence(t *testing.T) {
+	for _, rrule := range []string{"FREQ=WEEKLY", "FREQ=YEARLY;BYDAY=MO", "FREQ=YEARLY;BYMONTH=1,7", "FREQ=YEARLY;BYSETPOS=1", "INTERVAL=2"} {
+		ics := "BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20240101\r\nRRULE:" + rrule + "\r\nEND:VEVENT\r\n"
+		if _, err := parseICS(strings.NewReader(ics), "x"); err == nil {
+			t.Errorf("RRULE %s was accepted", rrule)
+		}
+	}
+}
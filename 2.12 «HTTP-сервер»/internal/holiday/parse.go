@@ -0,0 +1,162 @@
+package holiday
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type jsonCalendar struct {
+	Title    string   `json:"title"`
+	Weekend  []string `json:"weekend"`
+	Holidays []struct {
+		Date    string `json:"date"`
+		Name    string `json:"name"`
+		Working bool   `json:"working"`
+	} `json:"holidays"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+func parseJSON(r io.Reader, name string) (*Calendar, error) {
+	var doc jsonCalendar
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	c := newCalendar(name)
+	c.Title = doc.Title
+	if doc.Weekend != nil {
+		c.Weekend = nil
+		for _, day := range doc.Weekend {
+			weekday, ok := weekdayNames[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("invalid weekend day %q", day)
+			}
+			c.Weekend = append(c.Weekend, weekday)
+		}
+	}
+
+	for _, h := range doc.Holidays {
+		if _, err := time.Parse("2006-01-02", h.Date); err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", h.Date)
+		}
+		c.add(Holiday{Date: h.Date, Name: h.Name, Working: h.Working})
+	}
+	return c, nil
+}
+
+// parseICS reads the VEVENTs of an iCalendar file. Only the properties
+// needed for day-level holidays are interpreted: DTSTART, DTEND, SUMMARY,
+// yearly RRULEs and their EXDATEs, plus X-WR-CALNAME for the calendar
+// title.
+func parseICS(r io.Reader, name string) (*Calendar, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCalendar(name)
+	var event map[string]string
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		property, _, _ := strings.Cut(key, ";")
+		property = strings.ToUpper(property)
+
+		switch {
+		case property == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = make(map[string]string)
+		case property == "END" && strings.EqualFold(value, "VEVENT"):
+			if event == nil {
+				return nil, errors.New("END:VEVENT without BEGIN:VEVENT")
+			}
+			if err := addICSEvent(c, event); err != nil {
+				return nil, err
+			}
+			event = nil
+		case event != nil && property == "EXDATE" && event[property] != "":
+			event[property] += "," + value
+		case event != nil:
+			event[property] = value
+		case property == "X-WR-CALNAME":
+			c.Title = unescapeICS(value)
+		}
+	}
+	return c, nil
+}
+
+func addICSEvent(c *Calendar, event map[string]string) error {
+	start, err := parseICSDate(event["DTSTART"])
+	if err != nil {
+		return err
+	}
+	end := start.AddDate(0, 0, 1)
+	if value, ok := event["DTEND"]; ok {
+		if end, err = parseICSDate(value); err != nil {
+			return err
+		}
+	}
+
+	summary := unescapeICS(event["SUMMARY"])
+	if value, ok := event["RRULE"]; ok {
+		except := make(map[string]bool)
+		if exdates := event["EXDATE"]; exdates != "" {
+			for _, exdate := range strings.Split(exdates, ",") {
+				day, err := parseICSDate(exdate)
+				if err != nil {
+					return err
+				}
+				except[day.Format("2006-01-02")] = true
+			}
+		}
+		r, err := parseRule(value, start, int(end.Sub(start).Hours()/24), summary, except)
+		if err != nil {
+			return err
+		}
+		c.rules = append(c.rules, r)
+		return nil
+	}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		c.add(Holiday{Date: day.Format("2006-01-02"), Name: summary})
+	}
+	return nil
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid iCalendar date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid iCalendar date %q", value)
+	}
+	return date, nil
+}
+
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func unescapeICS(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,159 @@
+package holiday
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// rule repeats an ICS holiday as described by its RRULE. Holidays come
+// back once a year, so only FREQ=YEARLY is supported, on a fixed day or on
+// the nth weekday of a month, e.g. BYMONTH=11;BYDAY=4TH.
+type rule struct {
+	name     string
+	start    time.Time
+	days     int
+	interval int
+	count    int
+	until    time.Time
+	month    time.Month
+	monthDay int
+	// nth is the week of the month weekday falls in, counted from the end
+	// when negative, or 0 when the rule names a fixed day.
+	nth     int
+	weekday time.Weekday
+	except  map[string]bool
+}
+
+func parseRule(value string, start time.Time, days int, name string, except map[string]bool) (rule, error) {
+	r := rule{
+		name:     name,
+		start:    start,
+		days:     days,
+		interval: 1,
+		month:    start.Month(),
+		monthDay: start.Day(),
+		except:   except,
+	}
+	invalid := func(reason string) (rule, error) {
+		return rule{}, fmt.Errorf("unsupported RRULE %q: %s", value, reason)
+	}
+
+	parts := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(val)
+	}
+	for key, val := range parts {
+		var err error
+		switch key {
+		case "FREQ":
+			if val != "YEARLY" {
+				return invalid("only FREQ=YEARLY is supported")
+			}
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(val); err != nil || r.interval < 1 {
+				return invalid("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(val); err != nil || r.count < 1 {
+				return invalid("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if r.until, err = parseICSDate(val); err != nil {
+				return invalid(err.Error())
+			}
+		case "BYMONTH":
+			month, err := strconv.Atoi(val)
+			if err != nil || month < 1 || month > 12 {
+				return invalid("BYMONTH must be a single month")
+			}
+			r.month = time.Month(month)
+		case "BYMONTHDAY":
+			if r.monthDay, err = strconv.Atoi(val); err != nil || r.monthDay < 1 || r.monthDay > 31 {
+				return invalid("BYMONTHDAY must be a single day")
+			}
+		case "BYDAY":
+			weekday, ok := icsWeekdays[val[max(len(val)-2, 0):]]
+			nth, err := strconv.Atoi(val[:max(len(val)-2, 0)])
+			if !ok || err != nil || nth == 0 || nth < -5 || nth > 5 {
+				return invalid("BYDAY must name one weekday of the month, such as 4TH or -1MO")
+			}
+			r.nth, r.weekday = nth, weekday
+		case "WKST":
+		default:
+			return invalid(key + " is not supported")
+		}
+	}
+	if parts["FREQ"] == "" {
+		return invalid("FREQ is missing")
+	}
+	return r, nil
+}
+
+// occurrence returns the first day of the holiday in year, if it falls
+// into that year.
+func (r rule) occurrence(year int) (time.Time, bool) {
+	offset := year - r.start.Year()
+	if offset < 0 || offset%r.interval != 0 || r.count > 0 && offset/r.interval >= r.count {
+		return time.Time{}, false
+	}
+
+	var day time.Time
+	if r.nth != 0 {
+		day = nthWeekday(year, r.month, r.weekday, r.nth)
+	} else {
+		day = time.Date(year, r.month, r.monthDay, 0, 0, 0, 0, time.UTC)
+	}
+	// A 29 February or a fifth Monday is missing in some years.
+	if day.Month() != r.month || r.nth == 0 && day.Day() != r.monthDay {
+		return time.Time{}, false
+	}
+	if day.Before(r.start) || !r.until.IsZero() && day.After(r.until) || r.except[day.Format("2006-01-02")] {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// on reports whether the holiday covers date, a UTC midnight.
+func (r rule) on(date time.Time) bool {
+	// A holiday spanning New Year's Eve began the year before.
+	for year := date.Year() - 1; year <= date.Year(); year++ {
+		if first, ok := r.occurrence(year); ok && !date.Before(first) && date.Before(first.AddDate(0, 0, r.days)) {
+			return true
+		}
+	}
+	return false
+}
+
+// between lists the days of the holiday in [from, to).
+func (r rule) between(from, to time.Time) []Holiday {
+	var holidays []Holiday
+	for year := max(from.Year()-1, r.start.Year()); year <= to.Year(); year++ {
+		first, ok := r.occurrence(year)
+		if !ok {
+			continue
+		}
+		for day := first; day.Before(first.AddDate(0, 0, r.days)); day = day.AddDate(0, 0, 1) {
+			if !day.Before(from) && day.Before(to) {
+				holidays = append(holidays, Holiday{Date: day.Format("2006-01-02"), Name: r.name})
+			}
+		}
+	}
+	return holidays
+}
+
+func nthWeekday(year int, month time.Month, weekday time.Weekday, nth int) time.Time {
+	if nth > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return first.AddDate(0, 0, int(weekday-first.Weekday()+7)%7+7*(nth-1))
+	}
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	return last.AddDate(0, 0, -int(last.Weekday()-weekday+7)%7-7*(-nth-1))
+}
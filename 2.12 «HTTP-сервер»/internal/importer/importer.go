@@ -0,0 +1,401 @@
+// Package importer loads events from CSV or JSON files kept outside the
+// calendar, such as spreadsheet exports. A Mapping names the column or
+// field that holds each event field; rows are validated with the same
+// rules as events created through the API.
+package importer
+
+import (
+	"calendar/internal/helpers"
+	"calendar/internal/service"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case CSV:
+		return CSV, nil
+	case JSON:
+		return JSON, nil
+	}
+	return "", fmt.Errorf("unknown import format %q, expected csv or json", value)
+}
+
+// FormatForFile guesses the format from a file name.
+func FormatForFile(name string) (Format, bool) {
+	switch {
+	case strings.HasSuffix(strings.ToLower(name), ".csv"):
+		return CSV, true
+	case strings.HasSuffix(strings.ToLower(name), ".json"):
+		return JSON, true
+	}
+	return "", false
+}
+
+// Fields are the event fields a Mapping can fill.
+var Fields = []string{"title", "date", "calendar_id", "external_id"}
+
+// Mapping describes how to read events from a file. Fields maps an event
+// field to the CSV column header or JSON key holding it; fields left out
+// are read from a column or key of the same name. DateLayout is the Go
+// layout of dates in the file, YYYY-MM-DD when empty; events get the time
+// of day when the layout has one. CalendarID is used for rows that have no
+// calendar of their own.
+type Mapping struct {
+	Fields     map[string]string `json:"fields,omitempty"`
+	DateLayout string            `json:"date_layout,omitempty"`
+	CalendarID string            `json:"calendar_id,omitempty"`
+	Delimiter  string            `json:"delimiter,omitempty"`
+}
+
+func ParseMapping(data []byte) (Mapping, error) {
+	var m Mapping
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return m, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		return Mapping{}, fmt.Errorf("invalid mapping: %w", err)
+	}
+	return m, m.Validate()
+}
+
+func (m Mapping) Validate() error {
+	for field, source := range m.Fields {
+		known := false
+		for _, name := range Fields {
+			known = known || name == field
+		}
+		if !known {
+			return fmt.Errorf("invalid mapping: unknown event field %q", field)
+		}
+		if source == "" {
+			return fmt.Errorf("invalid mapping: empty source for %q", field)
+		}
+	}
+	if m.Delimiter != "" && utf8.RuneCountInString(m.Delimiter) != 1 {
+		return errors.New("invalid mapping: delimiter must be a single character")
+	}
+	return nil
+}
+
+func (m Mapping) source(field string) string {
+	if source, ok := m.Fields[field]; ok {
+		return source
+	}
+	return field
+}
+
+// Row is one record of the input with its values keyed by event field.
+// Number is the CSV line or the 1-based JSON array index, for reports.
+type Row struct {
+	Number int
+	Values url.Values
+}
+
+func Read(r io.Reader, format Format, m Mapping) ([]Row, error) {
+	switch format {
+	case CSV:
+		return readCSV(r, m)
+	case JSON:
+		return readJSON(r, m)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+func readCSV(r io.Reader, m Mapping) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("invalid csv: missing header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for field, source := range m.Fields {
+		if _, ok := columns[source]; !ok {
+			return nil, fmt.Errorf("invalid csv: no column %q for %s", source, field)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		values := url.Values{}
+		for _, field := range Fields {
+			if i, ok := columns[m.source(field)]; ok && i < len(record) {
+				values.Set(field, strings.TrimSpace(record[i]))
+			}
+		}
+		rows = append(rows, Row{Number: line, Values: values})
+	}
+}
+
+func readJSON(r io.Reader, m Mapping) ([]Row, error) {
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid json: expected an array of objects: %w", err)
+	}
+
+	rows := make([]Row, 0, len(records))
+	for i, record := range records {
+		values := url.Values{}
+		for _, field := range Fields {
+			switch value := record[m.source(field)].(type) {
+			case nil:
+			case string:
+				values.Set(field, strings.TrimSpace(value))
+			case json.Number:
+				values.Set(field, value.String())
+			case bool:
+				values.Set(field, strconv.FormatBool(value))
+			default:
+				return nil, fmt.Errorf("invalid json: item %d: %s must be a string", i+1, m.source(field))
+			}
+		}
+		rows = append(rows, Row{Number: i + 1, Values: values})
+	}
+	return rows, nil
+}
+
+// ExternalIDs lists the external IDs the rows refer to.
+func ExternalIDs(rows []Row) []string {
+	var ids []string
+	for _, row := range rows {
+		if id := row.Values.Get("external_id"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+type Action string
+
+const (
+	Create    Action = "create"
+	Update    Action = "update"
+	Unchanged Action = "unchanged"
+	Invalid   Action = "invalid"
+)
+
+type Result struct {
+	Row    int               `json:"row"`
+	Action Action            `json:"action"`
+	Event  *service.Event    `json:"event,omitempty"`
+	Error  *helpers.APIError `json:"error,omitempty"`
+}
+
+type Report struct {
+	DryRun    bool     `json:"dry_run"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Invalid   int      `json:"invalid"`
+	Rows      []Result `json:"rows"`
+}
+
+// Import creates or updates an event for every valid row. Rows with an
+// external ID update the event imported from it before, so running the same
+// import twice changes nothing; fields the file does not hold, such as the
+// end, are kept. Rows whose external ID belongs to one of the archived
+// events are left alone and reported unchanged. With dryRun the
+// storage is left untouched and the report tells what would happen. Rows
+// that would write to calendars, or update events, the user may not change
+// are reported invalid.
+func Import(storage service.Storage, user string, archived []service.Event, rows []Row, m Mapping, dryRun bool) Report {
+	existing := make(map[string]service.Event)
+	for _, event := range storage.GetEvent() {
+		if event.ExternalID != "" {
+			existing[event.ExternalID] = event
+		}
+	}
+	archivedIDs := make(map[string]service.Event)
+	for _, event := range archived {
+		if event.ExternalID != "" {
+			archivedIDs[event.ExternalID] = event
+		}
+	}
+
+	report := Report{DryRun: dryRun, Rows: make([]Result, 0, len(rows))}
+	seen := make(map[string]int)
+	for _, row := range rows {
+		result := Result{Row: row.Number}
+		event, allDay, err := toEvent(storage, user, row, m)
+		if current, ok := existing[event.ExternalID]; ok && err == nil && event.ExternalID != "" {
+			_, err = helpers.CheckEventWrite(storage, user, current)
+		}
+		switch {
+		case err != nil:
+			result.Action = Invalid
+			result.Error = asAPIError(err)
+		case event.ExternalID != "" && seen[event.ExternalID] != 0:
+			result.Action = Invalid
+			result.Error = helpers.InvalidField("external_id", fmt.Sprintf("external_id %q already used on row %d", event.ExternalID, seen[event.ExternalID]))
+		default:
+			if event.ExternalID != "" {
+				seen[event.ExternalID] = row.Number
+			}
+			if current, ok := archivedIDs[event.ExternalID]; ok && event.ExternalID != "" {
+				result.Action, event = Unchanged, current
+			} else if dryRun {
+				result.Action, event = plan(existing, event, allDay)
+			} else {
+				result.Action, event = apply(storage, event, allDay)
+			}
+			result.Event = &event
+		}
+
+		switch result.Action {
+		case Create:
+			report.Created++
+		case Update:
+			report.Updated++
+		case Unchanged:
+			report.Unchanged++
+		case Invalid:
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	return report
+}
+
+// toEvent also reports whether the row only gives the day of the event.
+func toEvent(storage service.Storage, user string, row Row, m Mapping) (service.Event, bool, error) {
+	values := url.Values{}
+	for field, value := range row.Values {
+		values[field] = value
+	}
+	if values.Get("calendar_id") == "" && m.CalendarID != "" {
+		values.Set("calendar_id", m.CalendarID)
+	}
+	if layout := m.DateLayout; layout != "" && values.Get("date") != "" {
+		date, err := time.Parse(layout, values.Get("date"))
+		if err != nil {
+			return service.Event{}, false, helpers.InvalidField("date", fmt.Sprintf("invalid date %q, expected layout %s", values.Get("date"), layout))
+		}
+		if hasClock(layout) {
+			values.Set("date", date.Format(time.RFC3339))
+		} else {
+			values.Set("date", date.Format("2006-01-02"))
+		}
+	}
+
+	params, err := helpers.ValidateEventValues(values)
+	if err != nil {
+		return service.Event{}, false, err
+	}
+	event := service.Event{
+		Title:      params["title"].(string),
+		Date:       params["date"].(time.Time),
+		ExternalID: values.Get("external_id"),
+	}
+	event.CalendarID, _ = params["calendar_id"].(string)
+	if _, err := helpers.CheckCalendarWrite(storage, user, "calendar_id", event.CalendarID); err != nil {
+		return service.Event{}, false, err
+	}
+	return event, params["all_day"].(bool), nil
+}
+
+// hasClock reports whether dates in the layout carry a time of day.
+func hasClock(layout string) bool {
+	reference := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	parsed, err := time.Parse(layout, reference.Format(layout))
+	if err != nil {
+		return false
+	}
+	hour, minute, second := parsed.Clock()
+	return hour != 0 || minute != 0 || second != 0
+}
+
+// merge writes what the row holds onto the stored event and keeps the
+// rest: the calendar only changes when the row names one, the end of a
+// timed event moves with its start, and a row that only gives the day
+// keeps the time of day of the stored event.
+func merge(current, event service.Event, allDay bool) service.Event {
+	merged := current
+	merged.Title = event.Title
+	if event.CalendarID != "" {
+		merged.CalendarID = event.CalendarID
+	}
+	merged.Date = event.Date
+	if allDay {
+		hour, minute, second := current.Date.Clock()
+		merged.Date = time.Date(event.Date.Year(), event.Date.Month(), event.Date.Day(), hour, minute, second, current.Date.Nanosecond(), current.Date.Location())
+	}
+	if current.Timed() {
+		end := merged.Date.Add(current.End.Sub(current.Date))
+		merged.End = &end
+	}
+	return merged
+}
+
+func plan(existing map[string]service.Event, event service.Event, allDay bool) (Action, service.Event) {
+	current, ok := existing[event.ExternalID]
+	if event.ExternalID == "" || !ok {
+		return Create, event
+	}
+	merged := merge(current, event, allDay)
+	if merged.Title == current.Title && merged.Date.Equal(current.Date) && merged.CalendarID == current.CalendarID {
+		return Unchanged, current
+	}
+	return Update, merged
+}
+
+// apply looks the external ID up and writes the event in one storage
+// operation, so concurrent imports of the same file never duplicate it.
+func apply(storage service.Storage, event service.Event, allDay bool) (Action, service.Event) {
+	stored, change := storage.PutEventByExternalID(event, func(current service.Event) service.Event {
+		return merge(current, event, allDay)
+	})
+	switch change {
+	case service.EventCreated:
+		return Create, stored
+	case service.EventUpdated:
+		return Update, stored
+	}
+	return Unchanged, stored
+}
+
+func asAPIError(err error) *helpers.APIError {
+	var apiErr *helpers.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return &helpers.APIError{Code: helpers.CodeInvalidRequest, Message: err.Error()}
+}
@@ -0,0 +1,287 @@
+package importer
+
+import (
+	"calendar/internal/service"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func actions(report Report) string {
+	result := make([]string, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		entry := fmt.Sprintf("%d:%s", row.Row, row.Action)
+		if row.Error != nil {
+			entry += "(" + row.Error.Field + ")"
+		}
+		result = append(result, entry)
+	}
+	return strings.Join(result, " ")
+}
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{``, false},
+		{`{"fields": {"title": "Subject", "date": "Start"}}`, false},
+		{`{"fields": {"title": "Subject"}, "date_layout": "02.01.2006", "delimiter": ";"}`, false},
+		{`{"fields": {"location": "Where"}}`, true},
+		{`{"fields": {"title": ""}}`, true},
+		{`{"delimiter": ";;"}`, true},
+		{`{"colums": {}}`, true},
+		{`[]`, true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseMapping([]byte(tt.input)); (err != nil) != tt.wantErr {
+			t.Errorf("ParseMapping(%s): error = %v, wantErr %t", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		mapping Mapping
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv with same names",
+			format: CSV,
+			input:  "title,date\nstandup,2024-10-14\n\"lunch, team\",2024-10-15\n",
+			want:   "2:[standup 2024-10-14] 3:[lunch, team 2024-10-15]",
+		},
+		{
+			name:    "csv with mapped columns and delimiter",
+			format:  CSV,
+			mapping: Mapping{Fields: map[string]string{"title": "Тема", "date": "Дата"}, Delimiter: ";"},
+			input:   "\ufeffДата;Тема;Место\n14.10.2024;Планёрка;офис\n",
+			want:    "2:[Планёрка 14.10.2024]",
+		},
+		{
+			name:    "csv missing mapped column",
+			format:  CSV,
+			mapping: Mapping{Fields: map[string]string{"title": "Subject"}},
+			input:   "title,date\nstandup,2024-10-14\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty csv",
+			format:  CSV,
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "json with mapped keys",
+			format:  JSON,
+			mapping: Mapping{Fields: map[string]string{"title": "summary"}},
+			input:   `[{"summary": "standup", "date": "2024-10-14", "uid": 1}, {"date": "2024-10-15"}]`,
+			want:    "1:[standup 2024-10-14] 2:[ 2024-10-15]",
+		},
+		{
+			name:    "json number external id",
+			format:  JSON,
+			mapping: Mapping{Fields: map[string]string{"external_id": "uid"}},
+			input:   `[{"title": "standup", "date": "2024-10-14", "uid": 42}]`,
+			want:    "1:[standup 2024-10-14]",
+		},
+		{
+			name:    "json object value",
+			format:  JSON,
+			input:   `[{"title": {"en": "standup"}, "date": "2024-10-14"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "json not an array",
+			format:  JSON,
+			input:   `{"title": "standup"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Read(strings.NewReader(tt.input), tt.format, tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			got := make([]string, 0, len(rows))
+			for _, row := range rows {
+				got = append(got, fmt.Sprintf("%d:[%s %s]", row.Number, row.Values.Get("title"), row.Values.Get("date")))
+			}
+			if !tt.wantErr && strings.Join(got, " ") != tt.want {
+				t.Errorf("got %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+
+	rows, _ := Read(strings.NewReader(`[{"uid": 42}, {"uid": 12345678901234567890}, {"uid": 1.5}]`), JSON, Mapping{Fields: map[string]string{"external_id": "uid"}})
+	for i, want := range []string{"42", "12345678901234567890", "1.5"} {
+		if got := rows[i].Values.Get("external_id"); got != want {
+			t.Errorf("external_id = %q, want %s", got, want)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	work := storage.CreateCalendar(service.Calendar{Name: "work"})
+	storage.CreateEvent(service.Event{Title: "kept", Date: mustDate(t, "2024-10-01"), ExternalID: "a-1", CalendarID: work.ID})
+	storage.CreateEvent(service.Event{Title: "old title", Date: mustDate(t, "2024-10-02"), ExternalID: "a-2"})
+
+	mapping := Mapping{
+		Fields:     map[string]string{"title": "Subject", "date": "Start", "external_id": "UID"},
+		DateLayout: "02.01.2006",
+		CalendarID: work.ID,
+	}
+	input := "UID,Subject,Start,calendar_id\n" +
+		"a-1,kept,01.10.2024," + work.ID + "\n" +
+		"a-2,new title,02.10.2024,\n" +
+		"a-3,fresh,03.10.2024,\n" +
+		",no id,04.10.2024,\n" +
+		"a-4,,05.10.2024,\n" +
+		"a-5,bad date,2024-10-06,\n" +
+		"a-6,lost,07.10.2024,missing\n" +
+		"a-3,repeat,08.10.2024,\n"
+	rows, err := Read(strings.NewReader(input), CSV, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dry := Import(storage, "", nil, rows, mapping, true)
+	want := "2:unchanged 3:update 4:create 5:create 6:invalid(title) 7:invalid(date) 8:invalid(calendar_id) 9:invalid(external_id)"
+	if got := actions(dry); got != want {
+		t.Errorf("dry run: got %s, want %s", got, want)
+	}
+	if !dry.DryRun || dry.Created != 2 || dry.Updated != 1 || dry.Unchanged != 1 || dry.Invalid != 4 {
+		t.Errorf("dry run counts %+v", dry)
+	}
+	if len(storage.GetEvent()) != 2 {
+		t.Fatalf("dry run changed the storage: %+v", storage.GetEvent())
+	}
+
+	report := Import(storage, "", nil, rows, mapping, false)
+	if got := actions(report); got != want {
+		t.Errorf("import: got %s, want %s", got, want)
+	}
+	events := storage.GetEvent()
+	if len(events) != 4 {
+		t.Fatalf("storage holds %d events, want 4", len(events))
+	}
+	for _, event := range events {
+		if event.CalendarID != work.ID {
+			t.Errorf("%s: calendar %q, want the mapping default", event.Title, event.CalendarID)
+		}
+		if event.ExternalID == "a-2" && event.Title != "new title" {
+			t.Errorf("a-2 was not updated: %+v", event)
+		}
+	}
+	if report.Rows[2].Event == nil || report.Rows[2].Event.ID == "" {
+		t.Errorf("created event has no ID: %+v", report.Rows[2])
+	}
+
+	// Importing the same file again only repeats the row without an external ID.
+	again := Import(storage, "", nil, rows, mapping, false)
+	if again.Created != 1 || again.Updated != 0 || again.Unchanged != 3 || len(storage.GetEvent()) != 5 {
+		t.Errorf("second import %+v, storage holds %d events", again, len(storage.GetEvent()))
+	}
+}
+
+func TestImportChecksOwnership(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	anns := storage.CreateCalendar(service.Calendar{Name: "ann's", Owner: "ann", Visibility: service.VisibilityShared})
+	storage.CreateEvent(service.Event{Title: "review", Date: mustDate(t, "2024-10-01"), ExternalID: "a-1", CalendarID: anns.ID})
+	rows, err := Read(strings.NewReader("external_id,title,date,calendar_id\na-1,mine now,2024-10-01,\na-2,sneaked in,2024-10-02,"+anns.ID+"\na-3,own,2024-10-03,\n"), CSV, Mapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Import(storage, "bob", nil, rows, Mapping{}, false)
+	if got := actions(report); got != "2:invalid() 3:invalid() 4:create" {
+		t.Errorf("got %s", got)
+	}
+	if report.Rows[0].Error.Code != "forbidden" || report.Rows[1].Error.Code != "forbidden" {
+		t.Errorf("unexpected errors %+v, %+v", report.Rows[0].Error, report.Rows[1].Error)
+	}
+	for _, event := range storage.GetEvent() {
+		if event.ExternalID == "a-1" && (event.Title != "review" || event.CalendarID != anns.ID) {
+			t.Errorf("bob changed the event of ann: %+v", event)
+		}
+	}
+}
+
+func TestImportSkipsArchivedEvents(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	archived := []service.Event{{ID: "e1", Title: "retro", Date: mustDate(t, "2020-01-10"), ExternalID: "a-1"}}
+	rows, err := Read(strings.NewReader("external_id,title,date\na-1,retro again,2020-01-10\na-2,fresh,2024-10-14\n"), CSV, Mapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		report := Import(storage, "", archived, rows, Mapping{}, dryRun)
+		if got := actions(report); got != "2:unchanged 3:create" {
+			t.Errorf("dry run %t: got %s", dryRun, got)
+		}
+		if report.Rows[0].Event == nil || report.Rows[0].Event.ID != "e1" {
+			t.Errorf("dry run %t: archived row reported %+v", dryRun, report.Rows[0].Event)
+		}
+	}
+	if events := storage.GetEvent(); len(events) != 1 || events[0].Title != "fresh" {
+		t.Errorf("storage holds %+v", events)
+	}
+}
+
+func TestImportKeepsUnmappedFields(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	work := storage.CreateCalendar(service.Calendar{Name: "work"})
+	start := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	storage.CreateEvent(service.Event{Title: "standup", Date: start, End: &end, ExternalID: "a-1", CalendarID: work.ID})
+	rows, err := Read(strings.NewReader("external_id,title,date\na-1,daily standup,2024-10-02\n"), CSV, Mapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantStart := time.Date(2024, 10, 2, 9, 0, 0, 0, time.UTC)
+	for _, dryRun := range []bool{true, false} {
+		report := Import(storage, "", nil, rows, Mapping{}, dryRun)
+		if got := actions(report); got != "2:update" {
+			t.Fatalf("dry run %t: got %s", dryRun, got)
+		}
+		event := *report.Rows[0].Event
+		if event.Title != "daily standup" || !event.Date.Equal(wantStart) || event.End == nil || !event.End.Equal(wantStart.Add(time.Hour)) || event.CalendarID != work.ID {
+			t.Errorf("dry run %t: merged into %+v", dryRun, event)
+		}
+	}
+	if events := storage.GetEvent(); len(events) != 1 || events[0].Title != "daily standup" || !events[0].Timed() || events[0].CalendarID != work.ID {
+		t.Errorf("storage holds %+v", events)
+	}
+}
+
+func TestImportKeepsTimeOfDay(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	mapping := Mapping{DateLayout: "02.01.2006 15:04"}
+	rows, err := Read(strings.NewReader("title,date\nstandup,03.10.2024 09:30\n"), CSV, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Import(storage, "", nil, rows, mapping, false)
+	if events := storage.GetEvent(); len(events) != 1 || !events[0].Date.Equal(time.Date(2024, 10, 3, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("storage holds %+v", events)
+	}
+}
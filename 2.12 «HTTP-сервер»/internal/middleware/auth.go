@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"calendar/internal/helpers"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// TokenMiddleware lets through only requests that carry the token as
+// "Authorization: Bearer <token>". With an empty token nothing gets
+// through, so the routes it guards stay closed until a token is set.
+func TokenMiddleware(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			helpers.WriteError(w, http.StatusUnauthorized, &helpers.APIError{Code: helpers.CodeUnauthorized, Message: "a valid bearer token is required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
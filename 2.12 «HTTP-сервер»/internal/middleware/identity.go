@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"calendar/internal/helpers"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ParseUserTokens parses a comma-separated list of user:token pairs into a
+// map from token to user.
+func ParseUserTokens(value string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		user, token, ok := strings.Cut(pair, ":")
+		if !ok || user == "" || token == "" {
+			return nil, fmt.Errorf("invalid user token %q, expected user:token", pair)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("token of user %s is used more than once", user)
+		}
+		tokens[token] = user
+	}
+	return tokens, nil
+}
+
+// IdentityMiddleware records who sent the request: the user whose token
+// came as "Authorization: Bearer <token>", or else the common name of a
+// verified TLS client certificate. Other requests stay anonymous; tokens
+// that belong to no user, such as the admin token, are left to the
+// middleware of their routes.
+func IdentityMiddleware(next http.Handler, tokens map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := ""
+		if given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			for token, owner := range tokens {
+				if subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+					user = owner
+				}
+			}
+		}
+		if user == "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			user = r.TLS.VerifiedChains[0][0].Subject.CommonName
+		}
+		next.ServeHTTP(w, helpers.WithUser(r, user))
+	})
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"calendar/internal/helpers"
+	"net/http"
+)
+
+// ReadOnlyMiddleware rejects every request that may modify data on a
+// replica and points the client at the primary instead.
+func ReadOnlyMiddleware(next http.Handler, primary string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("X-Primary", primary)
+			helpers.WriteError(w, http.StatusForbidden, &helpers.APIError{Code: helpers.CodeReadOnly, Message: "this instance is a read-only replica of " + primary})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
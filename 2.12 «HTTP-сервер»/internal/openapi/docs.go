@@ -0,0 +1,167 @@
+package openapi
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Info.Title}} {{.Info.Version}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+.op { border: 1px solid #ddd; border-radius: 4px; margin: 1em 0; padding: 0.5em 1em; }
+.method { display: inline-block; min-width: 4em; font-weight: bold; text-transform: uppercase; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #eee; padding: 4px; text-align: left; vertical-align: top; }
+code { background: #f5f5f5; padding: 0 3px; }
+</style>
+</head>
+<body>
+<h1>{{.Info.Title}} <small>{{.Info.Version}}</small></h1>
+<p>{{.Info.Description}}</p>
+<p>Machine-readable specification: <a href="/openapi.json">/openapi.json</a></p>
+{{range .Operations}}
+<div class="op">
+<h3><span class="method">{{.Method}}</span> <code>{{.Path}}</code></h3>
+<p>{{.Operation.Summary}}</p>
+{{with .Operation.Description}}<p>{{.}}</p>{{end}}
+{{if .Fields}}
+<table>
+<tr><th>Field</th><th>In</th><th>Type</th><th>Required</th><th>Description</th></tr>
+{{range .Fields}}<tr><td><code>{{.Name}}</code></td><td>{{.In}}</td><td>{{.Type}}</td><td>{{if .Required}}yes{{end}}</td><td>{{.Description}}</td></tr>
+{{end}}
+</table>
+{{end}}
+<p>Responses: {{range .Responses}}<code>{{.Status}}</code> {{.Description}}; {{end}}</p>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+type docsOperation struct {
+	Method    string
+	Path      string
+	Operation *Operation
+	Fields    []docsField
+	Responses []docsResponse
+}
+
+type docsField struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type docsResponse struct {
+	Status      string
+	Description string
+}
+
+func (s *Spec) RenderDocs(w io.Writer) error {
+	var operations []docsOperation
+
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		methods := make([]string, 0, len(s.Paths[path]))
+		for method := range s.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			operation := s.Paths[path][method]
+			operations = append(operations, docsOperation{
+				Method:    strings.ToUpper(method),
+				Path:      path,
+				Operation: operation,
+				Fields:    s.docsFields(operation),
+				Responses: s.docsResponses(operation),
+			})
+		}
+	}
+
+	return docsTemplate.Execute(w, struct {
+		Info       Info
+		Operations []docsOperation
+	}{s.Info, operations})
+}
+
+func (s *Spec) docsFields(operation *Operation) []docsField {
+	var fields []docsField
+	for _, param := range operation.Parameters {
+		fields = append(fields, docsField{param.Name, param.In, schemaType(param.Schema), param.Required, param.Description})
+	}
+
+	if operation.RequestBody == nil {
+		return fields
+	}
+	for contentType, media := range operation.RequestBody.Content {
+		if media.Schema == nil {
+			continue
+		}
+		if media.Schema.Ref != "" {
+			fields = append(fields, docsField{refName(media.Schema.Ref), contentType, "object", operation.RequestBody.Required, ""})
+			continue
+		}
+
+		required := make(map[string]bool)
+		for _, name := range media.Schema.Required {
+			required[name] = true
+		}
+		names := make([]string, 0, len(media.Schema.Properties))
+		for name := range media.Schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property := media.Schema.Properties[name]
+			fields = append(fields, docsField{name, "body", schemaType(property), required[name], property.Description})
+		}
+	}
+	return fields
+}
+
+func (s *Spec) docsResponses(operation *Operation) []docsResponse {
+	statuses := make([]string, 0, len(operation.Responses))
+	for status := range operation.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	responses := make([]docsResponse, 0, len(statuses))
+	for _, status := range statuses {
+		response := operation.Responses[status]
+		if response.Ref != "" {
+			response = s.Components.Responses[refName(response.Ref)]
+		}
+		responses = append(responses, docsResponse{status, response.Description})
+	}
+	return responses
+}
+
+func schemaType(schema *Schema) string {
+	if schema == nil {
+		return ""
+	}
+	if schema.Format != "" {
+		return schema.Type + " (" + schema.Format + ")"
+	}
+	return schema.Type
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"calendar/internal/helpers"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed openapi.json
+var document []byte
+
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Ref         string `json:"$ref"`
+	Description string `json:"description"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Pattern     string             `json:"pattern"`
+	Enum        []string           `json:"enum"`
+	MinLength   *int               `json:"minLength"`
+	Minimum     *float64           `json:"minimum"`
+	Maximum     *float64           `json:"maximum"`
+	Description string             `json:"description"`
+	Required    []string           `json:"required"`
+	Properties  map[string]*Schema `json:"properties"`
+	Items       *Schema            `json:"items"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema  `json:"schemas"`
+	Responses map[string]Response `json:"responses"`
+}
+
+func Document() []byte {
+	return document
+}
+
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return &spec, nil
+}
+
+func (s *Spec) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		item, ok := s.Paths[r.URL.Path]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		operation, ok := item[strings.ToLower(r.Method)]
+		if !ok {
+			helpers.WriteMethodNotAllowed(w, s.allowedMethods(r.URL.Path)...)
+			return
+		}
+
+		if err := s.ValidateRequest(operation, r); err != nil {
+			helpers.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Spec) allowedMethods(path string) []string {
+	var methods []string
+	for method := range s.Paths[path] {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func (s *Spec) ValidateRequest(operation *Operation, r *http.Request) error {
+	query := r.URL.Query()
+	for _, param := range operation.Parameters {
+		if param.In != "query" {
+			continue
+		}
+		if err := validateField(param.Name, query[param.Name], param.Required, param.Schema); err != nil {
+			return err
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return nil
+	}
+
+	if media, ok := operation.RequestBody.Content["application/x-www-form-urlencoded"]; ok && media.Schema != nil {
+		if err := r.ParseForm(); err != nil {
+			return &helpers.APIError{Code: helpers.CodeInvalidRequest, Message: "invalid form data"}
+		}
+		return validateObject(r.Form, media.Schema)
+	}
+	return nil
+}
+
+func validateObject(form url.Values, schema *Schema) error {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+		if form.Get(name) == "" {
+			return helpers.MissingField(name)
+		}
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := validateField(name, form[name], required[name], schema.Properties[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateField(name string, values []string, required bool, schema *Schema) error {
+	if len(values) == 0 || values[0] == "" {
+		if required {
+			return helpers.MissingField(name)
+		}
+		return nil
+	}
+	if schema == nil {
+		return nil
+	}
+	return validateValue(name, values[0], schema)
+}
+
+func validateValue(name, value string, schema *Schema) error {
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return helpers.InvalidField(name, name+" must be an integer")
+		}
+		if schema.Minimum != nil && float64(n) < *schema.Minimum {
+			return helpers.InvalidField(name, fmt.Sprintf("%s must be at least %v", name, *schema.Minimum))
+		}
+		if schema.Maximum != nil && float64(n) > *schema.Maximum {
+			return helpers.InvalidField(name, fmt.Sprintf("%s must be at most %v", name, *schema.Maximum))
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return helpers.InvalidField(name, name+" must be a boolean")
+		}
+	}
+
+	switch schema.Format {
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return helpers.InvalidField(name, "invalid date format, expected YYYY-MM-DD")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return helpers.InvalidField(name, "invalid date-time format, expected RFC 3339")
+		}
+	case "uri":
+		target, err := url.ParseRequestURI(value)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return helpers.InvalidField(name, "invalid url, expected http(s)://host/path")
+		}
+	}
+
+	if schema.MinLength != nil && len([]rune(value)) < *schema.MinLength {
+		return helpers.InvalidField(name, fmt.Sprintf("%s must be at least %d characters", name, *schema.MinLength))
+	}
+
+	if schema.Pattern != "" {
+		matched, err := regexp.MatchString(schema.Pattern, value)
+		if err != nil || !matched {
+			return helpers.InvalidField(name, fmt.Sprintf("%s must match %s", name, schema.Pattern))
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if strings.EqualFold(allowed, value) {
+				return nil
+			}
+		}
+		return helpers.InvalidField(name, fmt.Sprintf("%s must be one of: %s", name, strings.Join(schema.Enum, ", ")))
+	}
+	return nil
+}
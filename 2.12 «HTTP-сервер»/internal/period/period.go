@@ -0,0 +1,123 @@
+package period
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Period struct {
+	Start     time.Time
+	End       time.Time
+	WeekStart time.Weekday
+}
+
+var sundayRegions = map[string]bool{
+	"US": true, "CA": true, "MX": true, "BR": true, "JP": true, "KR": true, "TW": true,
+	"HK": true, "PH": true, "IL": true, "IN": true, "ZA": true, "SA": true,
+}
+
+var saturdayRegions = map[string]bool{
+	"AE": true, "AF": true, "DZ": true, "EG": true, "IQ": true, "IR": true,
+	"JO": true, "KW": true, "LY": true, "OM": true, "QA": true, "SY": true,
+}
+
+func ParseWeekStart(value string) (time.Weekday, error) {
+	switch strings.ToLower(value) {
+	case "monday", "mon", "iso":
+		return time.Monday, nil
+	case "sunday", "sun":
+		return time.Sunday, nil
+	case "saturday", "sat":
+		return time.Saturday, nil
+	}
+	return time.Sunday, fmt.Errorf("invalid week start %q, expected monday, sunday or saturday", value)
+}
+
+// WeekStartForLocale returns the first day of the week for a BCP 47 tag
+// such as "ru-RU" or "en-US". Tags without a region fall back to the
+// most common region for the language.
+func WeekStartForLocale(locale string) (time.Weekday, bool) {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return time.Sunday, false
+	}
+
+	region := ""
+	for _, part := range parts[1:] {
+		if len(part) == 2 {
+			region = strings.ToUpper(part)
+			break
+		}
+	}
+	if region == "" {
+		if strings.EqualFold(parts[0], "en") {
+			region = "US"
+		} else {
+			return time.Monday, true
+		}
+	}
+
+	switch {
+	case sundayRegions[region]:
+		return time.Sunday, true
+	case saturdayRegions[region]:
+		return time.Saturday, true
+	}
+	return time.Monday, true
+}
+
+func StartOfDay(date time.Time) time.Time {
+	year, month, day := date.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+}
+
+func Week(date time.Time, weekStart time.Weekday) Period {
+	day := StartOfDay(date)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	start := day.AddDate(0, 0, -offset)
+	return Period{Start: start, End: start.AddDate(0, 0, 7), WeekStart: weekStart}
+}
+
+func Month(date time.Time, weekStart time.Weekday) Period {
+	start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	return Period{Start: start, End: start.AddDate(0, 1, 0), WeekStart: weekStart}
+}
+
+var isoWeekPattern = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+
+func ParseISOWeek(value string) (Period, error) {
+	match := isoWeekPattern.FindStringSubmatch(strings.ToUpper(value))
+	if match == nil {
+		return Period{}, errors.New("invalid week format, expected YYYY-Www")
+	}
+	year, _ := strconv.Atoi(match[1])
+	week, _ := strconv.Atoi(match[2])
+
+	// 28 December is always in the last ISO week of its year, which is 52
+	// or 53.
+	if _, lastWeek := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek(); week < 1 || week > lastWeek {
+		return Period{}, fmt.Errorf("week %d is out of range for year %d", week, year)
+	}
+
+	// 4 January is always in ISO week 1.
+	first := Week(time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC), time.Monday)
+	start := first.Start.AddDate(0, 0, 7*(week-1))
+	return Period{Start: start, End: start.AddDate(0, 0, 7), WeekStart: time.Monday}, nil
+}
+
+func ISOWeekString(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+func (p Period) Days() []time.Time {
+	var days []time.Time
+	for day := p.Start; day.Before(p.End); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
@@ -0,0 +1,158 @@
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestWeek(t *testing.T) {
+	tests := []struct {
+		date      time.Time
+		weekStart time.Weekday
+		start     time.Time
+	}{
+		// Wednesday 16 October 2024.
+		{time.Date(2024, 10, 16, 15, 30, 0, 0, time.UTC), time.Monday, date(2024, 10, 14)},
+		{date(2024, 10, 16), time.Sunday, date(2024, 10, 13)},
+		{date(2024, 10, 16), time.Saturday, date(2024, 10, 12)},
+		// The first day of the week starts its own week.
+		{date(2024, 10, 14), time.Monday, date(2024, 10, 14)},
+		{date(2024, 10, 13), time.Monday, date(2024, 10, 7)},
+		{date(2024, 10, 12), time.Saturday, date(2024, 10, 12)},
+		// Weeks cross month and year boundaries.
+		{date(2025, 1, 1), time.Monday, date(2024, 12, 30)},
+		{date(2025, 1, 1), time.Sunday, date(2024, 12, 29)},
+	}
+
+	for _, test := range tests {
+		week := Week(test.date, test.weekStart)
+		if !week.Start.Equal(test.start) || !week.End.Equal(test.start.AddDate(0, 0, 7)) || week.WeekStart != test.weekStart {
+			t.Errorf("Week(%s, %s) = [%s, %s), want it to start on %s", test.date.Format("2006-01-02"), test.weekStart, week.Start, week.End, test.start.Format("2006-01-02"))
+		}
+		if days := week.Days(); len(days) != 7 || days[0].Weekday() != test.weekStart {
+			t.Errorf("Week(%s, %s) has days %v", test.date.Format("2006-01-02"), test.weekStart, days)
+		}
+	}
+}
+
+func TestWeekAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Clocks go back on Sunday 27 October 2024.
+	week := Week(time.Date(2024, 10, 30, 12, 0, 0, 0, berlin), time.Monday)
+	if want := time.Date(2024, 10, 28, 0, 0, 0, 0, berlin); !week.Start.Equal(want) {
+		t.Errorf("week starts at %s, want %s", week.Start, want)
+	}
+	week = Week(time.Date(2024, 10, 27, 12, 0, 0, 0, berlin), time.Monday)
+	for _, day := range week.Days() {
+		if day.Hour() != 0 {
+			t.Errorf("day %s does not start at midnight", day)
+		}
+	}
+}
+
+func TestMonth(t *testing.T) {
+	month := Month(time.Date(2024, 2, 29, 23, 0, 0, 0, time.UTC), time.Monday)
+	if !month.Start.Equal(date(2024, 2, 1)) || !month.End.Equal(date(2024, 3, 1)) || len(month.Days()) != 29 {
+		t.Errorf("February 2024 is [%s, %s)", month.Start, month.End)
+	}
+	month = Month(date(2024, 12, 31), time.Sunday)
+	if !month.Start.Equal(date(2024, 12, 1)) || !month.End.Equal(date(2025, 1, 1)) {
+		t.Errorf("December 2024 is [%s, %s)", month.Start, month.End)
+	}
+}
+
+func TestParseISOWeek(t *testing.T) {
+	tests := []struct {
+		value string
+		start time.Time
+	}{
+		{"2024-W42", date(2024, 10, 14)},
+		{"2024-w01", date(2024, 1, 1)},
+		// Week 1 of 2025 starts in December 2024, week 1 of 2021 on 4 January.
+		{"2025-W01", date(2024, 12, 30)},
+		{"2021-W01", date(2021, 1, 4)},
+		// 2020 has 53 ISO weeks; the last one ends in 2021.
+		{"2020-W53", date(2020, 12, 28)},
+		{"2024-W52", date(2024, 12, 23)},
+	}
+	for _, test := range tests {
+		week, err := ParseISOWeek(test.value)
+		if err != nil {
+			t.Errorf("ParseISOWeek(%q) returned %v", test.value, err)
+			continue
+		}
+		if !week.Start.Equal(test.start) || !week.End.Equal(test.start.AddDate(0, 0, 7)) || week.WeekStart != time.Monday {
+			t.Errorf("ParseISOWeek(%q) = [%s, %s), want it to start on %s", test.value, week.Start, week.End, test.start.Format("2006-01-02"))
+		}
+	}
+
+	for _, value := range []string{"2026-W42xyz", "2026-W4", "26-W42", "2026W42", "2026-42", "", "2026-W00", "2024-W53", "2026-W54"} {
+		if _, err := ParseISOWeek(value); err == nil {
+			t.Errorf("ParseISOWeek(%q) accepted an invalid week", value)
+		}
+	}
+}
+
+func TestISOWeekString(t *testing.T) {
+	tests := []struct {
+		date time.Time
+		want string
+	}{
+		{date(2024, 10, 16), "2024-W42"},
+		// Days around New Year may belong to the week of the other year.
+		{date(2024, 12, 30), "2025-W01"},
+		{date(2021, 1, 3), "2020-W53"},
+		{date(2021, 1, 4), "2021-W01"},
+		{date(2023, 1, 1), "2022-W52"},
+	}
+	for _, test := range tests {
+		if got := ISOWeekString(test.date); got != test.want {
+			t.Errorf("ISOWeekString(%s) = %s, want %s", test.date.Format("2006-01-02"), got, test.want)
+		}
+		week, err := ParseISOWeek(test.want)
+		if err != nil || test.date.Before(week.Start) || !test.date.Before(week.End) {
+			t.Errorf("%s is not within ParseISOWeek(%s) = [%s, %s)", test.date.Format("2006-01-02"), test.want, week.Start, week.End)
+		}
+	}
+}
+
+func TestParseWeekStart(t *testing.T) {
+	tests := map[string]time.Weekday{"monday": time.Monday, "ISO": time.Monday, "Sun": time.Sunday, "saturday": time.Saturday}
+	for value, want := range tests {
+		if got, err := ParseWeekStart(value); err != nil || got != want {
+			t.Errorf("ParseWeekStart(%q) = %s, %v, want %s", value, got, err, want)
+		}
+	}
+	if _, err := ParseWeekStart("friday"); err == nil {
+		t.Error("ParseWeekStart accepted friday")
+	}
+}
+
+func TestWeekStartForLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   time.Weekday
+		ok     bool
+	}{
+		{"ru-RU", time.Monday, true},
+		{"en-US", time.Sunday, true},
+		{"en_GB", time.Monday, true},
+		{"en", time.Sunday, true},
+		{"de", time.Monday, true},
+		{"ar-EG", time.Saturday, true},
+		{"", time.Sunday, false},
+	}
+	for _, test := range tests {
+		if got, ok := WeekStartForLocale(test.locale); got != test.want || ok != test.ok {
+			t.Errorf("WeekStartForLocale(%q) = %s, %t, want %s, %t", test.locale, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package preference
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Preferences are the settings of one user. Users are identified by the
+// same name that owns their calendars.
+type Preferences struct {
+	User      string `json:"user"`
+	WeekStart string `json:"week_start,omitempty"`
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	weekStarts map[string]time.Weekday
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		weekStarts: make(map[string]time.Weekday),
+	}
+}
+
+func (rg *Registry) SetWeekStart(user string, weekStart time.Weekday) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.weekStarts[user] = weekStart
+}
+
+// WeekStart returns the first day of the week the user chose, if any.
+func (rg *Registry) WeekStart(user string) (time.Weekday, bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	weekStart, ok := rg.weekStarts[user]
+	return weekStart, ok
+}
+
+// List returns the preferences of every user who set any, by user.
+func (rg *Registry) List() []Preferences {
+	rg.mu.Lock()
+	users := make([]string, 0, len(rg.weekStarts))
+	for user := range rg.weekStarts {
+		users = append(users, user)
+	}
+	rg.mu.Unlock()
+
+	sort.Strings(users)
+	prefs := make([]Preferences, 0, len(users))
+	for _, user := range users {
+		prefs = append(prefs, rg.Get(user))
+	}
+	return prefs
+}
+
+// Restore replaces the preferences of all users. Nothing changes when one
+// of them names an unknown day.
+func (rg *Registry) Restore(prefs []Preferences) error {
+	weekStarts := make(map[string]time.Weekday, len(prefs))
+	for _, p := range prefs {
+		if p.WeekStart == "" {
+			continue
+		}
+		weekStart, ok := parseWeekday(p.WeekStart)
+		if !ok {
+			return fmt.Errorf("invalid week start %q of user %q", p.WeekStart, p.User)
+		}
+		weekStarts[p.User] = weekStart
+	}
+
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.weekStarts = weekStarts
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func (rg *Registry) Get(user string) Preferences {
+	prefs := Preferences{User: user}
+	if weekStart, ok := rg.WeekStart(user); ok {
+		prefs.WeekStart = strings.ToLower(weekStart.String())
+	}
+	return prefs
+}
@@ -0,0 +1,648 @@
+package quickadd
+
+import (
+	"calendar/internal/service"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultDuration is the length of a timed event whose phrase gives a
+// start time but neither a duration nor an end time.
+const DefaultDuration = time.Hour
+
+var ErrNoTitle = errors.New("phrase has no title")
+
+type Frequency string
+
+const (
+	Daily    Frequency = "daily"
+	Weekly   Frequency = "weekly"
+	Weekdays Frequency = "weekdays"
+)
+
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	Weekday   string    `json:"weekday,omitempty"`
+}
+
+// Result is the parsed phrase. Start and End are in the reference zone;
+// Event holds the same event in the form the storage keeps it: timed
+// events in UTC, all-day events at midnight UTC of their date.
+type Result struct {
+	Title      string        `json:"title"`
+	Start      time.Time     `json:"start"`
+	End        *time.Time    `json:"end,omitempty"`
+	AllDay     bool          `json:"all_day"`
+	Recurrence *Recurrence   `json:"recurrence,omitempty"`
+	Event      service.Event `json:"event"`
+}
+
+type state struct {
+	ref      time.Time
+	date     *time.Time
+	weekday  *time.Weekday
+	nextWeek bool
+	clock    *time.Duration
+	endClock *time.Duration
+	duration time.Duration
+	repeat   *Recurrence
+}
+
+type rule func(st *state, tokens []string, i int) (int, error)
+
+var rules = []rule{
+	matchRecurrence,
+	matchRange,
+	matchDuration,
+	matchRelativeDay,
+	matchExplicitDate,
+	matchWeekday,
+	matchTime,
+}
+
+// Parse turns a phrase such as "Retro every Friday 15:00 for 1h" or
+// "Обед с Аней завтра в полдень" into an event. Relative words are
+// resolved against ref, whose location is the zone of the phrase.
+func Parse(text string, ref time.Time) (Result, error) {
+	original := strings.Fields(text)
+	tokens := make([]string, len(original))
+	for i, word := range original {
+		tokens[i] = normalize(word)
+	}
+
+	st := &state{ref: ref}
+	var title []string
+	for i := 0; i < len(tokens); {
+		consumed := 0
+		for _, match := range rules {
+			n, err := match(st, tokens, i)
+			if err != nil {
+				return Result{}, err
+			}
+			if n > 0 {
+				consumed = n
+				break
+			}
+		}
+		if consumed == 0 {
+			title = append(title, original[i])
+			consumed = 1
+		}
+		i += consumed
+	}
+
+	name := strings.Trim(strings.Join(title, " "), " ,;:-–")
+	if name == "" {
+		return Result{}, ErrNoTitle
+	}
+	return st.result(name)
+}
+
+func normalize(word string) string {
+	word = strings.ToLower(word)
+	word = strings.ReplaceAll(word, "ё", "е")
+	return strings.TrimRight(word, ",;!?")
+}
+
+func (st *state) setDate(date time.Time) error {
+	if st.date != nil || st.weekday != nil {
+		return errors.New("phrase has more than one date")
+	}
+	st.date = &date
+	return nil
+}
+
+func (st *state) setWeekday(day time.Weekday, next bool) error {
+	if st.date != nil || st.weekday != nil {
+		return errors.New("phrase has more than one date")
+	}
+	st.weekday = &day
+	st.nextWeek = next
+	return nil
+}
+
+func (st *state) setClock(clock time.Duration) error {
+	if st.clock != nil {
+		return errors.New("phrase has more than one time")
+	}
+	st.clock = &clock
+	return nil
+}
+
+func (st *state) today() time.Time {
+	return time.Date(st.ref.Year(), st.ref.Month(), st.ref.Day(), 0, 0, 0, 0, st.ref.Location())
+}
+
+func (st *state) at(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, st.ref.Location())
+}
+
+// upcoming reports whether day, at the phrase's time if any, has not
+// passed yet.
+func (st *state) upcoming(day time.Time) bool {
+	if st.clock == nil {
+		return !day.Before(st.today())
+	}
+	return st.at(day, *st.clock).After(st.ref)
+}
+
+func (st *state) resolveDay() time.Time {
+	today := st.today()
+	switch {
+	case st.date != nil:
+		return *st.date
+	case st.weekday != nil:
+		ahead := (int(*st.weekday) - int(today.Weekday()) + 7) % 7
+		day := today.AddDate(0, 0, ahead)
+		if st.nextWeek && ahead == 0 || !st.nextWeek && !st.upcoming(day) {
+			day = day.AddDate(0, 0, 7)
+		}
+		return day
+	case st.repeat != nil && st.repeat.Frequency == Weekdays:
+		day := today
+		for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || !st.upcoming(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day
+	case st.clock != nil && !st.upcoming(today):
+		return today.AddDate(0, 0, 1)
+	}
+	return today
+}
+
+func (st *state) result(title string) (Result, error) {
+	day := st.resolveDay()
+	r := Result{Title: title, Recurrence: st.repeat}
+	if r.Recurrence != nil && r.Recurrence.Frequency == Weekly {
+		r.Recurrence.Weekday = strings.ToLower(day.Weekday().String())
+	}
+
+	if st.clock == nil {
+		if st.duration > 0 {
+			return Result{}, errors.New("phrase has a duration but no start time")
+		}
+		r.AllDay = true
+		r.Start = day
+		r.Event = service.Event{Title: title, Date: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)}
+		return r, nil
+	}
+
+	start := st.at(day, *st.clock)
+	var end time.Time
+	switch {
+	case st.endClock != nil:
+		end = st.at(day, *st.endClock)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+	case st.duration > 0:
+		end = start.Add(st.duration)
+	default:
+		end = start.Add(DefaultDuration)
+	}
+
+	r.Start = start
+	r.End = &end
+	utcEnd := end.UTC()
+	r.Event = service.Event{Title: title, Date: start.UTC(), End: &utcEnd}
+	return r, nil
+}
+
+func token(tokens []string, i int) string {
+	if i < len(tokens) {
+		return tokens[i]
+	}
+	return ""
+}
+
+func matchRecurrence(st *state, tokens []string, i int) (int, error) {
+	repeat := func(r Recurrence, n int) (int, error) {
+		if st.repeat != nil {
+			return 0, errors.New("phrase has more than one recurrence")
+		}
+		st.repeat = &r
+		return n, nil
+	}
+	weekly := func(day time.Weekday, n int) (int, error) {
+		if err := st.setWeekday(day, false); err != nil {
+			return 0, err
+		}
+		return repeat(Recurrence{Frequency: Weekly}, n)
+	}
+
+	switch word := tokens[i]; {
+	case word == "daily" || word == "ежедневно":
+		return repeat(Recurrence{Frequency: Daily}, 1)
+	case word == "weekly" || word == "еженедельно":
+		return repeat(Recurrence{Frequency: Weekly}, 1)
+	case word == "weekdays":
+		return repeat(Recurrence{Frequency: Weekdays}, 1)
+	case word == "по":
+		if day, ok := pluralWeekdays[token(tokens, i+1)]; ok {
+			return weekly(day, 2)
+		}
+		if token(tokens, i+1) == "будням" {
+			return repeat(Recurrence{Frequency: Weekdays}, 2)
+		}
+	case word == "on":
+		if day, ok := pluralWeekdays[token(tokens, i+1)]; ok {
+			return weekly(day, 2)
+		}
+		if token(tokens, i+1) == "weekdays" {
+			return repeat(Recurrence{Frequency: Weekdays}, 2)
+		}
+	case everyWords[word]:
+		next := token(tokens, i+1)
+		switch {
+		case next == "day" || next == "день":
+			return repeat(Recurrence{Frequency: Daily}, 2)
+		case next == "week" || next == "неделю":
+			return repeat(Recurrence{Frequency: Weekly}, 2)
+		case next == "weekday" || next == "будний":
+			n := 2
+			if token(tokens, i+2) == "день" {
+				n = 3
+			}
+			return repeat(Recurrence{Frequency: Weekdays}, n)
+		}
+		if day, ok := weekdays[next]; ok {
+			return weekly(day, 2)
+		}
+	}
+	return 0, nil
+}
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})[:.](\d{2})$`)
+	meridiemPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m\.|p\.m\.)$`)
+	rangePattern    = regexp.MustCompile(`^(\d{1,2}(?::\d{2})?(?:am|pm)?)[-–](\d{1,2}(?::\d{2})?(?:am|pm)?)$`)
+	hourPattern     = regexp.MustCompile(`^\d{1,2}$`)
+)
+
+// parseClock reads a time of day starting at tokens[i]. bare allows a
+// lone hour such as "в 15", which is only a time after "at" or "в".
+func parseClock(tokens []string, i int, bare bool) (time.Duration, int) {
+	word := token(tokens, i)
+	switch word {
+	case "noon", "midday", "полдень":
+		return 12 * time.Hour, 1
+	case "midnight", "полночь":
+		return 0, 1
+	}
+
+	clock := func(hour, minute int) time.Duration {
+		return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute
+	}
+
+	if m := clockPattern.FindStringSubmatch(word); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour > 24 || minute > 59 || hour == 24 && minute > 0 || strings.Contains(word, ".") && !bare {
+			return 0, 0
+		}
+		return clock(hour%24, minute), 1
+	}
+
+	if m := meridiemPattern.FindStringSubmatch(word); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 1 || hour > 12 || minute > 59 {
+			return 0, 0
+		}
+		return clock(meridiem(hour, strings.HasPrefix(m[3], "p")), minute), 1
+	}
+
+	if !hourPattern.MatchString(word) {
+		return 0, 0
+	}
+	hour, _ := strconv.Atoi(word)
+	n := 1
+	switch next := token(tokens, i+1); next {
+	case "am", "a.m.", "pm", "p.m.":
+		if hour < 1 || hour > 12 {
+			return 0, 0
+		}
+		return clock(meridiem(hour, strings.HasPrefix(next, "p")), 0), 2
+	case "час", "часа", "часов":
+		n = 2
+	case "o'clock":
+		return clock(hour%24, 0), 2
+	}
+	if hour > 23 {
+		return 0, 0
+	}
+
+	switch token(tokens, i+n) {
+	case "утра":
+		return clock(hour%12, 0), n + 1
+	case "дня", "вечера":
+		if hour < 12 {
+			hour += 12
+		}
+		return clock(hour, 0), n + 1
+	case "ночи":
+		if hour == 12 {
+			hour = 0
+		}
+		return clock(hour, 0), n + 1
+	}
+	if n == 1 && !bare {
+		return 0, 0
+	}
+	return clock(hour, 0), n
+}
+
+func meridiem(hour int, pm bool) int {
+	hour %= 12
+	if pm {
+		hour += 12
+	}
+	return hour
+}
+
+func matchTime(st *state, tokens []string, i int) (int, error) {
+	prefixed := atWords[tokens[i]]
+	start := i
+	if prefixed {
+		start++
+	}
+	clock, n := parseClock(tokens, start, prefixed)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := st.setClock(clock); err != nil {
+		return 0, err
+	}
+	return start - i + n, nil
+}
+
+func matchRange(st *state, tokens []string, i int) (int, error) {
+	setRange := func(from, to time.Duration, n int) (int, error) {
+		if err := st.setClock(from); err != nil {
+			return 0, err
+		}
+		st.endClock = &to
+		return n, nil
+	}
+
+	if m := rangePattern.FindStringSubmatch(tokens[i]); m != nil {
+		from, n1 := parseClock([]string{m[1]}, 0, true)
+		to, n2 := parseClock([]string{m[2]}, 0, true)
+		if n1 > 0 && n2 > 0 && (strings.Contains(m[1], ":") || strings.Contains(m[2], ":") || strings.HasSuffix(m[2], "m")) {
+			return setRange(from, to, 1)
+		}
+		return 0, nil
+	}
+
+	start := i
+	if fromWords[tokens[i]] {
+		start++
+	}
+	from, n1 := parseClock(tokens, start, start > i)
+	if n1 == 0 || !toWords[token(tokens, start+n1)] {
+		return 0, nil
+	}
+	to, n2 := parseClock(tokens, start+n1+1, true)
+	if n2 == 0 {
+		return 0, nil
+	}
+	return setRange(from, to, start-i+n1+1+n2)
+}
+
+var (
+	compactDuration = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)(h|hr|hrs|ч|час|m|min|mins|м|мин)$`)
+	hourMinute      = regexp.MustCompile(`^(\d+)(?:h|ч)(\d+)(?:m|min|м|мин)?$`)
+)
+
+func parseNumber(word string) (float64, bool) {
+	if n, ok := numbers[word]; ok {
+		return n, true
+	}
+	n, err := strconv.ParseFloat(strings.Replace(word, ",", ".", 1), 64)
+	return n, err == nil && n > 0
+}
+
+// parseDuration reads "1h", "1h30m", "90 min", "an hour", "half an hour",
+// "полчаса", "полтора часа" or "1 час 30 минут" starting at tokens[i].
+func parseDuration(tokens []string, i int) (time.Duration, int) {
+	var total time.Duration
+	n := 0
+	for {
+		d, used := parseDurationPart(tokens, i+n)
+		if used == 0 {
+			return total, n
+		}
+		total += d
+		n += used
+	}
+}
+
+func parseDurationPart(tokens []string, i int) (time.Duration, int) {
+	word := token(tokens, i)
+	switch {
+	case word == "полчаса":
+		return 30 * time.Minute, 1
+	case word == "half" && numbers[token(tokens, i+1)] == 1 && hourUnits[token(tokens, i+2)]:
+		return 30 * time.Minute, 3
+	case word == "час":
+		return time.Hour, 1
+	}
+
+	if m := hourMinute.FindStringSubmatch(word); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, 1
+	}
+	if m := compactDuration.FindStringSubmatch(word); m != nil {
+		value, _ := parseNumber(m[1])
+		return unitDuration(value, m[2]), 1
+	}
+	if value, ok := parseNumber(word); ok {
+		unit := token(tokens, i+1)
+		if hourUnits[unit] || minuteUnits[unit] {
+			return unitDuration(value, unit), 2
+		}
+	}
+	return 0, 0
+}
+
+func unitDuration(value float64, unit string) time.Duration {
+	if hourUnits[unit] {
+		return time.Duration(value * float64(time.Hour))
+	}
+	return time.Duration(value * float64(time.Minute))
+}
+
+func matchDuration(st *state, tokens []string, i int) (int, error) {
+	if !forWords[tokens[i]] {
+		return 0, nil
+	}
+	d, n := parseDuration(tokens, i+1)
+	if n == 0 {
+		return 0, nil
+	}
+	if st.duration > 0 {
+		return 0, errors.New("phrase has more than one duration")
+	}
+	st.duration = d
+	return n + 1, nil
+}
+
+func matchRelativeDay(st *state, tokens []string, i int) (int, error) {
+	today := st.today()
+	switch word := tokens[i]; {
+	case word == "today" || word == "сегодня" || word == "tonight":
+		return 1, st.setDate(today)
+	case word == "tomorrow" || word == "завтра":
+		return 1, st.setDate(today.AddDate(0, 0, 1))
+	case word == "послезавтра":
+		return 1, st.setDate(today.AddDate(0, 0, 2))
+	case word == "day" && token(tokens, i+1) == "after" && token(tokens, i+2) == "tomorrow":
+		return 3, st.setDate(today.AddDate(0, 0, 2))
+	case word == "the" && token(tokens, i+1) == "day" && token(tokens, i+2) == "after" && token(tokens, i+3) == "tomorrow":
+		return 4, st.setDate(today.AddDate(0, 0, 2))
+	case inWords[word]:
+		count, n := 1.0, 1
+		if value, ok := parseNumber(token(tokens, i+1)); ok {
+			count, n = value, 2
+		}
+		if count != float64(int(count)) {
+			return 0, nil
+		}
+		unit := token(tokens, i+n)
+		switch {
+		case dayUnits[unit] && (n == 2 || unit == "день"):
+			return n + 1, st.setDate(today.AddDate(0, 0, int(count)))
+		case weekUnits[unit] && (n == 2 || unit == "неделю"):
+			return n + 1, st.setDate(today.AddDate(0, 0, 7*int(count)))
+		}
+	}
+	return 0, nil
+}
+
+var (
+	isoDate    = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	dottedDate = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{2}|\d{4}))?$`)
+	dayOfMonth = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearToken  = regexp.MustCompile(`^\d{4}$`)
+)
+
+func matchExplicitDate(st *state, tokens []string, i int) (int, error) {
+	start := i
+	if onWords[tokens[i]] {
+		start++
+	}
+	word := token(tokens, start)
+
+	if m := isoDate.FindStringSubmatch(word); m != nil {
+		date, err := time.ParseInLocation("2006-01-02", word, st.ref.Location())
+		if err != nil {
+			return 0, fmt.Errorf("invalid date %q", word)
+		}
+		return start - i + 1, st.setDate(date)
+	}
+
+	if m := dottedDate.FindStringSubmatch(word); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month < 1 || month > 12 {
+			// "15.30" is a time of day, not a date.
+			return 0, nil
+		}
+		year := 0
+		if m[3] != "" {
+			year, _ = strconv.Atoi(m[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+		date, err := st.calendarDate(year, time.Month(month), day)
+		if err != nil {
+			return 0, err
+		}
+		return start - i + 1, st.setDate(date)
+	}
+
+	if m := dayOfMonth.FindStringSubmatch(word); m != nil {
+		if month, ok := months[strings.TrimSuffix(token(tokens, start+1), ".")]; ok {
+			day, _ := strconv.Atoi(m[1])
+			n := 2
+			year := 0
+			if y := token(tokens, start+2); yearToken.MatchString(y) {
+				year, _ = strconv.Atoi(y)
+				n = 3
+			}
+			date, err := st.calendarDate(year, month, day)
+			if err != nil {
+				return 0, err
+			}
+			return start - i + n, st.setDate(date)
+		}
+	}
+
+	if month, ok := months[strings.TrimSuffix(word, ".")]; ok {
+		if m := dayOfMonth.FindStringSubmatch(token(tokens, start+1)); m != nil {
+			day, _ := strconv.Atoi(m[1])
+			n := 2
+			year := 0
+			if y := token(tokens, start+2); yearToken.MatchString(y) {
+				year, _ = strconv.Atoi(y)
+				n = 3
+			}
+			date, err := st.calendarDate(year, month, day)
+			if err != nil {
+				return 0, err
+			}
+			return start - i + n, st.setDate(date)
+		}
+	}
+	return 0, nil
+}
+
+// calendarDate builds a date, picking the next occurrence of day and month
+// when the year is omitted.
+func (st *state) calendarDate(year int, month time.Month, day int) (time.Time, error) {
+	explicit := year != 0
+	if !explicit {
+		year = st.ref.Year()
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, st.ref.Location())
+	if date.Month() != month || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %02d.%02d", day, month)
+	}
+	if !explicit && date.Before(st.today()) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
+
+func matchWeekday(st *state, tokens []string, i int) (int, error) {
+	j := i
+	if onWords[token(tokens, j)] {
+		j++
+	}
+	next := false
+	switch {
+	case thisWords[token(tokens, j)]:
+		j++
+	case nextWords[token(tokens, j)]:
+		next = true
+		j++
+	}
+
+	word := strings.TrimSuffix(token(tokens, j), ".")
+	day, ok := weekdays[word]
+	if !ok {
+		return 0, nil
+	}
+	if j == i && utf8.RuneCountInString(word) <= 4 {
+		// Abbreviations such as "sun" or "ср" only count after a preposition.
+		return 0, nil
+	}
+	return j - i + 1, st.setWeekday(day, next)
+}
@@ -0,0 +1,139 @@
+package quickadd
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	moscow = time.FixedZone("MSK", 3*60*60)
+	// ref is Wednesday, 16 October 2024, 10:00 in Moscow.
+	ref = time.Date(2024, 10, 16, 10, 0, 0, 0, moscow)
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text      string
+		title     string
+		start     string
+		end       string
+		frequency Frequency
+		weekday   string
+	}{
+		// English.
+		{"Retro every Friday 15:00 for 1h", "Retro", "2024-10-18 15:00", "2024-10-18 16:00", Weekly, "friday"},
+		{"Lunch with Ana tomorrow at noon", "Lunch with Ana", "2024-10-17 12:00", "2024-10-17 13:00", "", ""},
+		{"Dentist on Monday at 3pm for 30 min", "Dentist", "2024-10-21 15:00", "2024-10-21 15:30", "", ""},
+		{"Standup every weekday at 9:30 for 15 minutes", "Standup", "2024-10-17 09:30", "2024-10-17 09:45", Weekdays, ""},
+		{"Call mom at 9", "Call mom", "2024-10-17 09:00", "2024-10-17 10:00", "", ""},
+		{"Conference 14 November", "Conference", "2024-11-14", "", "", ""},
+		{"Birthday party Oct 5", "Birthday party", "2025-10-05", "", "", ""},
+		{"Release 2024-12-01 from 10:00 to 11:30", "Release", "2024-12-01 10:00", "2024-12-01 11:30", "", ""},
+		{"Review next Wednesday 10:00-11:00", "Review", "2024-10-23 10:00", "2024-10-23 11:00", "", ""},
+		{"Review Wednesday at 11", "Review", "2024-10-16 11:00", "2024-10-16 12:00", "", ""},
+		{"Review Wednesday at 9", "Review", "2024-10-23 09:00", "2024-10-23 10:00", "", ""},
+		{"Sync in 2 days at 4:30pm for an hour", "Sync", "2024-10-18 16:30", "2024-10-18 17:30", "", ""},
+		{"Planning in a week", "Planning", "2024-10-23", "", "", ""},
+		{"Workshop the day after tomorrow from 1pm to 3pm", "Workshop", "2024-10-18 13:00", "2024-10-18 15:00", "", ""},
+		{"Deploy today at midnight", "Deploy", "2024-10-16 00:00", "2024-10-16 01:00", "", ""},
+		{"Yoga daily at 7am for 1.5h", "Yoga", "2024-10-17 07:00", "2024-10-17 08:30", Daily, ""},
+		{"Interview Friday 2 pm for 45 mins", "Interview", "2024-10-18 14:00", "2024-10-18 14:45", "", ""},
+		{"Meeting 11:00-12:30 at office", "Meeting at office", "2024-10-16 11:00", "2024-10-16 12:30", "", ""},
+		{"Team lunch 12:30 for 1h30m", "Team lunch", "2024-10-16 12:30", "2024-10-16 14:00", "", ""},
+		{"Long call for 1 hour 30 minutes at 14:00", "Long call", "2024-10-16 14:00", "2024-10-16 15:30", "", ""},
+		{"Brunch Sunday at 12pm", "Brunch", "2024-10-20 12:00", "2024-10-20 13:00", "", ""},
+		{"Chess club on Thursdays at 6pm", "Chess club", "2024-10-17 18:00", "2024-10-17 19:00", Weekly, "thursday"},
+		{"Sun salutation", "Sun salutation", "2024-10-16", "", "", ""},
+		{"Night shift today 22:00-06:00", "Night shift", "2024-10-16 22:00", "2024-10-17 06:00", "", ""},
+
+		// Russian.
+		{"Обед с Аней завтра в полдень", "Обед с Аней", "2024-10-17 12:00", "2024-10-17 13:00", "", ""},
+		{"Ретро каждую пятницу в 15:00 на 1 час", "Ретро", "2024-10-18 15:00", "2024-10-18 16:00", Weekly, "friday"},
+		{"Стендап по будням в 9:30 на 15 минут", "Стендап", "2024-10-17 09:30", "2024-10-17 09:45", Weekdays, ""},
+		{"Йога по средам в 19:00 на полтора часа", "Йога", "2024-10-16 19:00", "2024-10-16 20:30", Weekly, "wednesday"},
+		{"Врач в понедельник в 15:30", "Врач", "2024-10-21 15:30", "2024-10-21 16:30", "", ""},
+		{"Встреча послезавтра с 10 до 12", "Встреча", "2024-10-18 10:00", "2024-10-18 12:00", "", ""},
+		{"Созвон через 3 дня в 10 утра", "Созвон", "2024-10-19 10:00", "2024-10-19 11:00", "", ""},
+		{"Планёрка через неделю", "Планёрка", "2024-10-23", "", "", ""},
+		{"Отпуск 25 декабря", "Отпуск", "2024-12-25", "", "", ""},
+		{"Дедлайн 01.03.2025 в 18:00", "Дедлайн", "2025-03-01 18:00", "2025-03-01 19:00", "", ""},
+		{"Ужин в следующую субботу в 8 вечера на 2 часа", "Ужин", "2024-10-19 20:00", "2024-10-19 22:00", "", ""},
+		{"Звонок в 9 часов утра", "Звонок", "2024-10-17 09:00", "2024-10-17 10:00", "", ""},
+		{"Тренировка ежедневно в 7:00 на полчаса", "Тренировка", "2024-10-17 07:00", "2024-10-17 07:30", Daily, ""},
+		{"Созвон сегодня в 23:30 на час", "Созвон", "2024-10-16 23:30", "2024-10-17 00:30", "", ""},
+		{"Митап в ср в 18.30", "Митап", "2024-10-16 18:30", "2024-10-16 19:30", "", ""},
+		{"Бег каждый день в 6 утра", "Бег", "2024-10-17 06:00", "2024-10-17 07:00", Daily, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := Parse(tt.text, ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Title != tt.title || got.Event.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+
+			allDay := tt.end == ""
+			if got.AllDay != allDay {
+				t.Fatalf("all_day = %t, want %t", got.AllDay, allDay)
+			}
+			if allDay {
+				date, _ := time.ParseInLocation("2006-01-02", tt.start, moscow)
+				if !got.Start.Equal(date) || got.End != nil {
+					t.Errorf("start = %v, end = %v, want all-day %s", got.Start, got.End, tt.start)
+				}
+				if want := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC); !got.Event.Date.Equal(want) || got.Event.End != nil {
+					t.Errorf("event date = %v, want %v", got.Event.Date, want)
+				}
+			} else {
+				start, _ := time.ParseInLocation("2006-01-02 15:04", tt.start, moscow)
+				end, _ := time.ParseInLocation("2006-01-02 15:04", tt.end, moscow)
+				if !got.Start.Equal(start) || got.End == nil || !got.End.Equal(end) {
+					t.Errorf("got %v – %v, want %v – %v", got.Start, got.End, start, end)
+				}
+				if got.Event.Date.Location() != time.UTC || !got.Event.Date.Equal(start) || got.Event.End == nil || !got.Event.End.Equal(end) {
+					t.Errorf("event = %v – %v, want %v – %v in UTC", got.Event.Date, got.Event.End, start.UTC(), end.UTC())
+				}
+			}
+
+			switch {
+			case tt.frequency == "" && got.Recurrence != nil:
+				t.Errorf("unexpected recurrence %+v", got.Recurrence)
+			case tt.frequency != "" && (got.Recurrence == nil || got.Recurrence.Frequency != tt.frequency || got.Recurrence.Weekday != tt.weekday):
+				t.Errorf("recurrence = %+v, want %s %s", got.Recurrence, tt.frequency, tt.weekday)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"tomorrow at 10",
+		"завтра в 10",
+		"Meeting tomorrow on Friday",
+		"Meeting at 10 at 11",
+		"Meeting tomorrow for 1h",
+		"Meeting 31.02",
+		"Встреча 30 февраля",
+		"Retro every Friday daily",
+	}
+	for _, text := range tests {
+		if got, err := Parse(text, ref); err == nil {
+			t.Errorf("Parse(%q) = %+v, want error", text, got)
+		}
+	}
+}
+
+func TestParseUsesReferenceZone(t *testing.T) {
+	// 23:00 UTC on the 16th is already the 17th in Moscow.
+	got, err := Parse("Breakfast tomorrow at 8:00", time.Date(2024, 10, 16, 23, 0, 0, 0, time.UTC).In(moscow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 10, 18, 5, 0, 0, 0, time.UTC); !got.Event.Date.Equal(want) {
+		t.Errorf("event date = %v, want %v", got.Event.Date, want)
+	}
+}
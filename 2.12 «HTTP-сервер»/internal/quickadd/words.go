@@ -0,0 +1,95 @@
+package quickadd
+
+import "time"
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+
+	"понедельник": time.Monday, "пн": time.Monday,
+	"вторник": time.Tuesday, "вт": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday,
+	"четверг": time.Thursday, "чт": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday,
+	"воскресенье": time.Sunday, "вс": time.Sunday,
+}
+
+// pluralWeekdays are the dative plurals of "по пятницам" (on Fridays).
+var pluralWeekdays = map[string]time.Weekday{
+	"понедельникам": time.Monday, "вторникам": time.Tuesday, "средам": time.Wednesday,
+	"четвергам": time.Thursday, "пятницам": time.Friday, "субботам": time.Saturday,
+	"воскресеньям": time.Sunday,
+	"mondays":      time.Monday, "tuesdays": time.Tuesday, "wednesdays": time.Wednesday,
+	"thursdays": time.Thursday, "fridays": time.Friday, "saturdays": time.Saturday,
+	"sundays": time.Sunday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+
+	"января": time.January, "янв": time.January,
+	"февраля": time.February, "фев": time.February,
+	"марта": time.March, "мар": time.March,
+	"апреля": time.April, "апр": time.April,
+	"мая":  time.May,
+	"июня": time.June, "июн": time.June,
+	"июля": time.July, "июл": time.July,
+	"августа": time.August, "авг": time.August,
+	"сентября": time.September, "сен": time.September, "сент": time.September,
+	"октября": time.October, "окт": time.October,
+	"ноября": time.November, "ноя": time.November,
+	"декабря": time.December, "дек": time.December,
+}
+
+var numbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"один": 1, "одна": 1, "одну": 1, "два": 2, "две": 2, "три": 3, "четыре": 4, "пять": 5,
+	"полтора": 1.5, "полторы": 1.5,
+}
+
+var hourUnits = map[string]bool{
+	"h": true, "hr": true, "hrs": true, "hour": true, "hours": true,
+	"ч": true, "час": true, "часа": true, "часов": true,
+}
+
+var minuteUnits = map[string]bool{
+	"m": true, "min": true, "mins": true, "minute": true, "minutes": true,
+	"м": true, "мин": true, "минута": true, "минуту": true, "минуты": true, "минут": true,
+}
+
+var dayUnits = map[string]bool{
+	"day": true, "days": true, "день": true, "дня": true, "дней": true,
+}
+
+var weekUnits = map[string]bool{
+	"week": true, "weeks": true, "неделю": true, "недели": true, "недель": true,
+}
+
+var (
+	atWords    = map[string]bool{"at": true, "@": true, "в": true, "во": true}
+	onWords    = map[string]bool{"on": true, "в": true, "во": true}
+	thisWords  = map[string]bool{"this": true, "этот": true, "эту": true, "это": true}
+	nextWords  = map[string]bool{"next": true, "следующий": true, "следующую": true, "следующее": true}
+	forWords   = map[string]bool{"for": true, "на": true}
+	inWords    = map[string]bool{"in": true, "через": true}
+	fromWords  = map[string]bool{"from": true, "с": true, "со": true}
+	toWords    = map[string]bool{"to": true, "till": true, "until": true, "-": true, "–": true, "до": true, "по": true}
+	everyWords = map[string]bool{"every": true, "each": true, "каждый": true, "каждую": true, "каждое": true, "каждые": true}
+)
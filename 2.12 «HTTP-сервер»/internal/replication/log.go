@@ -0,0 +1,133 @@
+package replication
+
+import (
+	"calendar/internal/service"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLogSize = 10000
+	maxBatch       = 500
+)
+
+// ErrTruncated means the change log no longer holds every change after the
+// requested position, either because they were dropped to respect the log
+// size or because the log was started afresh. The replica has to resync
+// from a checkpoint.
+var ErrTruncated = errors.New("change log no longer covers the requested position")
+
+type Op string
+
+const (
+	PutEvent       Op = "event.put"
+	DeleteEvent    Op = "event.delete"
+	ArchiveEvent   Op = "event.archive"
+	PutCalendar    Op = "calendar.put"
+	DeleteCalendar Op = "calendar.delete"
+)
+
+type Entry struct {
+	Seq      uint64            `json:"seq"`
+	Op       Op                `json:"op"`
+	Event    *service.Event    `json:"event,omitempty"`
+	Calendar *service.Calendar `json:"calendar,omitempty"`
+	ID       string            `json:"id,omitempty"`
+}
+
+type Batch struct {
+	Log     string  `json:"log"`
+	Seq     uint64  `json:"seq"`
+	Changes []Entry `json:"changes"`
+}
+
+// Log is a bounded, in-memory list of storage mutations numbered by a
+// sequence that only grows. Its ID changes whenever the sequence stops
+// describing the storage, so a replica can tell a restarted or restored
+// primary from one it is in sync with.
+type Log struct {
+	mu      sync.Mutex
+	id      string
+	seq     uint64
+	entries []Entry
+	size    int
+	changed chan struct{}
+}
+
+func NewLog(size int) *Log {
+	if size <= 0 {
+		size = DefaultLogSize
+	}
+	return &Log{id: uuid.New().String(), size: size, changed: make(chan struct{})}
+}
+
+func (l *Log) append(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	entry.Seq = l.seq
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.size {
+		l.entries = append([]Entry(nil), l.entries[len(l.entries)-l.size:]...)
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *Log) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.id = uuid.New().String()
+	l.entries = nil
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *Log) Position() (string, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.id, l.seq
+}
+
+// Since returns the changes after seq in the log with the given ID. When
+// there are none it waits up to wait for the next one and returns an empty
+// batch if nothing arrives.
+func (l *Log) Since(ctx context.Context, id string, seq uint64, wait time.Duration) (Batch, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		l.mu.Lock()
+		first := l.seq - uint64(len(l.entries))
+		if id != l.id || seq > l.seq || seq < first {
+			l.mu.Unlock()
+			return Batch{}, ErrTruncated
+		}
+		pending := l.entries[seq-first:]
+		if len(pending) > 0 || wait <= 0 {
+			if len(pending) > maxBatch {
+				pending = pending[:maxBatch]
+			}
+			batch := Batch{Log: l.id, Seq: l.seq, Changes: append([]Entry{}, pending...)}
+			l.mu.Unlock()
+			return batch, nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			wait = 0
+		case <-ctx.Done():
+			return Batch{}, ctx.Err()
+		}
+	}
+}
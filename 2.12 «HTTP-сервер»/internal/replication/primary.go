@@ -0,0 +1,177 @@
+package replication
+
+import (
+	"calendar/internal/service"
+	"calendar/internal/snapshot"
+	"context"
+	"sync"
+	"time"
+)
+
+type Checkpoint struct {
+	Log      string            `json:"log"`
+	Seq      uint64            `json:"seq"`
+	Snapshot snapshot.Snapshot `json:"snapshot"`
+}
+
+// Primary wraps the storage of the instance replicas follow and records
+// every mutation in a change log. Mutations are serialized so the log
+// order is the order in which they were applied.
+type Primary struct {
+	service.Storage
+
+	mu  sync.Mutex
+	log *Log
+}
+
+var _ service.Storage = (*Primary)(nil)
+
+func NewPrimary(storage service.Storage, logSize int) *Primary {
+	p := &Primary{Storage: storage, log: NewLog(logSize)}
+	storage.Subscribe(p.record)
+	return p
+}
+
+func (p *Primary) record(change service.Change) {
+	event := change.Event
+	switch change.Type {
+	case service.EventCreated, service.EventUpdated:
+		p.log.append(Entry{Op: PutEvent, Event: &event})
+	case service.EventDeleted:
+		p.log.append(Entry{Op: DeleteEvent, ID: event.ID})
+	case service.EventArchived:
+		p.log.append(Entry{Op: ArchiveEvent, ID: event.ID})
+	}
+}
+
+func (p *Primary) Log() *Log {
+	return p.log
+}
+
+func (p *Primary) Changes(ctx context.Context, log string, since uint64, wait time.Duration) (Batch, error) {
+	return p.log.Since(ctx, log, since, wait)
+}
+
+// Checkpoint returns a snapshot of the storage together with the log
+// position it corresponds to. Replicas only mirror the storage, so the
+// registries kept beside it are left out.
+func (p *Primary) Checkpoint() Checkpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, seq := p.log.Position()
+	return Checkpoint{Log: id, Seq: seq, Snapshot: snapshot.Take(p.Storage, snapshot.Registries{})}
+}
+
+func (p *Primary) CreateEvent(event service.Event) service.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.CreateEvent(event)
+}
+
+func (p *Primary) CreateEventIfFree(event service.Event, conflicts func(service.Event) bool) (service.Event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.CreateEventIfFree(event, conflicts)
+}
+
+func (p *Primary) UpdateEvent(updatedEvent service.Event) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.UpdateEvent(updatedEvent)
+}
+
+func (p *Primary) DeleteEvent(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.DeleteEvent(id)
+}
+
+func (p *Primary) PutEvent(event service.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Storage.PutEvent(event)
+}
+
+func (p *Primary) PutEventByExternalID(event service.Event, merge func(existing service.Event) service.Event) (service.Event, service.ChangeType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.PutEventByExternalID(event, merge)
+}
+
+func (p *Primary) ArchiveEventsBefore(before time.Time) []service.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.ArchiveEventsBefore(before)
+}
+
+func (p *Primary) ArchiveEvent(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Storage.ArchiveEvent(id)
+}
+
+func (p *Primary) Restore(events []service.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Storage.Restore(events)
+	p.log.reset()
+}
+
+func (p *Primary) CreateCalendar(calendar service.Calendar) service.Calendar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	created := p.Storage.CreateCalendar(calendar)
+	p.log.append(Entry{Op: PutCalendar, Calendar: &created})
+	return created
+}
+
+func (p *Primary) UpdateCalendar(calendar service.Calendar) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.Storage.UpdateCalendar(calendar) {
+		return false
+	}
+	p.log.append(Entry{Op: PutCalendar, Calendar: &calendar})
+	return true
+}
+
+// DeleteCalendar logs the deletion of every event of the calendar before
+// the calendar itself, as the storage notifies them.
+func (p *Primary) DeleteCalendar(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.Storage.DeleteCalendar(id) {
+		return false
+	}
+	p.log.append(Entry{Op: DeleteCalendar, ID: id})
+	return true
+}
+
+func (p *Primary) PutCalendar(calendar service.Calendar) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Storage.PutCalendar(calendar)
+	p.log.append(Entry{Op: PutCalendar, Calendar: &calendar})
+}
+
+func (p *Primary) RestoreCalendars(calendars []service.Calendar) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Storage.RestoreCalendars(calendars)
+	p.log.reset()
+}
@@ -0,0 +1,231 @@
+package replication
+
+import (
+	"calendar/internal/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replica keeps a storage in sync with a primary: it loads a checkpoint
+// once, then long-polls the primary's change log and applies every change
+// in order. After a dropped connection it continues from the last applied
+// sequence number; when the primary's log no longer covers it, it loads a
+// fresh checkpoint.
+type Replica struct {
+	// Wait is how long the primary may hold a request for changes, Retry
+	// the pause after a failed sync, and Timeout how much longer than Wait
+	// a request to the primary may take before it is abandoned.
+	Wait    time.Duration
+	Retry   time.Duration
+	Timeout time.Duration
+
+	primary string
+	secret  string
+	storage service.Storage
+	client  *http.Client
+
+	mu       sync.Mutex
+	log      string
+	seq      uint64
+	lag      uint64
+	syncedAt time.Time
+	lastErr  error
+}
+
+type Status struct {
+	Primary  string     `json:"primary"`
+	Log      string     `json:"log,omitempty"`
+	Seq      uint64     `json:"seq"`
+	Lag      uint64     `json:"lag"`
+	SyncedAt *time.Time `json:"synced_at,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// NewReplica creates a replica of the primary at the base URL primary. The
+// secret is sent as a bearer token with every request to the primary.
+func NewReplica(primary, secret string, storage service.Storage) *Replica {
+	return &Replica{
+		Wait:    30 * time.Second,
+		Retry:   2 * time.Second,
+		Timeout: 30 * time.Second,
+		primary: strings.TrimRight(primary, "/"),
+		secret:  secret,
+		storage: storage,
+		client:  &http.Client{},
+	}
+}
+
+func (r *Replica) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := Status{Primary: r.primary, Log: r.log, Seq: r.seq, Lag: r.lag}
+	if !r.syncedAt.IsZero() {
+		syncedAt := r.syncedAt
+		status.SyncedAt = &syncedAt
+	}
+	if r.lastErr != nil {
+		status.Error = r.lastErr.Error()
+	}
+	return status
+}
+
+// Sync performs one round of replication: a checkpoint if the replica has
+// none or fell behind the log, then one batch of changes.
+func (r *Replica) Sync(ctx context.Context) error {
+	err := r.sync(ctx)
+	r.mu.Lock()
+	r.lastErr = err
+	r.mu.Unlock()
+	return err
+}
+
+func (r *Replica) sync(ctx context.Context) error {
+	r.mu.Lock()
+	logID, seq := r.log, r.seq
+	r.mu.Unlock()
+
+	if logID == "" {
+		if err := r.loadCheckpoint(ctx); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		logID, seq = r.log, r.seq
+		r.mu.Unlock()
+	}
+
+	batch, err := r.fetchChanges(ctx, logID, seq)
+	if err == ErrTruncated {
+		log.Printf("Replication log of %s no longer covers sequence %d, loading a checkpoint", r.primary, seq)
+		r.mu.Lock()
+		r.log = ""
+		r.mu.Unlock()
+		return r.loadCheckpoint(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range batch.Changes {
+		if entry.Seq <= seq {
+			continue
+		}
+		if err := Apply(r.storage, entry); err != nil {
+			return err
+		}
+		seq = entry.Seq
+		r.mu.Lock()
+		r.seq = seq
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	r.lag = batch.Seq - seq
+	r.syncedAt = time.Now().UTC()
+	r.mu.Unlock()
+	return nil
+}
+
+// Run syncs until stop is closed, retrying after errors.
+func (r *Replica) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		if err := r.Sync(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Replication from %s failed: %v", r.primary, err)
+			select {
+			case <-time.After(r.Retry):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+func Apply(storage service.Storage, entry Entry) error {
+	switch {
+	case entry.Op == PutEvent && entry.Event != nil:
+		storage.PutEvent(*entry.Event)
+	case entry.Op == DeleteEvent:
+		storage.DeleteEvent(entry.ID)
+	case entry.Op == ArchiveEvent:
+		storage.ArchiveEvent(entry.ID)
+	case entry.Op == PutCalendar && entry.Calendar != nil:
+		storage.PutCalendar(*entry.Calendar)
+	case entry.Op == DeleteCalendar:
+		storage.DeleteCalendar(entry.ID)
+	default:
+		return fmt.Errorf("invalid change %d: %q", entry.Seq, entry.Op)
+	}
+	return nil
+}
+
+func (r *Replica) loadCheckpoint(ctx context.Context) error {
+	var checkpoint Checkpoint
+	if err := r.get(ctx, "/replication/snapshot", &checkpoint); err != nil {
+		return err
+	}
+	r.storage.RestoreCalendars(checkpoint.Snapshot.Calendars)
+	r.storage.Restore(checkpoint.Snapshot.Events)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = checkpoint.Log
+	r.seq = checkpoint.Seq
+	r.lag = 0
+	r.syncedAt = time.Now().UTC()
+	log.Printf("Loaded checkpoint %s at sequence %d from %s", checkpoint.Log, checkpoint.Seq, r.primary)
+	return nil
+}
+
+func (r *Replica) fetchChanges(ctx context.Context, logID string, seq uint64) (Batch, error) {
+	query := url.Values{
+		"log":   {logID},
+		"since": {strconv.FormatUint(seq, 10)},
+		"wait":  {strconv.Itoa(int(r.Wait / time.Second))},
+	}
+	var batch Batch
+	err := r.get(ctx, "/replication/changes?"+query.Encode(), &batch)
+	return batch, err
+}
+
+func (r *Replica) get(ctx context.Context, path string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, r.Wait+r.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.primary+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.secret)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusGone:
+		return ErrTruncated
+	default:
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("primary responded %s: %s", resp.Status, apiErr.Message)
+	}
+}
@@ -0,0 +1,169 @@
+package replication
+
+import (
+	"calendar/internal/service"
+	"calendar/internal/service/storagetest"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrimaryConformance(t *testing.T) {
+	storagetest.Run(t, func() service.Storage { return NewPrimary(service.NewInMemoryStorage(), 0) })
+}
+
+func ops(entries []Entry) string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, fmt.Sprintf("%d:%s", entry.Seq, entry.Op))
+	}
+	return fmt.Sprint(result)
+}
+
+func TestPrimaryRecordsMutations(t *testing.T) {
+	p := NewPrimary(service.NewInMemoryStorage(), 0)
+	team := p.CreateCalendar(service.Calendar{Name: "team"})
+	p.CreateEvent(service.Event{Title: "sync", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), CalendarID: team.ID})
+	event := p.GetEvent()[0]
+	event.Title = "weekly sync"
+	p.UpdateEvent(event)
+	p.DeleteCalendar(team.ID)
+	p.DeleteCalendar(team.ID)
+
+	logID, seq := p.Log().Position()
+	batch, err := p.Changes(context.Background(), logID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops(batch.Changes); got != "[1:calendar.put 2:event.put 3:event.put 4:event.delete 5:calendar.delete]" || seq != 5 || batch.Seq != 5 {
+		t.Fatalf("unexpected log %s at %d", got, seq)
+	}
+	if batch.Changes[2].Event.Title != "weekly sync" || batch.Changes[3].ID != event.ID || batch.Changes[4].ID != team.ID {
+		t.Errorf("unexpected entries %+v", batch.Changes)
+	}
+
+	checkpoint := p.Checkpoint()
+	if checkpoint.Log != logID || checkpoint.Seq != 5 || len(checkpoint.Snapshot.Events) != 0 {
+		t.Errorf("unexpected checkpoint %+v", checkpoint)
+	}
+
+	p.Restore([]service.Event{{ID: "e1", Title: "restored"}})
+	if _, err := p.Changes(context.Background(), logID, 5, 0); !errors.Is(err, ErrTruncated) {
+		t.Errorf("after restore the old log should be truncated, got %v", err)
+	}
+}
+
+func TestLogSince(t *testing.T) {
+	l := NewLog(3)
+	for i := 0; i < 5; i++ {
+		l.append(Entry{Op: DeleteEvent, ID: fmt.Sprint(i)})
+	}
+	id, _ := l.Position()
+	ctx := context.Background()
+
+	tests := []struct {
+		log     string
+		since   uint64
+		want    string
+		wantErr bool
+	}{
+		{id, 2, "[3:event.delete 4:event.delete 5:event.delete]", false},
+		{id, 4, "[5:event.delete]", false},
+		{id, 5, "[]", false},
+		{id, 1, "", true},
+		{id, 6, "", true},
+		{"other", 5, "", true},
+	}
+	for _, tt := range tests {
+		batch, err := l.Since(ctx, tt.log, tt.since, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Since(%s, %d): error = %v, wantErr %t", tt.log, tt.since, err, tt.wantErr)
+			continue
+		}
+		if got := ops(batch.Changes); !tt.wantErr && got != tt.want {
+			t.Errorf("Since(%s, %d) = %s, want %s", tt.log, tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestLogSinceWaits(t *testing.T) {
+	l := NewLog(0)
+	id, _ := l.Position()
+
+	start := time.Now()
+	batch, err := l.Since(context.Background(), id, 0, 50*time.Millisecond)
+	if err != nil || len(batch.Changes) != 0 || time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("expected an empty batch after the wait, got %+v, %v", batch, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.append(Entry{Op: DeleteCalendar, ID: "c1"})
+	}()
+	batch, err = l.Since(context.Background(), id, 0, 10*time.Second)
+	if err != nil || ops(batch.Changes) != "[1:calendar.delete]" {
+		t.Fatalf("long poll returned %+v, %v", batch, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Since(ctx, id, 1, 10*time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestArchivingReplicatesAsArchive(t *testing.T) {
+	p := NewPrimary(service.NewInMemoryStorage(), 0)
+	p.CreateEvent(service.Event{Title: "old", Date: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)})
+	p.ArchiveEventsBefore(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	logID, _ := p.Log().Position()
+	batch, err := p.Changes(context.Background(), logID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ops(batch.Changes); got != "[1:event.put 2:event.archive]" {
+		t.Fatalf("unexpected log %s", got)
+	}
+
+	replica := service.NewInMemoryStorage()
+	var changes []service.ChangeType
+	replica.Subscribe(func(change service.Change) {
+		changes = append(changes, change.Type)
+	})
+	for _, entry := range batch.Changes {
+		if err := Apply(replica, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(replica.GetEvent()) != 0 || fmt.Sprint(changes) != "[event.created event.archived]" {
+		t.Errorf("replica kept %v and notified %v", replica.GetEvent(), changes)
+	}
+}
+
+func TestReplicaRequestsTimeOut(t *testing.T) {
+	release := make(chan struct{})
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer primary.Close()
+	defer close(release)
+
+	r := NewReplica(primary.URL, "secret", service.NewInMemoryStorage())
+	r.Wait = 0
+	r.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	if err := r.Sync(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Sync took %s", elapsed)
+	}
+}
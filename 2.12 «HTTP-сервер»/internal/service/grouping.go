@@ -0,0 +1,67 @@
+package service
+
+import (
+	"calendar/internal/holiday"
+	"calendar/internal/period"
+	"strings"
+	"time"
+)
+
+// FilterByCalendar keeps the events that belong to one of calendarIDs. An
+// empty filter keeps every event.
+func FilterByCalendar(events []Event, calendarIDs []string) []Event {
+	if len(calendarIDs) == 0 {
+		return events
+	}
+
+	wanted := make(map[string]bool, len(calendarIDs))
+	for _, id := range calendarIDs {
+		wanted[id] = true
+	}
+	filtered := make([]Event, 0, len(events))
+	for _, event := range events {
+		if wanted[event.CalendarID] {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+func GroupByDay(p period.Period, events []Event) PeriodEvents {
+	days := p.Days()
+	grouped := PeriodEvents{
+		Start:     p.Start.Format("2006-01-02"),
+		End:       p.End.AddDate(0, 0, -1).Format("2006-01-02"),
+		WeekStart: strings.ToLower(p.WeekStart.String()),
+		Days:      make([]DayEvents, 0, len(days)),
+	}
+
+	index := make(map[string]int, len(days))
+	for i, day := range days {
+		date := day.Format("2006-01-02")
+		index[date] = i
+		grouped.Days = append(grouped.Days, DayEvents{Date: date, Events: []Event{}})
+	}
+
+	for _, event := range events {
+		if i, ok := index[event.Date.Format("2006-01-02")]; ok {
+			grouped.Days[i].Events = append(grouped.Days[i].Events, event)
+		}
+	}
+	return grouped
+}
+
+func (p *PeriodEvents) ApplyOverlays(calendars []*holiday.Calendar) {
+	for i := range p.Days {
+		date, err := time.Parse("2006-01-02", p.Days[i].Date)
+		if err != nil {
+			continue
+		}
+		for _, c := range calendars {
+			p.Days[i].Holidays = append(p.Days[i].Holidays, c.HolidaysOn(date)...)
+			if !c.IsWorkingDay(date) {
+				p.Days[i].NonWorking = true
+			}
+		}
+	}
+}
@@ -1,9 +1,93 @@
 package service
 
-import "time"
+import (
+	"calendar/internal/holiday"
+	"time"
+)
 
 type Event struct {
-	ID    string    `json:"id,omitempty"`
-	Title string    `json:"title"`
-	Date  time.Time `json:"date"`
+	ID         string     `json:"id,omitempty"`
+	Title      string     `json:"title"`
+	Date       time.Time  `json:"date"`
+	End        *time.Time `json:"end,omitempty"`
+	CalendarID string     `json:"calendar_id,omitempty"`
+	ExternalID string     `json:"external_id,omitempty"`
+}
+
+func (e Event) Timed() bool {
+	return e.End != nil
+}
+
+func (e Event) Overlaps(start, end time.Time) bool {
+	return e.Timed() && e.Date.Before(end) && e.End.After(start)
+}
+
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private"
+	VisibilityShared  Visibility = "shared"
+	VisibilityPublic  Visibility = "public"
+)
+
+type Calendar struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Colour     string     `json:"colour"`
+	Owner      string     `json:"owner,omitempty"`
+	Visibility Visibility `json:"visibility"`
+}
+
+// VisibleTo reports whether user may see the calendar and its events.
+// Public calendars are visible to everyone, shared ones to every named
+// user and private ones only to their owner. A calendar without an owner
+// has no one to hide from and is visible to everyone.
+func (c Calendar) VisibleTo(user string) bool {
+	switch {
+	case c.Owner == "" || c.Owner == user:
+		return true
+	case c.Visibility == VisibilityPublic:
+		return true
+	case c.Visibility == VisibilityShared:
+		return user != ""
+	}
+	return false
+}
+
+// WritableBy reports whether user may change the calendar and its events.
+// Only the owner may, except that a calendar without an owner is open to
+// everyone.
+func (c Calendar) WritableBy(user string) bool {
+	return c.Owner == "" || c.Owner == user
+}
+
+type ChangeType string
+
+const (
+	EventCreated ChangeType = "event.created"
+	EventUpdated ChangeType = "event.updated"
+	EventDeleted ChangeType = "event.deleted"
+	// EventArchived is sent for events moved out of the storage into the
+	// archive. Unlike EventDeleted it does not mean the event is gone.
+	EventArchived ChangeType = "event.archived"
+)
+
+type Change struct {
+	Type  ChangeType `json:"type"`
+	Event Event      `json:"event"`
+}
+
+type DayEvents struct {
+	Date       string            `json:"date"`
+	Events     []Event           `json:"events"`
+	Holidays   []holiday.Holiday `json:"holidays,omitempty"`
+	NonWorking bool              `json:"non_working,omitempty"`
+}
+
+type PeriodEvents struct {
+	Start     string      `json:"start"`
+	End       string      `json:"end"`
+	WeekStart string      `json:"week_start"`
+	ISOWeek   string      `json:"iso_week,omitempty"`
+	Days      []DayEvents `json:"days"`
 }
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"calendar/internal/period"
+	"sort"
 	"sync"
 	"time"
 
@@ -8,97 +10,393 @@ import (
 )
 
 type InMemoryStorage struct {
-	mu     sync.Mutex
-	events map[string]Event
+	mu        sync.Mutex
+	events    map[string]Event
+	calendars map[string]Calendar
+	listeners []func(Change)
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		events: make(map[string]Event),
+		events:    make(map[string]Event),
+		calendars: make(map[string]Calendar),
 	}
 }
 
-func (ms *InMemoryStorage) CreateEvent(event Event) string {
+func (ms *InMemoryStorage) Subscribe(listener func(Change)) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	ms.listeners = append(ms.listeners, listener)
+}
+
+func (ms *InMemoryStorage) notify(change Change) {
+	ms.mu.Lock()
+	listeners := ms.listeners
+	ms.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(change)
+	}
+}
+
+func (ms *InMemoryStorage) CreateEvent(event Event) Event {
+	ms.mu.Lock()
 	event.ID = uuid.New().String()
 	ms.events[event.ID] = event
-	return event.Title
+	ms.mu.Unlock()
+
+	ms.notify(Change{Type: EventCreated, Event: event})
+	return event
 }
 
-func (ms *InMemoryStorage) UpdateEvent(updatedEvent Event) bool {
+// CreateEventIfFree stores the event unless conflicts reports one of the
+// stored events as standing in its way, and returns that event instead.
+// The check and the write happen under one lock, so concurrent callers
+// never both get the same time; conflicts must not call the storage.
+func (ms *InMemoryStorage) CreateEventIfFree(event Event, conflicts func(Event) bool) (Event, bool) {
 	ms.mu.Lock()
-	defer ms.mu.Unlock()
+	for _, existing := range ms.events {
+		if conflicts(existing) {
+			ms.mu.Unlock()
+			return existing, false
+		}
+	}
+	event.ID = uuid.New().String()
+	ms.events[event.ID] = event
+	ms.mu.Unlock()
+
+	ms.notify(Change{Type: EventCreated, Event: event})
+	return event, true
+}
 
-	if _, exists := ms.events[updatedEvent.ID]; exists {
+func (ms *InMemoryStorage) UpdateEvent(updatedEvent Event) bool {
+	ms.mu.Lock()
+	_, exists := ms.events[updatedEvent.ID]
+	if exists {
 		ms.events[updatedEvent.ID] = updatedEvent
-		return true
 	}
-	return false
+	ms.mu.Unlock()
+
+	if exists {
+		ms.notify(Change{Type: EventUpdated, Event: updatedEvent})
+	}
+	return exists
 }
 
 func (ms *InMemoryStorage) DeleteEvent(id string) bool {
 	ms.mu.Lock()
-	defer ms.mu.Unlock()
-
-	_, exists := ms.events[id]
+	event, exists := ms.events[id]
 	if exists {
 		delete(ms.events, id)
-		return true
 	}
-	return false
+	ms.mu.Unlock()
+
+	if exists {
+		ms.notify(Change{Type: EventDeleted, Event: event})
+	}
+	return exists
+}
+
+// PutEvent stores the event under its own ID, creating or replacing it.
+func (ms *InMemoryStorage) PutEvent(event Event) {
+	ms.mu.Lock()
+	_, exists := ms.events[event.ID]
+	ms.events[event.ID] = event
+	ms.mu.Unlock()
+
+	if exists {
+		ms.notify(Change{Type: EventUpdated, Event: event})
+	} else {
+		ms.notify(Change{Type: EventCreated, Event: event})
+	}
+}
+
+// PutEventByExternalID creates the event, or updates the event with the
+// same ExternalID while keeping its ID: merge builds the new version from
+// the stored one, or the event replaces it when merge is nil. The lookup
+// and the write happen under one lock, so concurrent imports never
+// duplicate an event; merge must not call the storage. It returns the
+// stored event and EventCreated, EventUpdated, or "" when the stored event
+// already matched. An event without an ExternalID is always created.
+func (ms *InMemoryStorage) PutEventByExternalID(event Event, merge func(existing Event) Event) (Event, ChangeType) {
+	ms.mu.Lock()
+	change := EventCreated
+	event.ID = uuid.New().String()
+	if event.ExternalID != "" {
+		for _, existing := range ms.events {
+			if existing.ExternalID != event.ExternalID {
+				continue
+			}
+			if merge != nil {
+				event = merge(existing)
+			}
+			event.ID, event.ExternalID = existing.ID, existing.ExternalID
+			if sameEvent(existing, event) {
+				ms.mu.Unlock()
+				return existing, ""
+			}
+			change = EventUpdated
+			break
+		}
+	}
+	ms.events[event.ID] = event
+	ms.mu.Unlock()
+
+	ms.notify(Change{Type: change, Event: event})
+	return event, change
 }
 
 func (ms *InMemoryStorage) GetEvent() []Event {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	return ms.sortedEvents()
+}
+
+// sortedEvents lists all events. ms.mu must be held.
+func (ms *InMemoryStorage) sortedEvents() []Event {
 	allEvents := make([]Event, 0, len(ms.events))
 	for _, event := range ms.events {
 		allEvents = append(allEvents, event)
 	}
+	sortEvents(allEvents)
 	return allEvents
 }
 
-func (ms *InMemoryStorage) GetEventsForDay(date time.Time) []Event {
+func (ms *InMemoryStorage) GetEventByID(id string) (Event, bool) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	var dailyEvents []Event
+	event, ok := ms.events[id]
+	return event, ok
+}
+
+// ArchiveEventsBefore removes and returns the events that start before
+// the given time. Listeners get EventArchived for each of them.
+func (ms *InMemoryStorage) ArchiveEventsBefore(before time.Time) []Event {
+	ms.mu.Lock()
+	var archived []Event
+	for id, event := range ms.events {
+		if event.Date.Before(before) {
+			archived = append(archived, event)
+			delete(ms.events, id)
+		}
+	}
+	ms.mu.Unlock()
+
+	sortEvents(archived)
+	for _, event := range archived {
+		ms.notify(Change{Type: EventArchived, Event: event})
+	}
+	return archived
+}
+
+// ArchiveEvent removes one event like DeleteEvent, but listeners get
+// EventArchived, so the event is treated as kept elsewhere rather than gone.
+func (ms *InMemoryStorage) ArchiveEvent(id string) bool {
+	ms.mu.Lock()
+	event, exists := ms.events[id]
+	if exists {
+		delete(ms.events, id)
+	}
+	ms.mu.Unlock()
+
+	if exists {
+		ms.notify(Change{Type: EventArchived, Event: event})
+	}
+	return exists
+}
+
+// Restore replaces all events. Listeners are notified of the difference:
+// events missing from the new set are deleted, the others created or
+// updated, so webhooks fire and attachments of dropped events go away.
+func (ms *InMemoryStorage) Restore(events []Event) {
+	ms.mu.Lock()
+	previous := ms.events
+	ms.events = make(map[string]Event, len(events))
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.New().String()
+		}
+		ms.events[event.ID] = event
+	}
+	var deleted []Event
+	for id, event := range previous {
+		if _, kept := ms.events[id]; !kept {
+			deleted = append(deleted, event)
+		}
+	}
+	restored := make([]Event, 0, len(ms.events))
 	for _, event := range ms.events {
-		if event.Date.Equal(date) {
-			dailyEvents = append(dailyEvents, event)
+		restored = append(restored, event)
+	}
+	ms.mu.Unlock()
+
+	var changes []Change
+	sortEvents(deleted)
+	for _, event := range deleted {
+		changes = append(changes, Change{Type: EventDeleted, Event: event})
+	}
+	sortEvents(restored)
+	for _, event := range restored {
+		old, existed := previous[event.ID]
+		switch {
+		case !existed:
+			changes = append(changes, Change{Type: EventCreated, Event: event})
+		case !sameEvent(old, event):
+			changes = append(changes, Change{Type: EventUpdated, Event: event})
 		}
 	}
-	return dailyEvents
+	for _, change := range changes {
+		ms.notify(change)
+	}
 }
 
-func (ms *InMemoryStorage) GetEventsForWeek(date time.Time) []Event {
+func sameEvent(a, b Event) bool {
+	if a.Timed() != b.Timed() || a.Timed() && !a.End.Equal(*b.End) {
+		return false
+	}
+	return a.ID == b.ID && a.Title == b.Title && a.Date.Equal(b.Date) && a.CalendarID == b.CalendarID && a.ExternalID == b.ExternalID
+}
+
+func (ms *InMemoryStorage) GetEventsForDay(date time.Time) []Event {
+	day := period.StartOfDay(date)
+	return ms.GetEventsForRange(day, day.AddDate(0, 0, 1))
+}
+
+func (ms *InMemoryStorage) GetEventsForRange(start, end time.Time) []Event {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	startOfWeek := date.AddDate(0, 0, -int(date.Weekday()))
-	endOfWeek := startOfWeek.AddDate(0, 0, 7)
-
-	var weeklyEvents []Event
+	var rangeEvents []Event
 	for _, event := range ms.events {
-		if event.Date.Before(endOfWeek) && event.Date.After(startOfWeek) {
-			weeklyEvents = append(weeklyEvents, event)
+		if !event.Date.Before(start) && event.Date.Before(end) {
+			rangeEvents = append(rangeEvents, event)
 		}
 	}
-	return weeklyEvents
+	sortEvents(rangeEvents)
+	return rangeEvents
+}
+
+func (ms *InMemoryStorage) GetEventsForWeek(date time.Time, weekStart time.Weekday) []Event {
+	week := period.Week(date, weekStart)
+	return ms.GetEventsForRange(week.Start, week.End)
 }
 
 func (ms *InMemoryStorage) GetEventsForMonth(date time.Time) []Event {
+	month := period.Month(date, time.Sunday)
+	return ms.GetEventsForRange(month.Start, month.End)
+}
+
+func sortEvents(events []Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date.Equal(events[j].Date) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Date.Before(events[j].Date)
+	})
+}
+
+func (ms *InMemoryStorage) CreateCalendar(calendar Calendar) Calendar {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
-	var monthlyEvents []Event
-	for _, event := range ms.events {
-		if event.Date.Year() == date.Year() && event.Date.Month() == date.Month() {
-			monthlyEvents = append(monthlyEvents, event)
+	calendar.ID = uuid.New().String()
+	ms.calendars[calendar.ID] = calendar
+	return calendar
+}
+
+func (ms *InMemoryStorage) UpdateCalendar(calendar Calendar) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	_, exists := ms.calendars[calendar.ID]
+	if exists {
+		ms.calendars[calendar.ID] = calendar
+	}
+	return exists
+}
+
+func (ms *InMemoryStorage) PutCalendar(calendar Calendar) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.calendars[calendar.ID] = calendar
+}
+
+// DeleteCalendar removes the calendar together with its events. Listeners
+// are notified of every deleted event.
+func (ms *InMemoryStorage) DeleteCalendar(id string) bool {
+	ms.mu.Lock()
+	_, exists := ms.calendars[id]
+	var deleted []Event
+	if exists {
+		delete(ms.calendars, id)
+		for eventID, event := range ms.events {
+			if event.CalendarID == id {
+				deleted = append(deleted, event)
+				delete(ms.events, eventID)
+			}
+		}
+	}
+	ms.mu.Unlock()
+
+	sortEvents(deleted)
+	for _, event := range deleted {
+		ms.notify(Change{Type: EventDeleted, Event: event})
+	}
+	return exists
+}
+
+func (ms *InMemoryStorage) GetCalendar(id string) (Calendar, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	calendar, ok := ms.calendars[id]
+	return calendar, ok
+}
+
+func (ms *InMemoryStorage) GetCalendars() []Calendar {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.sortedCalendars()
+}
+
+// sortedCalendars lists all calendars by name. ms.mu must be held.
+func (ms *InMemoryStorage) sortedCalendars() []Calendar {
+	calendars := make([]Calendar, 0, len(ms.calendars))
+	for _, calendar := range ms.calendars {
+		calendars = append(calendars, calendar)
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		if calendars[i].Name == calendars[j].Name {
+			return calendars[i].ID < calendars[j].ID
+		}
+		return calendars[i].Name < calendars[j].Name
+	})
+	return calendars
+}
+
+// Dump returns the calendars and events as of one moment, so that no event
+// refers to a calendar created after the calendars were read.
+func (ms *InMemoryStorage) Dump() ([]Calendar, []Event) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.sortedCalendars(), ms.sortedEvents()
+}
+
+func (ms *InMemoryStorage) RestoreCalendars(calendars []Calendar) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.calendars = make(map[string]Calendar, len(calendars))
+	for _, calendar := range calendars {
+		if calendar.ID == "" {
+			calendar.ID = uuid.New().String()
 		}
+		ms.calendars[calendar.ID] = calendar
 	}
-	return monthlyEvents
 }
@@ -0,0 +1,34 @@
+package service_test
+
+import (
+	"calendar/internal/service"
+	"calendar/internal/service/storagetest"
+	"testing"
+)
+
+func TestInMemoryStorageConformance(t *testing.T) {
+	storagetest.Run(t, func() service.Storage {
+		return service.NewInMemoryStorage()
+	})
+}
+
+func TestCalendarVisibleTo(t *testing.T) {
+	tests := []struct {
+		calendar service.Calendar
+		user     string
+		visible  bool
+	}{
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityPrivate}, "ann", true},
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityPrivate}, "bob", false},
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityPrivate}, "", false},
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityShared}, "bob", true},
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityShared}, "", false},
+		{service.Calendar{Owner: "ann", Visibility: service.VisibilityPublic}, "", true},
+		{service.Calendar{Visibility: service.VisibilityPrivate}, "", true},
+	}
+	for _, test := range tests {
+		if got := test.calendar.VisibleTo(test.user); got != test.visible {
+			t.Errorf("%+v visible to %q: got %t, want %t", test.calendar, test.user, got, test.visible)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import "time"
+
+type Storage interface {
+	CreateEvent(event Event) Event
+	CreateEventIfFree(event Event, conflicts func(Event) bool) (Event, bool)
+	UpdateEvent(updatedEvent Event) bool
+	DeleteEvent(id string) bool
+	PutEvent(event Event)
+	PutEventByExternalID(event Event, merge func(existing Event) Event) (Event, ChangeType)
+	GetEvent() []Event
+	GetEventByID(id string) (Event, bool)
+	GetEventsForDay(date time.Time) []Event
+	GetEventsForWeek(date time.Time, weekStart time.Weekday) []Event
+	GetEventsForMonth(date time.Time) []Event
+	GetEventsForRange(start, end time.Time) []Event
+	ArchiveEventsBefore(before time.Time) []Event
+	ArchiveEvent(id string) bool
+	Restore(events []Event)
+	CreateCalendar(calendar Calendar) Calendar
+	UpdateCalendar(calendar Calendar) bool
+	DeleteCalendar(id string) bool
+	PutCalendar(calendar Calendar)
+	GetCalendar(id string) (Calendar, bool)
+	GetCalendars() []Calendar
+	RestoreCalendars(calendars []Calendar)
+	Dump() ([]Calendar, []Event)
+	Subscribe(listener func(Change))
+}
+
+var _ Storage = (*InMemoryStorage)(nil)
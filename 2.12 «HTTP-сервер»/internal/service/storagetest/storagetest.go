@@ -0,0 +1,560 @@
+// Package storagetest is a conformance suite for implementations of
+// service.Storage. A backend passes it by calling Run from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func() service.Storage { return NewMyStorage() })
+//	}
+package storagetest
+
+import (
+	"calendar/internal/service"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Run(t *testing.T, newStorage func() service.Storage) {
+	t.Run("CRUD", func(t *testing.T) { testCRUD(t, newStorage()) })
+	t.Run("CreateEventIfFree", func(t *testing.T) { testCreateEventIfFree(t, newStorage()) })
+	t.Run("DayBoundaries", func(t *testing.T) { testDayBoundaries(t, newStorage()) })
+	t.Run("WeekBoundaries", func(t *testing.T) { testWeekBoundaries(t, newStorage()) })
+	t.Run("MonthBoundaries", func(t *testing.T) { testMonthBoundaries(t, newStorage()) })
+	t.Run("RangeBoundaries", func(t *testing.T) { testRangeBoundaries(t, newStorage()) })
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, newStorage()) })
+	t.Run("Restore", func(t *testing.T) { testRestore(t, newStorage()) })
+	t.Run("Calendars", func(t *testing.T) { testCalendars(t, newStorage()) })
+	t.Run("Dump", func(t *testing.T) { testDump(t, newStorage()) })
+	t.Run("Put", func(t *testing.T) { testPut(t, newStorage()) })
+	t.Run("PutEventByExternalID", func(t *testing.T) { testPutEventByExternalID(t, newStorage()) })
+	t.Run("ArchiveEventsBefore", func(t *testing.T) { testArchiveEventsBefore(t, newStorage()) })
+	t.Run("ArchiveEvent", func(t *testing.T) { testArchiveEvent(t, newStorage()) })
+	t.Run("Subscribe", func(t *testing.T) { testSubscribe(t, newStorage()) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newStorage()) })
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func create(t *testing.T, storage service.Storage, title string, date time.Time) service.Event {
+	t.Helper()
+
+	storage.CreateEvent(service.Event{Title: title, Date: date})
+	for _, event := range storage.GetEvent() {
+		if event.Title == title && event.Date.Equal(date) {
+			if event.ID == "" {
+				t.Fatalf("created event %q has no ID", title)
+			}
+			return event
+		}
+	}
+	t.Fatalf("created event %q not found", title)
+	return service.Event{}
+}
+
+func titles(events []service.Event) []string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Title)
+	}
+	return result
+}
+
+func expectTitles(t *testing.T, name string, events []service.Event, want ...string) {
+	t.Helper()
+
+	got := titles(events)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+	}
+}
+
+func testCRUD(t *testing.T, storage service.Storage) {
+	if events := storage.GetEvent(); len(events) != 0 {
+		t.Fatalf("new storage is not empty: %v", events)
+	}
+
+	returned := storage.CreateEvent(service.Event{Title: "standup", Date: day(2024, 10, 1)})
+	events := storage.GetEvent()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %v", events)
+	}
+	created := events[0]
+	if returned != created {
+		t.Errorf("CreateEvent returned %+v, stored %+v", returned, created)
+	}
+
+	updated := service.Event{ID: created.ID, Title: "retro", Date: day(2024, 10, 2)}
+	if !storage.UpdateEvent(updated) {
+		t.Fatal("UpdateEvent returned false for an existing event")
+	}
+	if events := storage.GetEvent(); len(events) != 1 || events[0] != updated {
+		t.Errorf("after update got %v, want [%v]", events, updated)
+	}
+
+	if got, ok := storage.GetEventByID(created.ID); !ok || got != updated {
+		t.Errorf("GetEventByID returned %v, %t, want %v", got, ok, updated)
+	}
+	if _, ok := storage.GetEventByID("missing"); ok {
+		t.Error("GetEventByID found a missing event")
+	}
+
+	if storage.UpdateEvent(service.Event{ID: "missing", Title: "x", Date: day(2024, 10, 2)}) {
+		t.Error("UpdateEvent returned true for a missing event")
+	}
+	if len(storage.GetEvent()) != 1 {
+		t.Error("UpdateEvent of a missing event created it")
+	}
+
+	if !storage.DeleteEvent(created.ID) {
+		t.Fatal("DeleteEvent returned false for an existing event")
+	}
+	if storage.DeleteEvent(created.ID) {
+		t.Error("DeleteEvent returned true for an already deleted event")
+	}
+	if events := storage.GetEvent(); len(events) != 0 {
+		t.Errorf("expected no events after delete, got %v", events)
+	}
+}
+
+func at(hour, minute int) *time.Time {
+	tm := time.Date(2024, 10, 15, hour, minute, 0, 0, time.UTC)
+	return &tm
+}
+
+// overlapping is the conflict check of CreateEventIfFree that lets only
+// timed events overlapping the event stand in its way.
+func overlapping(event service.Event) func(service.Event) bool {
+	return func(existing service.Event) bool {
+		return existing.Overlaps(event.Date, *event.End)
+	}
+}
+
+func createIfFree(storage service.Storage, event service.Event) (service.Event, bool) {
+	return storage.CreateEventIfFree(event, overlapping(event))
+}
+
+func testCreateEventIfFree(t *testing.T, storage service.Storage) {
+	allDay := create(t, storage, "all day", day(2024, 10, 15))
+
+	first, ok := createIfFree(storage, service.Event{Title: "first", Date: *at(10, 0), End: at(10, 30)})
+	if !ok || first.ID == "" {
+		t.Fatalf("CreateEventIfFree into a free slot failed: %+v", first)
+	}
+
+	if conflict, ok := createIfFree(storage, service.Event{Title: "overlap", Date: *at(10, 15), End: at(10, 45)}); ok {
+		t.Error("CreateEventIfFree created an overlapping event")
+	} else if conflict.ID != first.ID {
+		t.Errorf("expected conflict with %s, got %+v", first.ID, conflict)
+	}
+
+	if _, ok := createIfFree(storage, service.Event{Title: "adjacent", Date: *at(10, 30), End: at(11, 0)}); !ok {
+		t.Error("CreateEventIfFree rejected an adjacent event")
+	}
+
+	wholeDay := func(existing service.Event) bool { return !existing.Timed() }
+	if conflict, ok := storage.CreateEventIfFree(service.Event{Title: "blocked", Date: *at(15, 0), End: at(15, 30)}, wholeDay); ok {
+		t.Error("CreateEventIfFree ignored the conflict check")
+	} else if conflict.ID != allDay.ID {
+		t.Errorf("expected conflict with the all-day event, got %+v", conflict)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := createIfFree(storage, service.Event{Title: "race", Date: *at(12, 0), End: at(12, 30)}); ok {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Errorf("concurrent CreateEventIfFree created %d events for one slot, want 1", created)
+	}
+}
+
+func testDayBoundaries(t *testing.T, storage service.Storage) {
+	create(t, storage, "previous", day(2024, 10, 14))
+	create(t, storage, "target", day(2024, 10, 15))
+	create(t, storage, "next", day(2024, 10, 16))
+
+	expectTitles(t, "GetEventsForDay", storage.GetEventsForDay(day(2024, 10, 15)), "target")
+	expectTitles(t, "GetEventsForDay empty", storage.GetEventsForDay(day(2024, 10, 20)))
+}
+
+func testWeekBoundaries(t *testing.T, storage service.Storage) {
+	// 2024-10-13 is a Sunday, 2024-10-14 a Monday.
+	create(t, storage, "saturday before", day(2024, 10, 12))
+	create(t, storage, "sunday", day(2024, 10, 13))
+	create(t, storage, "monday", day(2024, 10, 14))
+	create(t, storage, "saturday", day(2024, 10, 19))
+	create(t, storage, "next sunday", day(2024, 10, 20))
+	create(t, storage, "next monday", day(2024, 10, 21))
+
+	expectTitles(t, "sunday week from wednesday", storage.GetEventsForWeek(day(2024, 10, 16), time.Sunday),
+		"sunday", "monday", "saturday")
+	expectTitles(t, "sunday week from its first day", storage.GetEventsForWeek(day(2024, 10, 13), time.Sunday),
+		"sunday", "monday", "saturday")
+	expectTitles(t, "monday week from wednesday", storage.GetEventsForWeek(day(2024, 10, 16), time.Monday),
+		"monday", "saturday", "next sunday")
+	expectTitles(t, "monday week from sunday", storage.GetEventsForWeek(day(2024, 10, 13), time.Monday),
+		"saturday before", "sunday")
+}
+
+func testMonthBoundaries(t *testing.T, storage service.Storage) {
+	create(t, storage, "last of january", day(2024, 1, 31))
+	create(t, storage, "first of february", day(2024, 2, 1))
+	create(t, storage, "leap day", day(2024, 2, 29))
+	create(t, storage, "first of march", day(2024, 3, 1))
+	create(t, storage, "february next year", day(2025, 2, 10))
+
+	expectTitles(t, "GetEventsForMonth", storage.GetEventsForMonth(day(2024, 2, 15)), "first of february", "leap day")
+}
+
+func testRangeBoundaries(t *testing.T, storage service.Storage) {
+	create(t, storage, "before", day(2024, 5, 9))
+	create(t, storage, "start", day(2024, 5, 10))
+	create(t, storage, "inside", day(2024, 5, 12))
+	create(t, storage, "end", day(2024, 5, 15))
+
+	expectTitles(t, "start inclusive, end exclusive", storage.GetEventsForRange(day(2024, 5, 10), day(2024, 5, 15)),
+		"start", "inside")
+	expectTitles(t, "empty range", storage.GetEventsForRange(day(2024, 5, 10), day(2024, 5, 10)))
+}
+
+func testOrdering(t *testing.T, storage service.Storage) {
+	create(t, storage, "third", day(2024, 3, 30))
+	create(t, storage, "first", day(2024, 3, 1))
+	create(t, storage, "second", day(2024, 3, 15))
+
+	expectTitles(t, "GetEvent", storage.GetEvent(), "first", "second", "third")
+	expectTitles(t, "GetEventsForMonth", storage.GetEventsForMonth(day(2024, 3, 1)), "first", "second", "third")
+	expectTitles(t, "GetEventsForRange", storage.GetEventsForRange(day(2024, 3, 1), day(2024, 4, 1)), "first", "second", "third")
+
+	storage.CreateEvent(service.Event{Title: "same day a", Date: day(2024, 3, 20)})
+	storage.CreateEvent(service.Event{Title: "same day b", Date: day(2024, 3, 20)})
+	first := storage.GetEventsForDay(day(2024, 3, 20))
+	for i := 0; i < 5; i++ {
+		if again := storage.GetEventsForDay(day(2024, 3, 20)); fmt.Sprint(again) != fmt.Sprint(first) {
+			t.Fatalf("ordering of events on the same day is not stable: %v vs %v", first, again)
+		}
+	}
+}
+
+func testRestore(t *testing.T, storage service.Storage) {
+	create(t, storage, "old", day(2024, 1, 1))
+	storage.PutEvent(service.Event{ID: "a", Title: "restored a", Date: day(2024, 2, 1)})
+	storage.PutEvent(service.Event{ID: "b", Title: "draft b", Date: day(2024, 2, 2)})
+
+	var mu sync.Mutex
+	var changes []string
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, string(change.Type)+" "+change.Event.Title)
+	})
+
+	restored := []service.Event{
+		{ID: "a", Title: "restored a", Date: day(2024, 2, 1)},
+		{ID: "b", Title: "restored b", Date: day(2024, 2, 2)},
+		{ID: "c", Title: "restored c", Date: day(2024, 2, 3)},
+	}
+	storage.Restore(restored)
+
+	events := storage.GetEvent()
+	if fmt.Sprint(events) != fmt.Sprint(restored) {
+		t.Errorf("after restore got %v, want %v", events, restored)
+	}
+	mu.Lock()
+	if want := "[event.deleted old event.updated restored b event.created restored c]"; fmt.Sprint(changes) != want {
+		t.Errorf("Restore notified %v, want %s", changes, want)
+	}
+	mu.Unlock()
+	if !storage.DeleteEvent("a") {
+		t.Error("restored event IDs are not preserved")
+	}
+}
+
+func testCalendars(t *testing.T, storage service.Storage) {
+	if calendars := storage.GetCalendars(); len(calendars) != 0 {
+		t.Fatalf("new storage has calendars: %v", calendars)
+	}
+
+	team := storage.CreateCalendar(service.Calendar{Name: "team", Colour: "#00aa00", Visibility: service.VisibilityShared})
+	personal := storage.CreateCalendar(service.Calendar{Name: "personal", Owner: "ann", Visibility: service.VisibilityPrivate})
+	if team.ID == "" || personal.ID == "" || team.ID == personal.ID {
+		t.Fatalf("calendars got IDs %q and %q", team.ID, personal.ID)
+	}
+	if calendars := storage.GetCalendars(); len(calendars) != 2 || calendars[0] != personal || calendars[1] != team {
+		t.Errorf("GetCalendars returned %v, want sorted by name", calendars)
+	}
+
+	team.Colour = "#ff0000"
+	if !storage.UpdateCalendar(team) {
+		t.Fatal("UpdateCalendar returned false for an existing calendar")
+	}
+	if got, ok := storage.GetCalendar(team.ID); !ok || got != team {
+		t.Errorf("GetCalendar returned %v, %t, want %v", got, ok, team)
+	}
+	if storage.UpdateCalendar(service.Calendar{ID: "missing", Name: "x"}) {
+		t.Error("UpdateCalendar returned true for a missing calendar")
+	}
+
+	storage.CreateEvent(service.Event{Title: "sync", Date: day(2024, 5, 1), CalendarID: team.ID})
+	storage.CreateEvent(service.Event{Title: "dentist", Date: day(2024, 5, 2), CalendarID: personal.ID})
+	storage.CreateEvent(service.Event{Title: "loose", Date: day(2024, 5, 3)})
+
+	var mu sync.Mutex
+	var deleted []string
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		if change.Type == service.EventDeleted {
+			deleted = append(deleted, change.Event.Title)
+		}
+	})
+
+	if !storage.DeleteCalendar(team.ID) {
+		t.Fatal("DeleteCalendar returned false for an existing calendar")
+	}
+	if storage.DeleteCalendar(team.ID) {
+		t.Error("DeleteCalendar returned true for an already deleted calendar")
+	}
+	expectTitles(t, "events after calendar delete", storage.GetEvent(), "dentist", "loose")
+	mu.Lock()
+	if fmt.Sprint(deleted) != "[sync]" {
+		t.Errorf("deleted events notified: %v, want [sync]", deleted)
+	}
+	mu.Unlock()
+
+	storage.RestoreCalendars([]service.Calendar{{ID: "c1", Name: "on-call"}})
+	if calendars := storage.GetCalendars(); len(calendars) != 1 || calendars[0].ID != "c1" {
+		t.Errorf("after restore got %v", calendars)
+	}
+}
+
+func testDump(t *testing.T, storage service.Storage) {
+	team := storage.CreateCalendar(service.Calendar{Name: "team"})
+	storage.CreateCalendar(service.Calendar{Name: "all hands"})
+	storage.CreateEvent(service.Event{Title: "sync", Date: day(2024, 5, 2), CalendarID: team.ID})
+	storage.CreateEvent(service.Event{Title: "kickoff", Date: day(2024, 5, 1)})
+
+	calendars, events := storage.Dump()
+	if len(calendars) != 2 || calendars[0].Name != "all hands" || calendars[1] != team {
+		t.Errorf("Dump returned calendars %v", calendars)
+	}
+	expectTitles(t, "dumped events", events, "kickoff", "sync")
+}
+
+func testPut(t *testing.T, storage service.Storage) {
+	var mu sync.Mutex
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.Type)
+	})
+
+	storage.PutEvent(service.Event{ID: "e1", Title: "draft", Date: day(2024, 6, 1)})
+	storage.PutEvent(service.Event{ID: "e1", Title: "final", Date: day(2024, 6, 2)})
+	if got, ok := storage.GetEventByID("e1"); !ok || got.Title != "final" || !got.Date.Equal(day(2024, 6, 2)) {
+		t.Errorf("GetEventByID after PutEvent returned %v, %t", got, ok)
+	}
+	expectTitles(t, "events after PutEvent", storage.GetEvent(), "final")
+	mu.Lock()
+	if fmt.Sprint(changes) != "[event.created event.updated]" {
+		t.Errorf("PutEvent notified %v, want [event.created event.updated]", changes)
+	}
+	mu.Unlock()
+
+	storage.PutCalendar(service.Calendar{ID: "c1", Name: "team"})
+	storage.PutCalendar(service.Calendar{ID: "c1", Name: "squad"})
+	if got, ok := storage.GetCalendar("c1"); !ok || got.Name != "squad" {
+		t.Errorf("GetCalendar after PutCalendar returned %v, %t", got, ok)
+	}
+	if calendars := storage.GetCalendars(); len(calendars) != 1 {
+		t.Errorf("PutCalendar with the same ID created %d calendars", len(calendars))
+	}
+}
+
+func testPutEventByExternalID(t *testing.T, storage service.Storage) {
+	var mu sync.Mutex
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.Type)
+	})
+
+	first, change := storage.PutEventByExternalID(service.Event{Title: "draft", Date: day(2024, 6, 1), ExternalID: "x-1"}, nil)
+	if change != service.EventCreated || first.ID == "" {
+		t.Fatalf("first put returned %+v, %q", first, change)
+	}
+	if got, change := storage.PutEventByExternalID(service.Event{Title: "draft", Date: day(2024, 6, 1), ExternalID: "x-1"}, nil); change != "" || got.ID != first.ID {
+		t.Errorf("unchanged put returned %+v, %q", got, change)
+	}
+	if got, change := storage.PutEventByExternalID(service.Event{Title: "final", Date: day(2024, 6, 2), ExternalID: "x-1"}, nil); change != service.EventUpdated || got.ID != first.ID {
+		t.Errorf("changed put returned %+v, %q", got, change)
+	}
+	storage.PutEventByExternalID(service.Event{Title: "no id", Date: day(2024, 6, 3)}, nil)
+	storage.PutEventByExternalID(service.Event{Title: "no id", Date: day(2024, 6, 3)}, nil)
+	expectTitles(t, "events after PutEventByExternalID", storage.GetEvent(), "final", "no id", "no id")
+	mu.Lock()
+	if fmt.Sprint(changes) != "[event.created event.updated event.created event.created]" {
+		t.Errorf("PutEventByExternalID notified %v", changes)
+	}
+	mu.Unlock()
+
+	// merge builds the update from the stored event.
+	merged, change := storage.PutEventByExternalID(service.Event{Title: "ignored", ExternalID: "x-1"}, func(existing service.Event) service.Event {
+		existing.Title = "merged"
+		return existing
+	})
+	if change != service.EventUpdated || merged.ID != first.ID || merged.Title != "merged" || !merged.Date.Equal(day(2024, 6, 2)) {
+		t.Errorf("merged put returned %+v, %q", merged, change)
+	}
+
+	// Concurrent imports of the same row create a single event.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			storage.PutEventByExternalID(service.Event{Title: "race", Date: day(2024, 7, 1), ExternalID: "x-2"}, nil)
+		}()
+	}
+	wg.Wait()
+	if events := storage.GetEventsForDay(day(2024, 7, 1)); len(events) != 1 {
+		t.Errorf("concurrent puts stored %d events, want 1", len(events))
+	}
+}
+
+func testArchiveEventsBefore(t *testing.T, storage service.Storage) {
+	create(t, storage, "old", day(2022, 3, 1))
+	create(t, storage, "older", day(2021, 7, 9))
+	create(t, storage, "boundary", day(2023, 1, 1))
+	create(t, storage, "new", day(2024, 2, 2))
+
+	var mu sync.Mutex
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.Type)
+	})
+
+	expectTitles(t, "archived events", storage.ArchiveEventsBefore(day(2023, 1, 1)), "older", "old")
+	expectTitles(t, "remaining events", storage.GetEvent(), "boundary", "new")
+	if archived := storage.ArchiveEventsBefore(day(2023, 1, 1)); len(archived) != 0 {
+		t.Errorf("second archive returned %v", titles(archived))
+	}
+	mu.Lock()
+	if fmt.Sprint(changes) != "[event.archived event.archived]" {
+		t.Errorf("ArchiveEventsBefore notified %v", changes)
+	}
+	mu.Unlock()
+}
+
+func testArchiveEvent(t *testing.T, storage service.Storage) {
+	create(t, storage, "old", day(2022, 3, 1))
+	create(t, storage, "new", day(2024, 2, 2))
+	old := storage.GetEvent()[0]
+
+	var mu sync.Mutex
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.Type)
+	})
+
+	if !storage.ArchiveEvent(old.ID) || storage.ArchiveEvent(old.ID) {
+		t.Error("ArchiveEvent should succeed once")
+	}
+	expectTitles(t, "remaining events", storage.GetEvent(), "new")
+	mu.Lock()
+	if fmt.Sprint(changes) != "[event.archived]" {
+		t.Errorf("ArchiveEvent notified %v", changes)
+	}
+	mu.Unlock()
+}
+
+func testSubscribe(t *testing.T, storage service.Storage) {
+	var mu sync.Mutex
+	var changes []service.ChangeType
+	storage.Subscribe(func(change service.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.Type)
+	})
+
+	event := create(t, storage, "watched", day(2024, 6, 1))
+	event.Title = "watched again"
+	storage.UpdateEvent(event)
+	storage.UpdateEvent(service.Event{ID: "missing"})
+	storage.DeleteEvent(event.ID)
+	storage.DeleteEvent(event.ID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []service.ChangeType{service.EventCreated, service.EventUpdated, service.EventDeleted}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("got changes %v, want %v", changes, want)
+	}
+}
+
+func testConcurrency(t *testing.T, storage service.Storage) {
+	const workers = 8
+	const perWorker = 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				date := day(2024, 7, 1+i%28)
+				storage.CreateEvent(service.Event{Title: fmt.Sprintf("w%d-%d", w, i), Date: date})
+				storage.GetEventsForDay(date)
+				storage.GetEventsForWeek(date, time.Monday)
+				storage.GetEventsForMonth(date)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	events := storage.GetEvent()
+	if len(events) != workers*perWorker {
+		t.Fatalf("expected %d events, got %d", workers*perWorker, len(events))
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(events); i += workers {
+				event := events[i]
+				if i%2 == 0 {
+					event.Title += " updated"
+					if !storage.UpdateEvent(event) {
+						t.Errorf("UpdateEvent(%s) returned false", event.ID)
+					}
+				} else if !storage.DeleteEvent(event.ID) {
+					t.Errorf("DeleteEvent(%s) returned false", event.ID)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if remaining := len(storage.GetEvent()); remaining != (len(events)+1)/2 {
+		t.Errorf("expected %d events after concurrent deletes, got %d", (len(events)+1)/2, remaining)
+	}
+}
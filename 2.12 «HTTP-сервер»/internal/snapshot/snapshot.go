@@ -0,0 +1,248 @@
+package snapshot
+
+import (
+	"calendar/internal/booking"
+	"calendar/internal/preference"
+	"calendar/internal/service"
+	"calendar/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const CurrentVersion = 3
+
+// Snapshot holds the calendars and events of the storage together with the
+// booking pages, preferences and webhooks kept beside it. It leaves out
+// attachments, whose directory keeps its own index, the archive, webhook
+// dead letters and the replication log.
+type Snapshot struct {
+	Version      int                      `json:"version"`
+	CreatedAt    time.Time                `json:"created_at"`
+	Calendars    []service.Calendar       `json:"calendars"`
+	Events       []service.Event          `json:"events"`
+	BookingPages []booking.Page           `json:"booking_pages"`
+	Preferences  []preference.Preferences `json:"preferences"`
+	Webhooks     []Webhook                `json:"webhooks"`
+}
+
+// Webhook is a registered webhook with the secret the API never shows, so
+// a restored webhook keeps signing with it.
+type Webhook struct {
+	webhook.Webhook
+	Secret string `json:"secret"`
+}
+
+// Registries are the state kept beside the storage. Nil registries are
+// neither saved nor restored.
+type Registries struct {
+	Bookings    *booking.Registry
+	Preferences *preference.Registry
+	Webhooks    *webhook.Registry
+	// Dispatcher, if set, drops the queues of webhooks a restore removes.
+	Dispatcher *webhook.Dispatcher
+}
+
+// Migration upgrades a raw snapshot document from the version it is
+// registered for to the next one, e.g. by filling in a field that
+// service.Event gained in the meantime.
+type Migration func(doc map[string]interface{}) error
+
+var migrations = map[int]Migration{
+	// Version 2 added calendars; events of older snapshots belong to none.
+	1: func(doc map[string]interface{}) error {
+		doc["calendars"] = []interface{}{}
+		return nil
+	},
+	// Version 3 added booking pages, preferences and webhooks.
+	2: func(doc map[string]interface{}) error {
+		doc["booking_pages"] = []interface{}{}
+		doc["preferences"] = []interface{}{}
+		doc["webhooks"] = []interface{}{}
+		return nil
+	},
+}
+
+func RegisterMigration(fromVersion int, migration Migration) {
+	migrations[fromVersion] = migration
+}
+
+func Take(storage service.Storage, registries Registries) Snapshot {
+	calendars, events := storage.Dump()
+	snap := Snapshot{
+		Version:      CurrentVersion,
+		CreatedAt:    time.Now().UTC(),
+		Calendars:    calendars,
+		Events:       events,
+		BookingPages: []booking.Page{},
+		Preferences:  []preference.Preferences{},
+		Webhooks:     []Webhook{},
+	}
+	if registries.Bookings != nil {
+		snap.BookingPages = registries.Bookings.List()
+		sort.Slice(snap.BookingPages, func(i, j int) bool { return snap.BookingPages[i].Slug < snap.BookingPages[j].Slug })
+	}
+	if registries.Preferences != nil {
+		snap.Preferences = registries.Preferences.List()
+	}
+	if registries.Webhooks != nil {
+		for _, wh := range registries.Webhooks.List() {
+			snap.Webhooks = append(snap.Webhooks, Webhook{Webhook: wh, Secret: wh.Secret})
+		}
+		sort.Slice(snap.Webhooks, func(i, j int) bool { return snap.Webhooks[i].ID < snap.Webhooks[j].ID })
+	}
+	return snap
+}
+
+func Write(w io.Writer, storage service.Storage, registries Registries) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Take(storage, registries))
+}
+
+func Read(r io.Reader) (Snapshot, error) {
+	doc, err := upgrade(r, CurrentVersion, migrations)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// upgrade decodes a raw snapshot document and runs the migrations from its
+// version up to the current one, one step at a time.
+func upgrade(r io.Reader, current int, migrations map[int]Migration) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	version, ok := doc["version"].(float64)
+	if !ok {
+		return nil, errors.New("invalid snapshot: missing version")
+	}
+	if int(version) > current {
+		return nil, fmt.Errorf("snapshot version %d is newer than supported version %d", int(version), current)
+	}
+
+	for v := int(version); v < current; v++ {
+		migration, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from snapshot version %d", v)
+		}
+		if err := migration(doc); err != nil {
+			return nil, fmt.Errorf("migration from version %d failed: %w", v, err)
+		}
+		doc["version"] = float64(v + 1)
+	}
+	return doc, nil
+}
+
+func Restore(r io.Reader, storage service.Storage, registries Registries) (Snapshot, error) {
+	snap, err := Read(r)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := snap.RestoreRegistries(registries); err != nil {
+		return Snapshot{}, err
+	}
+	snap.RestoreStorage(storage)
+	return snap, nil
+}
+
+// RestoreStorage replaces the calendars and events of the storage.
+func (snap Snapshot) RestoreStorage(storage service.Storage) {
+	storage.RestoreCalendars(snap.Calendars)
+	storage.Restore(snap.Events)
+}
+
+// RestoreRegistries replaces the contents of the registries. Nothing
+// changes when the preferences are invalid.
+func (snap Snapshot) RestoreRegistries(registries Registries) error {
+	if registries.Preferences != nil {
+		if err := registries.Preferences.Restore(snap.Preferences); err != nil {
+			return fmt.Errorf("invalid snapshot: %w", err)
+		}
+	}
+	if registries.Bookings != nil {
+		registries.Bookings.Restore(snap.BookingPages)
+	}
+	if registries.Webhooks != nil {
+		webhooks := make([]webhook.Webhook, 0, len(snap.Webhooks))
+		for _, wh := range snap.Webhooks {
+			restored := wh.Webhook
+			restored.Secret = wh.Secret
+			webhooks = append(webhooks, restored)
+		}
+		for _, id := range registries.Webhooks.Restore(webhooks) {
+			if registries.Dispatcher != nil {
+				registries.Dispatcher.Remove(id)
+			}
+		}
+	}
+	return nil
+}
+
+func SaveFile(path string, storage service.Storage, registries Registries) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Write(tmp, storage, registries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadFile reads the snapshot at path without restoring it, so the storage
+// can be filled before anything subscribes to it and the registries once
+// they exist.
+func ReadFile(path string) (Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+func RunPeriodic(path string, interval time.Duration, storage service.Storage, registries Registries, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := SaveFile(path, storage, registries); err != nil {
+				log.Printf("Failed to write snapshot %s: %v", path, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package snapshot
+
+import (
+	"calendar/internal/booking"
+	"calendar/internal/preference"
+	"calendar/internal/service"
+	"calendar/internal/webhook"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpgradeChainsMigrations(t *testing.T) {
+	var applied []int
+	steps := map[int]Migration{
+		1: func(doc map[string]interface{}) error {
+			applied = append(applied, 1)
+			doc["calendars"] = []interface{}{}
+			return nil
+		},
+		2: func(doc map[string]interface{}) error {
+			applied = append(applied, 2)
+			if doc["version"] != float64(2) {
+				t.Errorf("migration from 2 saw version %v", doc["version"])
+			}
+			doc["owner"] = "ann"
+			return nil
+		},
+	}
+
+	doc, err := upgrade(strings.NewReader(`{"version": 1, "events": []}`), 3, steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Errorf("applied migrations %v, want [1 2]", applied)
+	}
+	if doc["version"] != float64(3) || doc["owner"] != "ann" || doc["calendars"] == nil {
+		t.Errorf("upgraded document %v", doc)
+	}
+
+	applied = nil
+	if _, err := upgrade(strings.NewReader(`{"version": 3}`), 3, steps); err != nil || len(applied) != 0 {
+		t.Errorf("current version ran migrations %v, err %v", applied, err)
+	}
+}
+
+func TestUpgradeErrors(t *testing.T) {
+	failed := errors.New("bad data")
+	steps := map[int]Migration{
+		1: func(doc map[string]interface{}) error { return nil },
+		3: func(doc map[string]interface{}) error { return failed },
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"version": 1}`, "no migration from snapshot version 2"},
+		{`{"version": 3}`, "migration from version 3 failed: bad data"},
+		{`{"version": 5}`, "snapshot version 5 is newer than supported version 4"},
+		{`{"events": []}`, "invalid snapshot: missing version"},
+		{`[]`, "invalid snapshot"},
+	}
+	for _, test := range tests {
+		_, err := upgrade(strings.NewReader(test.input), 4, steps)
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("upgrade(%s) returned %v, want %q", test.input, err, test.want)
+		}
+	}
+}
+
+func TestReadVersion1(t *testing.T) {
+	snap, err := Read(strings.NewReader(`{"version": 1, "created_at": "2023-01-02T00:00:00Z", "events": [{"id": "e1", "title": "old", "date": "2023-01-02T00:00:00Z"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != CurrentVersion || snap.Calendars == nil || snap.Webhooks == nil || len(snap.Events) != 1 || snap.Events[0].ID != "e1" {
+		t.Errorf("read %+v", snap)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	end := time.Date(2024, 10, 14, 10, 0, 0, 0, time.UTC)
+	source := service.NewInMemoryStorage()
+	source.RestoreCalendars([]service.Calendar{{ID: "c1", Name: "team"}})
+	source.Restore([]service.Event{{ID: "e1", Title: "standup", Date: end.Add(-time.Hour), End: &end, CalendarID: "c1"}})
+
+	sourceRegistries := Registries{Bookings: booking.NewRegistry(), Preferences: preference.NewRegistry(), Webhooks: webhook.NewRegistry()}
+	sourceRegistries.Bookings.Save(booking.Page{Slug: "intro", Title: "Intro call", SlotMinutes: 30})
+	sourceRegistries.Preferences.SetWeekStart("ann", time.Monday)
+	hook := sourceRegistries.Webhooks.Register(webhook.Webhook{URL: "http://example.com/hook", Secret: "s3cret", User: "ann"})
+
+	var buf strings.Builder
+	if err := Write(&buf, source, sourceRegistries); err != nil {
+		t.Fatal(err)
+	}
+	target := service.NewInMemoryStorage()
+	registries := Registries{Bookings: booking.NewRegistry(), Preferences: preference.NewRegistry(), Webhooks: webhook.NewRegistry()}
+	registries.Webhooks.Register(webhook.Webhook{URL: "http://example.com/dropped"})
+	if _, err := Restore(strings.NewReader(buf.String()), target, registries); err != nil {
+		t.Fatal(err)
+	}
+	events := target.GetEvent()
+	if len(events) != 1 || events[0].Title != "standup" || !events[0].End.Equal(end) || len(target.GetCalendars()) != 1 {
+		t.Errorf("restored %+v", events)
+	}
+	if page, ok := registries.Bookings.Get("intro"); !ok || page.SlotMinutes != 30 {
+		t.Errorf("restored booking page %+v, %t", page, ok)
+	}
+	if weekStart, ok := registries.Preferences.WeekStart("ann"); !ok || weekStart != time.Monday {
+		t.Errorf("restored week start %v, %t", weekStart, ok)
+	}
+	if webhooks := registries.Webhooks.List(); len(webhooks) != 1 || webhooks[0].ID != hook.ID || webhooks[0].Secret != "s3cret" || webhooks[0].User != "ann" {
+		t.Errorf("restored webhooks %+v, want %+v", webhooks, hook)
+	}
+}
+
+func TestRestoreRejectsInvalidPreferences(t *testing.T) {
+	storage := service.NewInMemoryStorage()
+	storage.Restore([]service.Event{{ID: "e1", Title: "kept", Date: time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)}})
+	registries := Registries{Preferences: preference.NewRegistry()}
+	registries.Preferences.SetWeekStart("ann", time.Monday)
+
+	_, err := Restore(strings.NewReader(`{"version": 3, "events": [], "preferences": [{"user": "bob", "week_start": "someday"}]}`), storage, registries)
+	if err == nil {
+		t.Fatal("expected an error for an unknown week start")
+	}
+	if len(storage.GetEvent()) != 1 {
+		t.Error("a rejected snapshot replaced the events")
+	}
+	if _, ok := registries.Preferences.WeekStart("ann"); !ok {
+		t.Error("a rejected snapshot replaced the preferences")
+	}
+}
+
+func TestTakeReadsStorageOnce(t *testing.T) {
+	snap := Take(dumpOnly{}, Registries{})
+	if len(snap.Calendars) != 1 || len(snap.Events) != 1 || snap.Webhooks == nil {
+		t.Errorf("took %+v", snap)
+	}
+}
+
+// dumpOnly panics if Take reads the calendars or events on their own.
+type dumpOnly struct {
+	service.Storage
+}
+
+func (dumpOnly) Dump() ([]service.Calendar, []service.Event) {
+	return []service.Calendar{{ID: "c1"}}, []service.Event{{ID: "e1", CalendarID: "c1"}}
+}
@@ -0,0 +1,191 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type ClientAuth string
+
+const (
+	ClientAuthNone    ClientAuth = "none"
+	ClientAuthRequest ClientAuth = "request"
+	ClientAuthRequire ClientAuth = "require"
+)
+
+type Options struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   ClientAuth
+}
+
+// Reloader serves the current certificate and client CA pool and swaps
+// them atomically on Reload, so renewed certificates are picked up
+// without restarting the server.
+type Reloader struct {
+	options Options
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+func ParseClientAuth(value string) (ClientAuth, error) {
+	switch ClientAuth(value) {
+	case "", ClientAuthNone:
+		return ClientAuthNone, nil
+	case ClientAuthRequest, ClientAuthRequire:
+		return ClientAuth(value), nil
+	}
+	return ClientAuthNone, fmt.Errorf("invalid client auth %q, expected none, request or require", value)
+}
+
+func NewReloader(options Options) (*Reloader, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, errors.New("both certificate and key files are required for TLS")
+	}
+	if options.ClientAuth == "" {
+		options.ClientAuth = ClientAuthNone
+	}
+	if options.ClientAuth != ClientAuthNone && options.ClientCAFile == "" {
+		return nil, errors.New("client authentication requires a client CA file")
+	}
+
+	rl := &Reloader{options: options}
+	if err := rl.Reload(); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
+func (rl *Reloader) files() []string {
+	files := []string{rl.options.CertFile, rl.options.KeyFile}
+	if rl.options.ClientCAFile != "" {
+		files = append(files, rl.options.ClientCAFile)
+	}
+	return files
+}
+
+func (rl *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(rl.options.CertFile, rl.options.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if rl.options.ClientCAFile != "" {
+		pem, err := os.ReadFile(rl.options.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA %s", rl.options.ClientCAFile)
+		}
+	}
+
+	modTimes := make(map[string]time.Time)
+	for _, file := range rl.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+
+	rl.mu.Lock()
+	rl.cert = &cert
+	rl.clientCAs = clientCAs
+	rl.modTimes = modTimes
+	rl.mu.Unlock()
+	return nil
+}
+
+func (rl *Reloader) changed() bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	for _, file := range rl.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(rl.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the certificates whenever one of the files changes,
+// checking every interval until stop is closed.
+func (rl *Reloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !rl.changed() {
+				continue
+			}
+			if err := rl.Reload(); err != nil {
+				log.Printf("Failed to reload TLS certificates: %v", err)
+			} else {
+				log.Printf("Reloaded TLS certificates")
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (rl *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			rl.mu.RLock()
+			defer rl.mu.RUnlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*rl.cert},
+				ClientCAs:    rl.clientCAs,
+			}
+			switch rl.options.ClientAuth {
+			case ClientAuthRequest:
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			case ClientAuthRequire:
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
+
+// RedirectHandler sends plain HTTP requests to the same path over HTTPS
+// on httpsPort.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
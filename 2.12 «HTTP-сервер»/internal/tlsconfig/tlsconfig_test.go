@@ -0,0 +1,324 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *authority) issue(t *testing.T, name string, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fixture struct {
+	ca       *authority
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+func newFixture(t *testing.T) *fixture {
+	dir := t.TempDir()
+	f := &fixture{
+		ca:       newAuthority(t),
+		certFile: filepath.Join(dir, "server.crt"),
+		keyFile:  filepath.Join(dir, "server.key"),
+		caFile:   filepath.Join(dir, "ca.crt"),
+	}
+	f.issueServer(t, 2)
+	writeFile(t, f.caFile, f.ca.pem)
+	return f
+}
+
+func (f *fixture) issueServer(t *testing.T, serial int64) {
+	certPEM, keyPEM := f.ca.issue(t, "localhost", serial, x509.ExtKeyUsageServerAuth)
+	writeFile(t, f.certFile, certPEM)
+	writeFile(t, f.keyFile, keyPEM)
+}
+
+func startServer(t *testing.T, rl *Reloader) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			name = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		w.Write([]byte(name))
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = rl.TLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(ca *authority, certs ...tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func servedSerial(t *testing.T, client *http.Client, url string) int64 {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestHTTP2(t *testing.T) {
+	f := newFixture(t)
+	rl, err := NewReloader(Options{CertFile: f.certFile, KeyFile: f.keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := startServer(t, rl)
+
+	resp, err := newClient(f.ca).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+}
+
+func TestReload(t *testing.T) {
+	f := newFixture(t)
+	rl, err := NewReloader(Options{CertFile: f.certFile, KeyFile: f.keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := startServer(t, rl)
+
+	if serial := servedSerial(t, newClient(f.ca), server.URL); serial != 2 {
+		t.Fatalf("serial = %d, want 2", serial)
+	}
+
+	f.issueServer(t, 3)
+	if err := rl.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if serial := servedSerial(t, newClient(f.ca), server.URL); serial != 3 {
+		t.Errorf("serial after reload = %d, want 3", serial)
+	}
+
+	writeFile(t, f.certFile, []byte("not a certificate"))
+	if err := rl.Reload(); err == nil {
+		t.Error("expected error for an invalid certificate")
+	}
+	if serial := servedSerial(t, newClient(f.ca), server.URL); serial != 3 {
+		t.Errorf("serial after failed reload = %d, want 3", serial)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	f := newFixture(t)
+	rl, err := NewReloader(Options{CertFile: f.certFile, KeyFile: f.keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := startServer(t, rl)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go rl.Watch(10*time.Millisecond, stop)
+
+	f.issueServer(t, 4)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{f.certFile, f.keyFile} {
+		if err := os.Chtimes(file, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for servedSerial(t, newClient(f.ca), server.URL) != 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate change was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	f := newFixture(t)
+	clientPEM, clientKey := f.ca.issue(t, "billing-service", 10, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger := newAuthority(t)
+	strangerPEM, strangerKey := stranger.issue(t, "stranger", 11, x509.ExtKeyUsageClientAuth)
+	strangerCert, err := tls.X509KeyPair(strangerPEM, strangerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode    ClientAuth
+		certs   []tls.Certificate
+		want    string
+		wantErr bool
+	}{
+		{ClientAuthRequire, []tls.Certificate{clientCert}, "billing-service", false},
+		{ClientAuthRequire, nil, "", true},
+		{ClientAuthRequire, []tls.Certificate{strangerCert}, "", true},
+		{ClientAuthRequest, nil, "", false},
+		{ClientAuthRequest, []tls.Certificate{clientCert}, "billing-service", false},
+		{ClientAuthRequest, []tls.Certificate{strangerCert}, "", true},
+	}
+
+	for _, tt := range tests {
+		rl, err := NewReloader(Options{CertFile: f.certFile, KeyFile: f.keyFile, ClientCAFile: f.caFile, ClientAuth: tt.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := startServer(t, rl)
+
+		resp, err := newClient(f.ca, tt.certs...).Get(server.URL)
+		if tt.wantErr {
+			if err == nil {
+				resp.Body.Close()
+				t.Errorf("%s with %d certs: expected handshake error", tt.mode, len(tt.certs))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s with %d certs: %v", tt.mode, len(tt.certs), err)
+			continue
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		resp.Body.Close()
+		if got := string(body[:n]); got != tt.want {
+			t.Errorf("%s with %d certs: client = %q, want %q", tt.mode, len(tt.certs), got, tt.want)
+		}
+	}
+}
+
+func TestNewReloaderErrors(t *testing.T) {
+	f := newFixture(t)
+
+	if _, err := NewReloader(Options{CertFile: f.certFile}); err == nil {
+		t.Error("expected error without key file")
+	}
+	if _, err := NewReloader(Options{CertFile: f.certFile, KeyFile: f.keyFile, ClientAuth: ClientAuthRequire}); err == nil {
+		t.Error("expected error for client auth without CA")
+	}
+	if _, err := ParseClientAuth("optional"); err == nil {
+		t.Error("expected error for unknown client auth mode")
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port   string
+		host   string
+		target string
+		want   string
+	}{
+		{"8443", "example.com:8080", "/events_for_day?date=2024-01-01", "https://example.com:8443/events_for_day?date=2024-01-01"},
+		{"443", "example.com", "/docs", "https://example.com/docs"},
+		{"8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: status = %d, want %d", tt.target, rec.Code, http.StatusPermanentRedirect)
+		}
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s: Location = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
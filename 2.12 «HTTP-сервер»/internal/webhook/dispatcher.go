@@ -0,0 +1,354 @@
+package webhook
+
+import (
+	"bytes"
+	"calendar/internal/service"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-Calendar-Signature"
+	EventHeader     = "X-Calendar-Event"
+	DeliveryHeader  = "X-Calendar-Delivery"
+)
+
+// queueSize is the number of deliveries waiting for one webhook. When its
+// queue is full, new deliveries go straight to the dead letters so that a
+// slow receiver never blocks the storage calls that produce changes.
+const queueSize = 100
+
+var errQueueFull = errors.New("delivery queue is full")
+var errStopped = errors.New("dispatcher stopped before the retry")
+
+type delivery struct {
+	webhook Webhook
+	payload Payload
+	attempt int
+}
+
+// pendingRetry is a delivery waiting out its backoff.
+type pendingRetry struct {
+	timer    *time.Timer
+	delivery delivery
+}
+
+// queue holds the deliveries of one webhook and the retries waiting for
+// their turn. removed is closed and ctx cancelled when the webhook is
+// deleted, which also aborts a delivery in flight.
+type queue struct {
+	deliveries chan delivery
+	retries    map[*pendingRetry]struct{}
+	removed    chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// Dispatcher delivers payloads with one queue and one worker per webhook,
+// so an unreachable receiver only delays its own deliveries.
+type Dispatcher struct {
+	// Visible reports whether user may see the event. Webhooks registered
+	// for a user only get changes of the events it lets through.
+	Visible func(user string, event service.Event) bool
+
+	registry    *Registry
+	client      *http.Client
+	done        chan struct{}
+	stopOnce    sync.Once
+	workers     sync.WaitGroup
+	maxAttempts int
+	baseDelay   time.Duration
+
+	mu          sync.Mutex
+	started     bool
+	queues      map[string]*queue
+	deadLetters []DeadLetter
+}
+
+func NewDispatcher(registry *Registry, maxAttempts int, baseDelay time.Duration) *Dispatcher {
+	return &Dispatcher{
+		registry:    registry,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		done:        make(chan struct{}),
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		queues:      make(map[string]*queue),
+	}
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Start runs the workers of the queues filled so far; queues created later
+// get their worker right away.
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.started = true
+	for _, q := range d.queues {
+		d.startWorker(q)
+	}
+}
+
+// Stop stops taking changes, delivers what is already queued and waits for
+// the workers to finish. Retries still waiting for their turn, and
+// deliveries that fail while draining, go to the dead letters.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		d.mu.Lock()
+		close(d.done)
+		for _, q := range d.queues {
+			for retry := range q.retries {
+				retry.timer.Stop()
+				dl := retry.delivery
+				dl.attempt-- // the retry was never sent
+				d.addDeadLetter(dl, errStopped)
+			}
+			q.retries = nil
+		}
+		d.mu.Unlock()
+
+		d.workers.Wait()
+	})
+}
+
+// Remove drops the queue of a deleted webhook: queued deliveries and
+// scheduled retries are discarded, a delivery in flight is aborted and the
+// worker exits.
+func (d *Dispatcher) Remove(webhookID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	q, ok := d.queues[webhookID]
+	if !ok {
+		return
+	}
+	delete(d.queues, webhookID)
+	for retry := range q.retries {
+		retry.timer.Stop()
+	}
+	q.retries = nil
+	close(q.removed)
+	q.cancel()
+}
+
+func (d *Dispatcher) HandleChange(change service.Change) {
+	payload := Payload{
+		ID:        uuid.New().String(),
+		Type:      change.Type,
+		Event:     change.Event,
+		Timestamp: time.Now().UTC(),
+	}
+
+	for _, wh := range d.registry.List() {
+		if !wh.Accepts(change.Type) {
+			continue
+		}
+		if wh.User != "" && d.Visible != nil && !d.Visible(wh.User, change.Event) {
+			continue
+		}
+		d.enqueue(delivery{webhook: wh, payload: payload, attempt: 1})
+	}
+}
+
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deadLetters := make([]DeadLetter, len(d.deadLetters))
+	copy(deadLetters, d.deadLetters)
+	return deadLetters
+}
+
+func (d *Dispatcher) stopping() bool {
+	select {
+	case <-d.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue never blocks. Changes that arrive after Stop, or for a webhook
+// deleted since the registry was listed, are dropped.
+func (d *Dispatcher) enqueue(dl delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopping() {
+		return
+	}
+	if _, ok := d.registry.Get(dl.webhook.ID); !ok {
+		return
+	}
+	d.push(d.queueFor(dl.webhook.ID), dl)
+}
+
+// push dead-letters a delivery that does not fit into the queue instead of
+// waiting. d.mu must be held.
+func (d *Dispatcher) push(q *queue, dl delivery) {
+	select {
+	case q.deliveries <- dl:
+	default:
+		log.Printf("Webhook %s dropped delivery %s: %v", dl.webhook.URL, dl.payload.ID, errQueueFull)
+		dl.attempt-- // the dropped attempt was never sent
+		d.addDeadLetter(dl, errQueueFull)
+	}
+}
+
+// queueFor returns the queue of the webhook, creating it on first use.
+// d.mu must be held.
+func (d *Dispatcher) queueFor(webhookID string) *queue {
+	q, ok := d.queues[webhookID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		q = &queue{
+			deliveries: make(chan delivery, queueSize),
+			retries:    make(map[*pendingRetry]struct{}),
+			removed:    make(chan struct{}),
+			ctx:        ctx,
+			cancel:     cancel,
+		}
+		d.queues[webhookID] = q
+		if d.started {
+			d.startWorker(q)
+		}
+	}
+	return q
+}
+
+func (d *Dispatcher) startWorker(q *queue) {
+	d.workers.Add(1)
+	go d.run(q)
+}
+
+func (d *Dispatcher) run(q *queue) {
+	defer d.workers.Done()
+
+	for {
+		select {
+		case dl := <-q.deliveries:
+			d.deliver(q, dl)
+		case <-q.removed:
+			return
+		case <-d.done:
+			d.drain(q)
+			return
+		}
+	}
+}
+
+// drain delivers what is left in the queue once the dispatcher stops.
+func (d *Dispatcher) drain(q *queue) {
+	for {
+		select {
+		case dl := <-q.deliveries:
+			d.deliver(q, dl)
+		case <-q.removed:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(q *queue, dl delivery) {
+	err := d.send(q.ctx, dl)
+	if err == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	select {
+	case <-q.removed:
+		return
+	default:
+	}
+	if dl.attempt >= d.maxAttempts || d.stopping() {
+		log.Printf("Webhook %s gave up after %d attempts: %v", dl.webhook.URL, dl.attempt, err)
+		d.addDeadLetter(dl, err)
+		return
+	}
+
+	delay := d.baseDelay << (dl.attempt - 1)
+	dl.attempt++
+	retry := &pendingRetry{delivery: dl}
+	retry.timer = time.AfterFunc(delay, func() {
+		d.retry(q, retry)
+	})
+	q.retries[retry] = struct{}{}
+}
+
+// retry queues a delivery whose backoff is over, unless Stop or Remove
+// took it back in the meantime.
+func (d *Dispatcher) retry(q *queue, retry *pendingRetry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := q.retries[retry]; !ok {
+		return
+	}
+	delete(q.retries, retry)
+	d.push(q, retry.delivery)
+}
+
+// addDeadLetter records a delivery that will not be retried. d.mu must be
+// held.
+func (d *Dispatcher) addDeadLetter(dl delivery, err error) {
+	d.deadLetters = append(d.deadLetters, DeadLetter{
+		WebhookID: dl.webhook.ID,
+		URL:       dl.webhook.URL,
+		Payload:   dl.payload,
+		Attempts:  dl.attempt,
+		LastError: err.Error(),
+		FailedAt:  time.Now().UTC(),
+	})
+}
+
+func (d *Dispatcher) send(ctx context.Context, dl delivery) error {
+	body, err := json.Marshal(dl.payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(dl.webhook.Secret, body))
+	req.Header.Set(EventHeader, string(dl.payload.Type))
+	req.Header.Set(DeliveryHeader, dl.payload.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
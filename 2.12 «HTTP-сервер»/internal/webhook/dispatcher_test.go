@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"calendar/internal/service"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDispatcher(t *testing.T, maxAttempts int) (*Registry, *Dispatcher, *service.InMemoryStorage) {
+	registry := NewRegistry()
+	dispatcher := NewDispatcher(registry, maxAttempts, time.Millisecond)
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+
+	storage := service.NewInMemoryStorage()
+	storage.Subscribe(dispatcher.HandleChange)
+	return registry, dispatcher, storage
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	received := make(chan Payload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("secret", body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload Payload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+	}))
+	defer receiver.Close()
+
+	registry, _, storage := newTestDispatcher(t, 3)
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret", EventTypes: []service.ChangeType{service.EventDeleted}})
+
+	storage.CreateEvent(service.Event{Title: "standup", Date: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)})
+	events := storage.GetEvent()
+	storage.DeleteEvent(events[0].ID)
+
+	select {
+	case payload := <-received:
+		if payload.Type != service.EventDeleted || payload.Event.Title != "standup" {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	select {
+	case payload := <-received:
+		t.Errorf("filtered event type was delivered: %+v", payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	delivered := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		close(delivered)
+	}))
+	defer receiver.Close()
+
+	registry, dispatcher, storage := newTestDispatcher(t, 5)
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret"})
+	storage.CreateEvent(service.Event{Title: "retro"})
+
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("webhook was not delivered after retries, calls: %d", calls.Load())
+	}
+	if len(dispatcher.DeadLetters()) != 0 {
+		t.Errorf("expected no dead letters, got %v", dispatcher.DeadLetters())
+	}
+}
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	registry, dispatcher, storage := newTestDispatcher(t, 3)
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret"})
+	storage.CreateEvent(service.Event{Title: "planning"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(dispatcher.DeadLetters()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery was not moved to dead letters")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	deadLetter := dispatcher.DeadLetters()[0]
+	if deadLetter.Attempts != 3 || deadLetter.Payload.Type != service.EventCreated {
+		t.Errorf("unexpected dead letter: %+v", deadLetter)
+	}
+}
+
+func TestDispatcherSlowReceiverDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	var fastCalls atomic.Int32
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastCalls.Add(1)
+	}))
+	defer fast.Close()
+
+	registry, dispatcher, storage := newTestDispatcher(t, 1)
+	slowHook := registry.Register(Webhook{URL: slow.URL, Secret: "secret"})
+	registry.Register(Webhook{URL: fast.URL, Secret: "secret"})
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	const changes = queueSize + 20
+	created := make(chan struct{})
+	go func() {
+		for i := 0; i < changes; i++ {
+			storage.CreateEvent(service.Event{Title: "standup"})
+		}
+		close(created)
+	}()
+	select {
+	case <-created:
+	case <-time.After(2 * time.Second):
+		t.Fatal("storage calls blocked on a slow webhook receiver")
+	}
+
+	// Every delivery to the fast receiver is either sent or, if the burst
+	// outran its own queue, dead-lettered; none wait for the slow one.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		slowDropped, fastDropped := 0, 0
+		for _, deadLetter := range dispatcher.DeadLetters() {
+			if deadLetter.LastError != errQueueFull.Error() || deadLetter.Attempts != 0 {
+				t.Fatalf("unexpected dead letter: %+v", deadLetter)
+			}
+			if deadLetter.WebhookID == slowHook.ID {
+				slowDropped++
+			} else {
+				fastDropped++
+			}
+		}
+		if slowDropped > 0 && int(fastCalls.Load())+fastDropped == changes {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("fast receiver got %d deliveries and dropped %d of %d, slow dropped %d", fastCalls.Load(), fastDropped, changes, slowDropped)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherRemoveCancelsRetries(t *testing.T) {
+	var calls atomic.Int32
+	failed := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	registry := NewRegistry()
+	dispatcher := NewDispatcher(registry, 5, 50*time.Millisecond)
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+	storage := service.NewInMemoryStorage()
+	storage.Subscribe(dispatcher.HandleChange)
+
+	hook := registry.Register(Webhook{URL: receiver.URL, Secret: "secret"})
+	storage.CreateEvent(service.Event{Title: "retro"})
+	select {
+	case <-failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	registry.Delete(hook.ID)
+	dispatcher.Remove(hook.ID)
+	storage.CreateEvent(service.Event{Title: "planning"})
+	time.Sleep(200 * time.Millisecond)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("deleted webhook got %d deliveries, want 1", got)
+	}
+	dispatcher.mu.Lock()
+	queues := len(dispatcher.queues)
+	dispatcher.mu.Unlock()
+	if queues != 0 || len(dispatcher.DeadLetters()) != 0 {
+		t.Errorf("deleted webhook left %d queues and dead letters %v", queues, dispatcher.DeadLetters())
+	}
+}
+
+func TestDispatcherStopDrainsQueues(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	registry, dispatcher, storage := newTestDispatcher(t, 3)
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret"})
+	const changes = 20
+	for i := 0; i < changes; i++ {
+		storage.CreateEvent(service.Event{Title: "standup"})
+	}
+	dispatcher.Stop()
+
+	if got := calls.Load(); got != changes {
+		t.Errorf("Stop returned after %d of %d deliveries", got, changes)
+	}
+	storage.CreateEvent(service.Event{Title: "late"})
+	time.Sleep(20 * time.Millisecond)
+	if got := calls.Load(); got != changes {
+		t.Errorf("change after Stop was delivered")
+	}
+}
+
+func TestDispatcherStopDeadLettersPendingRetries(t *testing.T) {
+	failed := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	registry := NewRegistry()
+	dispatcher := NewDispatcher(registry, 5, time.Hour)
+	dispatcher.Start()
+	storage := service.NewInMemoryStorage()
+	storage.Subscribe(dispatcher.HandleChange)
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret"})
+
+	storage.CreateEvent(service.Event{Title: "retro"})
+	select {
+	case <-failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		dispatcher.mu.Lock()
+		retries := 0
+		for _, q := range dispatcher.queues {
+			retries += len(q.retries)
+		}
+		dispatcher.mu.Unlock()
+		if retries == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("retry was not scheduled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	dispatcher.Stop()
+	deadLetters := dispatcher.DeadLetters()
+	if len(deadLetters) != 1 || deadLetters[0].Attempts != 1 || deadLetters[0].LastError != errStopped.Error() {
+		t.Errorf("unexpected dead letters %+v", deadLetters)
+	}
+}
+
+func TestDispatcherLimitsUserWebhooksToVisibleEvents(t *testing.T) {
+	received := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload.Event.Title
+	}))
+	defer receiver.Close()
+
+	registry, dispatcher, storage := newTestDispatcher(t, 1)
+	dispatcher.Visible = func(user string, event service.Event) bool {
+		return user == "ann" || event.CalendarID == ""
+	}
+	registry.Register(Webhook{URL: receiver.URL, Secret: "secret", User: "bob"})
+
+	storage.CreateEvent(service.Event{Title: "private", CalendarID: "anns"})
+	storage.CreateEvent(service.Event{Title: "public"})
+	select {
+	case title := <-received:
+		if title != "public" {
+			t.Errorf("webhook of bob got %q", title)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	select {
+	case title := <-received:
+		t.Errorf("webhook of bob got %q", title)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"calendar/internal/service"
+	"time"
+)
+
+type Webhook struct {
+	ID         string               `json:"id"`
+	URL        string               `json:"url"`
+	Secret     string               `json:"-"`
+	EventTypes []service.ChangeType `json:"event_types,omitempty"`
+	// User limits deliveries to events of calendars the user may see;
+	// without one the webhook gets every change.
+	User string `json:"user,omitempty"`
+}
+
+type Payload struct {
+	ID        string             `json:"id"`
+	Type      service.ChangeType `json:"type"`
+	Event     service.Event      `json:"event"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+type DeadLetter struct {
+	WebhookID string    `json:"webhook_id"`
+	URL       string    `json:"url"`
+	Payload   Payload   `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+func (wh Webhook) Accepts(changeType service.ChangeType) bool {
+	if len(wh.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range wh.EventTypes {
+		if t == changeType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type Registry struct {
+	mu       sync.Mutex
+	webhooks map[string]Webhook
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		webhooks: make(map[string]Webhook),
+	}
+}
+
+func (rg *Registry) Register(wh Webhook) Webhook {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	wh.ID = uuid.New().String()
+	rg.webhooks[wh.ID] = wh
+	return wh
+}
+
+func (rg *Registry) Get(id string) (Webhook, bool) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	wh, ok := rg.webhooks[id]
+	return wh, ok
+}
+
+func (rg *Registry) Delete(id string) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	_, exists := rg.webhooks[id]
+	if exists {
+		delete(rg.webhooks, id)
+	}
+	return exists
+}
+
+func (rg *Registry) List() []Webhook {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	webhooks := make([]Webhook, 0, len(rg.webhooks))
+	for _, wh := range rg.webhooks {
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks
+}
+
+// Restore replaces all webhooks and returns the IDs of the ones it dropped.
+func (rg *Registry) Restore(webhooks []Webhook) []string {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	previous := rg.webhooks
+	rg.webhooks = make(map[string]Webhook, len(webhooks))
+	for _, wh := range webhooks {
+		if wh.ID == "" {
+			wh.ID = uuid.New().String()
+		}
+		rg.webhooks[wh.ID] = wh
+	}
+	var dropped []string
+	for id := range previous {
+		if _, kept := rg.webhooks[id]; !kept {
+			dropped = append(dropped, id)
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// localeFromEnv возвращает локаль сравнения из окружения в порядке POSIX:
+// LC_ALL, затем LC_COLLATE, затем LANG.
+func localeFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseLocale разбирает имя локали вида "ru_RU.UTF-8", "ru-RU" или "en".
+// Кодировка и модификатор после "@" отбрасываются. Для "", "C" и "POSIX"
+// возвращает false: строки сравниваются побайтно.
+func parseLocale(name string) (language.Tag, bool, error) {
+	base, _, _ := strings.Cut(name, "@")
+	base, _, _ = strings.Cut(base, ".")
+	switch base {
+	case "", "C", "POSIX":
+		return language.Und, false, nil
+	}
+	tag, err := language.Parse(strings.ReplaceAll(base, "_", "-"))
+	if err != nil {
+		return language.Und, false, fmt.Errorf("invalid locale '%s'", name)
+	}
+	return tag, true, nil
+}
+
+// textComparator возвращает сравнение текста для локали: по правилам
+// Unicode Collation Algorithm с таблицами языка локали или побайтно для C.
+// С foldCase регистр не учитывается.
+func textComparator(locale string, foldCase bool) compareFunc {
+	tag, collated, err := parseLocale(locale)
+	switch {
+	case (err != nil || !collated) && foldCase:
+		return compareFolded
+	case err != nil || !collated:
+		return strings.Compare
+	}
+
+	var options []collate.Option
+	if foldCase {
+		options = append(options, collate.IgnoreCase)
+	}
+	// Collator хранит буферы и не годится для нескольких горутин сразу,
+	// поэтому при параллельной сортировке у каждого потока свой экземпляр.
+	collators := &sync.Pool{New: func() any { return collate.New(tag, options...) }}
+	return func(a, b string) int {
+		collator := collators.Get().(*collate.Collator)
+		defer collators.Put(collator)
+		return collator.CompareString(a, b)
+	}
+}
+
+// filter убирает из ключа символы, которые не сравниваются: с -d всё, кроме
+// букв, цифр и пробелов, с -i — непечатаемые символы.
+func (o KeyOptions) filter(s string) string {
+	if !o.Dictionary && !o.Nonprinting {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if o.Dictionary && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '\t' {
+			return -1
+		}
+		if o.Nonprinting && !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
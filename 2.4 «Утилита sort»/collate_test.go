@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedTag      string
+		expectedCollated bool
+		expectedError    bool
+	}{
+		{"", "und", false, false},
+		{"C", "und", false, false},
+		{"POSIX", "und", false, false},
+		{"C.UTF-8", "und", false, false},
+		{"ru_RU.UTF-8", "ru-RU", true, false},
+		{"de_DE@euro", "de-DE", true, false},
+		{"en", "en", true, false},
+		{"ru_RU!", "und", false, true},
+	}
+
+	for _, test := range tests {
+		tag, collated, err := parseLocale(test.input)
+		if tag.String() != test.expectedTag || collated != test.expectedCollated || (err != nil) != test.expectedError {
+			t.Errorf("For input %q, expected %s, %t and error %t, but got %s, %t and error %v", test.input, test.expectedTag, test.expectedCollated, test.expectedError, tag, collated, err)
+		}
+	}
+}
+
+func TestLocaleCollation(t *testing.T) {
+	words := []string{"ёж", "Ель", "еда", "жук", "Яблоко", "апельсин", "banana", "Apple", "ель"}
+	tests := []struct {
+		input          []string
+		flags          SortFlags
+		expectedOutput []string
+	}{
+		// Побайтно заглавные буквы идут раньше строчных, а «ё» — после «я».
+		{words, SortFlags{}, []string{"Apple", "banana", "Ель", "Яблоко", "апельсин", "еда", "ель", "жук", "ёж"}},
+		{words, SortFlags{Locale: "ru_RU.UTF-8"}, []string{"Apple", "banana", "апельсин", "еда", "ёж", "ель", "Ель", "жук", "Яблоко"}},
+		// С -f строки, различающиеся только регистром, равны, и -u оставляет
+		// первую из них во входе.
+		{[]string{"Ель", "ель", "ЁЖ"}, SortFlags{Locale: "ru", FoldCase: true, Unique: true}, []string{"ЁЖ", "Ель"}},
+		{[]string{"2 Ель", "1 еда", "3 ёж"}, SortFlags{Keys: keys("2"), Locale: "ru-RU"}, []string{"1 еда", "3 ёж", "2 Ель"}},
+		{[]string{"b-c", "a_d", "(b)b"}, SortFlags{Dictionary: true}, []string{"a_d", "(b)b", "b-c"}},
+		{[]string{"b\x01a", "ba", "a\x7fz"}, SortFlags{Nonprinting: true}, []string{"a\x7fz", "b\x01a", "ba"}},
+		{[]string{"x «ель»", "y ёж!", "z еда"}, SortFlags{Keys: keys("2d"), Locale: "ru_RU"}, []string{"z еда", "y ёж!", "x «ель»"}},
+	}
+
+	for _, test := range tests {
+		for _, workers := range []int{1, 4} {
+			test.flags.Parallel = workers
+			output := sortLinesByKey(test.input, test.flags)
+			if !reflect.DeepEqual(output, test.expectedOutput) {
+				t.Errorf("For input %q with %+v, expected %q, but got %q", test.input, test.flags, test.expectedOutput, output)
+			}
+		}
+	}
+}
+
+func TestLocaleFromEnv(t *testing.T) {
+	tests := []struct {
+		env            map[string]string
+		args           []string
+		expectedOutput string
+	}{
+		{map[string]string{"LC_ALL": "", "LC_COLLATE": "", "LANG": "ru_RU.UTF-8"}, nil, "еда\nёж\nЕль\n"},
+		{map[string]string{"LC_ALL": "", "LC_COLLATE": "ru_RU.UTF-8", "LANG": "C"}, nil, "еда\nёж\nЕль\n"},
+		{map[string]string{"LC_ALL": "C", "LC_COLLATE": "ru_RU.UTF-8", "LANG": ""}, nil, "Ель\nеда\nёж\n"},
+		{map[string]string{"LC_ALL": "not a locale!", "LC_COLLATE": "", "LANG": ""}, nil, "Ель\nеда\nёж\n"},
+		{map[string]string{"LC_ALL": "C", "LC_COLLATE": "", "LANG": ""}, []string{"--locale=ru"}, "еда\nёж\nЕль\n"},
+	}
+
+	for _, test := range tests {
+		for name, value := range test.env {
+			t.Setenv(name, value)
+		}
+		var stdout, stderr bytes.Buffer
+		if code := run(test.args, strings.NewReader("ёж\nЕль\nеда\n"), &stdout, &stderr); code != exitOK || stdout.String() != test.expectedOutput {
+			t.Errorf("For env %v and args %q, expected %q, but got code %d, output %q and error %q", test.env, test.args, test.expectedOutput, code, stdout.String(), stderr.String())
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// compareFunc сравнивает две строки и возвращает отрицательное число, ноль
+// или положительное число, как strings.Compare.
+type compareFunc func(a, b string) int
+
+// sortLinesByKey сортирует строки согласно флагам. Ключи -k сравниваются по
+// очереди, каждый со своими модификаторами; строки с равными ключами
+// сравниваются целиком, как в POSIX sort. С -u из каждой группы равных
+// ключей остаётся первая по входу строка. Исходный срез не изменяется.
+func sortLinesByKey(lines []string, flags SortFlags) []string {
+	result := make([]string, len(lines))
+	copy(result, lines)
+	return sortChunk(result, flags)
+}
+
+// sortChunk сортирует строки на месте, как sortLinesByKey, и возвращает
+// срез без повторов для -u.
+func sortChunk(lines []string, flags SortFlags) []string {
+	workers := flags.Parallel
+	if workers <= 0 {
+		workers = defaultParallel()
+	}
+	sortStable(lines, orderComparator(flags), workers)
+
+	if flags.Unique {
+		lines = uniqueLines(lines, keyComparator(flags))
+	}
+	return lines
+}
+
+// uniqueLines удаляет из отсортированного среза строки, ключ которых равен
+// ключу предыдущей строки.
+func uniqueLines(lines []string, compare compareFunc) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	unique := lines[:1]
+	for _, line := range lines[1:] {
+		if compare(unique[len(unique)-1], line) != 0 {
+			unique = append(unique, line)
+		}
+	}
+	return unique
+}
+
+// orderComparator возвращает сравнение, задающее порядок вывода. С -u, как в
+// GNU sort, строки сравниваются только по ключам: устойчивая сортировка
+// оставляет равные строки в порядке входа, и uniqueLines сохраняет первую.
+func orderComparator(flags SortFlags) compareFunc {
+	if flags.Unique {
+		return keyComparator(flags)
+	}
+	return lineComparator(flags)
+}
+
+// lineComparator сравнивает строки по ключам, а при равенстве всех ключей —
+// целиком по правилам локали и затем побайтно (в обратном порядке с
+// глобальным -r).
+func lineComparator(flags SortFlags) compareFunc {
+	compareKeys := keyComparator(flags)
+	compareText := textComparator(flags.Locale, false)
+	return func(a, b string) int {
+		if result := compareKeys(a, b); result != 0 {
+			return result
+		}
+		if flags.Reverse {
+			a, b = b, a
+		}
+		if result := compareText(a, b); result != 0 {
+			return result
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+// keyComparator сравнивает строки по цепочке ключей: следующий ключ
+// сравнивается, только если предыдущие равны.
+func keyComparator(flags SortFlags) compareFunc {
+	keys := flags.keys()
+	chain := make([]compareFunc, len(keys))
+	for i, key := range keys {
+		chain[i] = key.comparator(flags.Separator, flags.Locale)
+	}
+	if len(chain) == 1 {
+		return chain[0]
+	}
+
+	return func(a, b string) int {
+		for _, compare := range chain {
+			if result := compare(a, b); result != 0 {
+				return result
+			}
+		}
+		return 0
+	}
+}
+
+// comparator возвращает функцию, сравнивающую один ключ строк с учётом его
+// модификаторов; текст сравнивается по правилам локали locale.
+func (k KeySpec) comparator(separator, locale string) compareFunc {
+	compare := textComparator(locale, k.Options.FoldCase)
+	switch {
+	case k.Options.Month:
+		compare = compareMonths
+	case k.Options.Human:
+		compare = compareHuman
+	case k.Options.Numeric:
+		compare = compareNumeric
+	case k.Options.General:
+		compare = compareGeneral
+	case k.Options.Version:
+		compare = compareVersions
+	case k.Options.Natural:
+		compare = compareNatural
+	}
+	filtered := k.Options.Dictionary || k.Options.Nonprinting
+	if k.Start.Field == 0 && !k.Options.IgnoreBlanks && !k.Options.Reverse && !filtered {
+		return compare
+	}
+
+	return func(a, b string) int {
+		result := compare(k.Options.filter(k.extract(a, separator)), k.Options.filter(k.extract(b, separator)))
+		if k.Options.Reverse {
+			return -result
+		}
+		return result
+	}
+}
+
+// extract возвращает часть строки, которую сравнивает ключ. Если в строке
+// меньше полей, чем нужно, ключ короче или пуст, но сама строка сохраняется.
+func (k KeySpec) extract(line, separator string) string {
+	if k.Start.Field == 0 {
+		if k.Options.IgnoreBlanks {
+			return strings.TrimLeft(line, blanks)
+		}
+		return line
+	}
+
+	start := fieldStart(line, separator, k.Start.Field)
+	if k.Options.IgnoreBlanks {
+		start = skipBlanks(line, start)
+	}
+	start = advanceChars(line, start, k.Start.Char-1)
+
+	end := len(line)
+	if k.End.Field > 0 {
+		end = fieldStart(line, separator, k.End.Field)
+		switch {
+		case k.End.Char == 0:
+			end = fieldEnd(line, separator, end)
+		default:
+			if k.Options.IgnoreEndBlanks {
+				end = skipBlanks(line, end)
+			}
+			end = advanceChars(line, end, k.End.Char)
+		}
+	}
+
+	if start >= end {
+		return ""
+	}
+	return line[start:end]
+}
+
+// blanks — символы, разделяющие поля без -t.
+const blanks = " \t"
+
+// fieldStart возвращает смещение начала поля n (с 1) или длину строки, если
+// полей меньше. Без разделителя поле начинается с пробелов перед ним, как в
+// POSIX sort; с разделителем поле начинается сразу после него.
+func fieldStart(line, separator string, n int) int {
+	pos := 0
+	for i := 1; i < n && pos < len(line); i++ {
+		if separator == "" {
+			pos = skipNonBlanks(line, skipBlanks(line, pos))
+			continue
+		}
+		next := strings.Index(line[pos:], separator)
+		if next < 0 {
+			return len(line)
+		}
+		pos += next + len(separator)
+	}
+	return pos
+}
+
+// fieldEnd возвращает смещение конца поля, начинающегося с pos.
+func fieldEnd(line, separator string, pos int) int {
+	if separator == "" {
+		return skipNonBlanks(line, skipBlanks(line, pos))
+	}
+	if next := strings.Index(line[pos:], separator); next >= 0 {
+		return pos + next
+	}
+	return len(line)
+}
+
+func skipBlanks(line string, pos int) int {
+	for pos < len(line) && strings.IndexByte(blanks, line[pos]) >= 0 {
+		pos++
+	}
+	return pos
+}
+
+func skipNonBlanks(line string, pos int) int {
+	for pos < len(line) && strings.IndexByte(blanks, line[pos]) < 0 {
+		pos++
+	}
+	return pos
+}
+
+// advanceChars сдвигает смещение pos на n символов (рун), но не дальше
+// конца строки.
+func advanceChars(line string, pos, n int) int {
+	for ; n > 0 && pos < len(line); n-- {
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// compareFolded сравнивает строки, считая строчные буквы заглавными.
+func compareFolded(a, b string) int {
+	return strings.Compare(strings.ToUpper(a), strings.ToUpper(b))
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// defaultBufferSize — объём строк в памяти, после которого отсортированная
+	// часть входа сбрасывается во временный файл (-S по умолчанию).
+	defaultBufferSize = 256 << 20
+	// lineOverhead — примерный расход памяти на строку сверх её байтов:
+	// заголовок строки в срезе.
+	lineOverhead = 16
+	// mergeFanIn — сколько временных файлов сливается за один проход; при
+	// большем числе файлов слияние идёт в несколько проходов.
+	mergeFanIn = 64
+)
+
+// externalSorter сортирует вход, который может не поместиться в память:
+// части по bufferSize байт сортируются в памяти и записываются во временные
+// файлы (серии), а затем серии сливаются через кучу.
+type externalSorter struct {
+	flags      SortFlags
+	bufferSize int64
+	tempDir    string
+	runs       []string
+}
+
+func newExternalSorter(flags SortFlags) *externalSorter {
+	s := &externalSorter{flags: flags, bufferSize: flags.BufferSize, tempDir: flags.TempDir}
+	if s.bufferSize <= 0 {
+		s.bufferSize = defaultBufferSize
+	}
+	if s.tempDir == "" {
+		s.tempDir = os.TempDir()
+	}
+	return s
+}
+
+// sortFiles сортирует строки файлов и пишет результат в w. Если вход
+// помещается в буфер, временные файлы не создаются.
+func sortFiles(files []string, stdin io.Reader, w io.Writer, flags SortFlags) error {
+	s := newExternalSorter(flags)
+	defer s.cleanup()
+
+	var chunk []string
+	var size int64
+	err := forEachLine(files, stdin, func(line string) error {
+		chunk = append(chunk, line)
+		size += int64(len(line)) + lineOverhead
+		if size < s.bufferSize {
+			return nil
+		}
+		if err := s.spill(chunk); err != nil {
+			return err
+		}
+		chunk, size = chunk[:0], 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(s.runs) == 0 {
+		return writeLines(w, sortChunk(chunk, flags))
+	}
+	if len(chunk) > 0 {
+		if err := s.spill(chunk); err != nil {
+			return err
+		}
+	}
+	return s.merge(w)
+}
+
+// mergeFiles сливает уже отсортированные файлы (-m) в w, не сортируя их
+// заново. Если файлов больше mergeFanIn, группы входов сначала сливаются во
+// временные серии, чтобы не держать открытыми слишком много файлов.
+func mergeFiles(files []string, stdin io.Reader, w io.Writer, flags SortFlags) error {
+	s := newExternalSorter(flags)
+	defer s.cleanup()
+
+	if len(files) <= mergeFanIn {
+		return s.mergeRuns(files, stdin, flags.Verify, w)
+	}
+	for start := 0; start < len(files); start += mergeFanIn {
+		group := files[start:min(start+mergeFanIn, len(files))]
+		err := s.newRun(func(w io.Writer) error {
+			return s.mergeRuns(group, stdin, flags.Verify, w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.merge(w)
+}
+
+// spill сортирует часть входа и записывает её в новую серию.
+func (s *externalSorter) spill(chunk []string) error {
+	return s.newRun(func(w io.Writer) error {
+		return writeLines(w, sortChunk(chunk, s.flags))
+	})
+}
+
+// newRun создаёт временный файл, заполняет его функцией fill и добавляет в
+// конец списка серий.
+func (s *externalSorter) newRun(fill func(w io.Writer) error) error {
+	file, err := os.CreateTemp(s.tempDir, "sort-*")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, file.Name())
+
+	if err := fill(file); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", file.Name(), err)
+	}
+	return file.Close()
+}
+
+// merge сливает серии в w. Пока серий больше mergeFanIn, первые из них
+// сливаются в новую серию, которая занимает их место, поэтому порядок серий
+// и устойчивость сортировки сохраняются.
+func (s *externalSorter) merge(w io.Writer) error {
+	for len(s.runs) > mergeFanIn {
+		group := s.runs[:mergeFanIn]
+		rest := append([]string(nil), s.runs[mergeFanIn:]...)
+		s.runs = nil
+		if err := s.newRun(func(w io.Writer) error { return s.mergeRuns(group, nil, false, w) }); err != nil {
+			s.runs = append(s.runs, group...)
+			s.runs = append(s.runs, rest...)
+			return err
+		}
+		for _, name := range group {
+			os.Remove(name)
+		}
+		s.runs = append(s.runs, rest...)
+	}
+	return s.mergeRuns(s.runs, nil, false, w)
+}
+
+// mergeRuns сливает отсортированные файлы в w; "-" означает stdin. Из равных
+// строк первой идёт строка из более ранней серии. С -u серии упорядочиваются
+// только по ключам и номеру, поэтому из строк с равными ключами остаётся
+// строка из более ранней серии, как в GNU sort. С verify строка, нарушающая порядок своего файла,
+// прерывает слияние с ошибкой *disorderError.
+func (s *externalSorter) mergeRuns(names []string, stdin io.Reader, verify bool, w io.Writer) error {
+	compare := orderComparator(s.flags)
+	merger := &runHeap{compare: compare}
+	for i, name := range names {
+		input := stdin
+		if name != "-" {
+			file, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
+		}
+
+		run := &mergeRun{index: i, name: name, reader: bufio.NewReader(input)}
+		if verify {
+			run.check = compare
+		}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			merger.runs = append(merger.runs, run)
+		}
+	}
+	heap.Init(merger)
+
+	compareKeys := keyComparator(s.flags)
+	writer := bufio.NewWriter(w)
+	var last string
+	written := false
+	for merger.Len() > 0 {
+		run := merger.runs[0]
+		if !s.flags.Unique || !written || compareKeys(last, run.line) != 0 {
+			writer.WriteString(run.line)
+			writer.WriteByte('\n')
+			last, written = run.line, true
+		}
+
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(merger, 0)
+		} else {
+			heap.Pop(merger)
+		}
+	}
+	return writer.Flush()
+}
+
+// cleanup удаляет оставшиеся временные файлы.
+func (s *externalSorter) cleanup() {
+	for _, name := range s.runs {
+		os.Remove(name)
+	}
+	s.runs = nil
+}
+
+// mergeRun — серия при слиянии с её текущей строкой. Если задан check,
+// каждая строка сравнивается с предыдущей строкой той же серии.
+type mergeRun struct {
+	index  int
+	name   string
+	reader *bufio.Reader
+	line   string
+	number int
+	check  compareFunc
+}
+
+// next читает следующую строку серии; false означает конец серии.
+func (r *mergeRun) next() (bool, error) {
+	line, err := r.reader.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("read %s: %w", r.name, err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	r.number++
+	if r.check != nil && r.number > 1 && r.check(r.line, line) > 0 {
+		return false, &disorderError{name: r.name, number: r.number, line: line}
+	}
+	r.line = line
+	return true, nil
+}
+
+// disorderError сообщает о строке, нарушающей порядок входного файла -m.
+type disorderError struct {
+	name   string
+	number int
+	line   string
+}
+
+func (e *disorderError) Error() string {
+	return fmt.Sprintf("%s:%d: disorder: %s", e.name, e.number, e.line)
+}
+
+// runHeap — куча серий, упорядоченная по текущим строкам, а при равенстве —
+// по номеру серии.
+type runHeap struct {
+	runs    []*mergeRun
+	compare compareFunc
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	if result := h.compare(h.runs[i].line, h.runs[j].line); result != 0 {
+		return result < 0
+	}
+	return h.runs[i].index < h.runs[j].index
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*mergeRun)) }
+
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// parseSize разбирает размер буфера -S: число с необязательным суффиксом
+// b (байты), K, M, G или T (степени 1024); без суффикса — килобайты, как в
+// GNU sort.
+func parseSize(value string) (int64, error) {
+	digits, suffix := leadingNumber(value)
+	if digits < 0 {
+		return 0, fmt.Errorf("invalid -S argument '%s'", value)
+	}
+
+	var shift int
+	switch suffix {
+	case "b":
+		shift = 0
+	case "", "K", "k":
+		shift = 10
+	case "M", "m":
+		shift = 20
+	case "G", "g":
+		shift = 30
+	case "T", "t":
+		shift = 40
+	default:
+		return 0, fmt.Errorf("invalid suffix in -S argument '%s'", value)
+	}
+	size := int64(digits) << shift
+	if size>>shift != int64(digits) {
+		return 0, fmt.Errorf("-S argument '%s' too large", value)
+	}
+	return size, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput int64
+		expectedError  bool
+	}{
+		{"10", 10 << 10, false},
+		{"512b", 512, false},
+		{"64K", 64 << 10, false},
+		{"3M", 3 << 20, false},
+		{"2g", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"1.5M", 0, true},
+		{"10KB", 0, true},
+		{"-1K", 0, true},
+		{"99999999999T", 0, true},
+	}
+
+	for _, test := range tests {
+		output, err := parseSize(test.input)
+		if output != test.expectedOutput || (err != nil) != test.expectedError {
+			t.Errorf("For input %q, expected %d and error %t, but got %d and error %v", test.input, test.expectedOutput, test.expectedError, output, err)
+		}
+	}
+}
+
+// generateLines создаёт воспроизводимый набор строк с повторами, числовыми
+// колонками и строками без второй колонки.
+func generateLines(count int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	words := []string{"apple", "Banana", "cherry", "date", "Elder", "fig", "grape", ""}
+	lines := make([]string, count)
+	for i := range lines {
+		switch random.Intn(10) {
+		case 0:
+			lines[i] = words[random.Intn(len(words))]
+		case 1:
+			lines[i] = lines[random.Intn(i+1)]
+		default:
+			lines[i] = fmt.Sprintf("%s %d %.2f", words[random.Intn(len(words))], random.Intn(200)-100, random.Float64()*1000)
+		}
+	}
+	return lines
+}
+
+func TestExternalSortMatchesInMemory(t *testing.T) {
+	lines := generateLines(6000, 1)
+	tests := []SortFlags{
+		{},
+		{Reverse: true},
+		{Unique: true},
+		{Numeric: true, Keys: keys("2")},
+		{Keys: keys("2,2n", "1,1fr")},
+		{Keys: keys("3n"), Unique: true, Reverse: true},
+		{FoldCase: true, Unique: true},
+	}
+
+	for _, flags := range tests {
+		expectedOutput := sortLinesByKey(lines, flags)
+
+		dir := t.TempDir()
+		flags.BufferSize = 2 << 10
+		flags.TempDir = dir
+		var output bytes.Buffer
+		if err := sortFiles([]string{"-"}, strings.NewReader(strings.Join(lines, "\n")+"\n"), &output, flags); err != nil {
+			t.Fatalf("For %+v, unexpected error %v", flags, err)
+		}
+
+		got := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+		if !reflect.DeepEqual(got, expectedOutput) {
+			t.Errorf("For %+v, external sort differs from the in-memory one", flags)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("For %+v, temporary files left behind: %v", flags, entries)
+		}
+	}
+}
+
+func TestExternalSortSpills(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	lines := generateLines(3000, 2)
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// С буфером в 512 байт серий больше mergeFanIn, и слияние идёт в
+	// несколько проходов.
+	runs := 0
+	for size, i := 0, 0; i < len(lines); i++ {
+		size += len(lines[i]) + lineOverhead
+		if size >= 512 {
+			runs, size = runs+1, 0
+		}
+	}
+	if runs <= mergeFanIn {
+		t.Fatalf("expected more than %d runs, but the data gives %d", mergeFanIn, runs)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-S", "512b", "-T", dir, "-o", input, input}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected code 0, but got %d: %s", code, stderr.String())
+	}
+	data, _ := os.ReadFile(input)
+	if string(data) != strings.Join(sortLinesByKey(lines, SortFlags{}), "\n")+"\n" {
+		t.Error("multi-pass external sort differs from the in-memory one")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if code := run([]string{"-S", "1b", "-T", filepath.Join(dir, "missing"), input}, nil, &stdout, &stderr); code != exitTrouble {
+		t.Errorf("expected code 2 for a missing temporary directory, but got %d", code)
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	flags := SortFlags{Keys: keys("2,2n", "1,1"), Unique: true}
+	lines := generateLines(5000, 3)
+
+	// Строки подряд раскладываются по шардам больше mergeFanIn, каждый шард
+	// сортируется отдельно, как выход независимых запусков sort. С -u слияние
+	// оставляет строку из более раннего шарда, то есть первую во входе.
+	shards := make([][]string, mergeFanIn+6)
+	size := (len(lines) + len(shards) - 1) / len(shards)
+	for i, line := range lines {
+		shards[i/size] = append(shards[i/size], line)
+	}
+	var files []string
+	for i, shard := range shards {
+		path := filepath.Join(dir, fmt.Sprintf("shard-%02d.txt", i))
+		if err := os.WriteFile(path, []byte(strings.Join(sortLinesByKey(shard, flags), "\n")+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	tempDir := t.TempDir()
+	flags.TempDir, flags.Verify = tempDir, true
+	var output bytes.Buffer
+	if err := mergeFiles(files, nil, &output, flags); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if output.String() != strings.Join(sortLinesByKey(lines, flags), "\n")+"\n" {
+		t.Error("merged shards differ from sorting all lines at once")
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	// Неотсортированный шард в одной из групп обнаруживается при слиянии.
+	unsorted := files[len(files)-1]
+	if err := os.WriteFile(unsorted, []byte("b 2\na 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := mergeFiles(files, nil, &output, flags)
+	var disorder *disorderError
+	if !errors.As(err, &disorder) || disorder.name != unsorted || disorder.number != 2 {
+		t.Errorf("expected disorder at %s:2, but got %v", unsorted, err)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("temporary files left behind after an error: %v", entries)
+	}
+}
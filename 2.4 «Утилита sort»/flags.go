@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SortFlags хранит флаги для утилиты sort. Флаги сравнения (-n, -g, -h, -M,
+// -V, --natural-sort, -r, -b, -f, -d, -i) действуют на ключи без собственных
+// модификаторов.
+type SortFlags struct {
+	Keys         []KeySpec // ключи сортировки (-k) в порядке важности
+	Separator    string    // разделитель колонок (-t), пусто — пробельные символы
+	Numeric      bool      // -n
+	General      bool      // -g
+	Reverse      bool      // -r
+	Unique       bool      // -u
+	Month        bool      // -M
+	IgnoreBlanks bool      // -b
+	FoldCase     bool      // -f
+	Dictionary   bool      // -d
+	Nonprinting  bool      // -i
+	Locale       string    // --locale, пусто — LC_ALL, LC_COLLATE или LANG
+	Check        bool      // -c
+	Merge        bool      // -m: слить уже отсортированные файлы
+	Verify       bool      // --verify: с -m проверять порядок каждого файла
+	Human        bool      // -h
+	Version      bool      // -V
+	Natural      bool      // --natural-sort
+	Output       string    // -o
+	BufferSize   int64     // -S, байты; 0 — defaultBufferSize
+	TempDir      string    // -T, пусто — os.TempDir()
+	Parallel     int       // --parallel, 0 — defaultParallel()
+	Help         bool      // --help
+}
+
+// KeyOptions — модификаторы сравнения одного ключа.
+type KeyOptions struct {
+	Numeric         bool // n
+	General         bool // g
+	Human           bool // h
+	Month           bool // M
+	Version         bool // V
+	Natural         bool // N
+	Reverse         bool // r
+	IgnoreBlanks    bool // b после POS1: пропускать пробелы перед началом ключа
+	IgnoreEndBlanks bool // b после POS2: пропускать пробелы поля конца ключа
+	FoldCase        bool // f
+	Dictionary      bool // d
+	Nonprinting     bool // i
+}
+
+// KeyPosition — позиция в строке: поле Field и символ Char в нём (с 1).
+type KeyPosition struct {
+	Field int
+	Char  int
+}
+
+// KeySpec описывает ключ -k POS1[,POS2] в формате POSIX: ключ начинается с
+// символа Start.Char поля Start.Field и заканчивается символом End.Char поля
+// End.Field включительно. End.Char, равный 0, означает конец поля, а
+// End.Field, равный 0, — конец строки. Start.Field, равный 0, означает всю
+// строку (ключ по умолчанию). Ключ без модификаторов использует глобальные
+// флаги.
+type KeySpec struct {
+	Start   KeyPosition
+	End     KeyPosition
+	Options KeyOptions
+}
+
+// keyModifiers — буквы модификаторов, допустимые в описании ключа.
+const keyModifiers = "bdfghiMNnrV"
+
+// valueOptions — короткие опции, которые принимают значение: слитно (-k2, -t,)
+// или следующим аргументом (-k 2).
+const valueOptions = "ktoST"
+
+// longOnlyOptions — длинные опции без короткой формы; значение показывает,
+// принимает ли опция аргумент.
+var longOnlyOptions = map[string]bool{
+	"help":         false,
+	"locale":       true,
+	"natural-sort": false,
+	"parallel":     true,
+	"verify":       false,
+}
+
+// longOptions сопоставляет длинные опции коротким.
+var longOptions = map[string]byte{
+	"key":                   'k',
+	"field-separator":       't',
+	"output":                'o',
+	"numeric-sort":          'n',
+	"general-numeric-sort":  'g',
+	"reverse":               'r',
+	"unique":                'u',
+	"month-sort":            'M',
+	"ignore-leading-blanks": 'b',
+	"ignore-case":           'f',
+	"dictionary-order":      'd',
+	"ignore-nonprinting":    'i',
+	"check":                 'c',
+	"merge":                 'm',
+	"human-numeric-sort":    'h',
+	"buffer-size":           'S',
+	"temporary-directory":   'T',
+	"version-sort":          'V',
+}
+
+const usage = `Usage: sort [OPTION]... [FILE]...
+Write sorted concatenation of all FILE(s) to standard output.
+With no FILE, or when FILE is -, read standard input.
+
+  -b, --ignore-leading-blanks  ignore leading blanks
+  -c, --check                  check for sorted input; do not sort
+  -d, --dictionary-order       consider only blanks and alphanumeric characters
+  -f, --ignore-case            fold lower case to upper case characters
+  -g, --general-numeric-sort   compare according to general numerical value
+  -h, --human-numeric-sort     compare human readable numbers (e.g., 2K 1Mi)
+  -i, --ignore-nonprinting     consider only printable characters
+  -k, --key=KEYDEF             sort via a key; KEYDEF gives location and type
+      --locale=LOCALE          compare text by the collation rules of LOCALE
+                               (e.g., ru_RU.UTF-8); C compares bytes
+  -m, --merge                  merge already sorted files; do not sort
+  -M, --month-sort             compare (unknown) < 'JAN' < ... < 'DEC'; English
+                               and Russian names and abbreviations are known
+  -n, --numeric-sort           compare according to string numerical value
+      --natural-sort           compare digit runs in text by their value
+  -o, --output=FILE            write result to FILE instead of standard output
+      --parallel=N             sort with N threads (default: CPUs, at most 8)
+  -r, --reverse                reverse the result of comparisons
+  -S, --buffer-size=SIZE       use SIZE for main memory buffer; larger input is
+                               sorted in parts through temporary files
+  -t, --field-separator=SEP    use SEP instead of blanks to separate columns
+  -T, --temporary-directory=DIR  use DIR for temporaries, not $TMPDIR or /tmp
+  -u, --unique                 output only the first of an equal run
+  -V, --version-sort           natural sort of (version) numbers within text
+      --verify                 with -m, fail if an input is not sorted
+      --help                   display this help and exit
+
+KEYDEF is F[.C][OPTS][,F[.C][OPTS]] for start and stop position, where F is a
+field number and C a character position in the field; both are origin 1, and
+the stop position defaults to the line's end. If neither -t nor -b is in
+effect, characters in a field are counted from the beginning of the preceding
+whitespace. OPTS is one or more single-letter ordering options [bdfghiMNnrV],
+where N stands for --natural-sort; they override global ordering options for
+that key. Several keys are compared in order; lines whose keys are all equal
+are compared as a whole.
+
+SIZE may be followed by b, K, M, G or T; the default unit is K and the default
+size 256M.
+
+Without --locale, the locale is taken from LC_ALL, LC_COLLATE or LANG.
+
+Exit status is 0 on success, 1 if -c or -m --verify finds disorder and 2 on
+trouble.
+`
+
+// parseArgs разбирает аргументы командной строки так же, как GNU sort:
+// короткие опции можно объединять (-nr, -rk2), значение опции можно писать
+// слитно или отдельно, опции и файлы могут идти в любом порядке, а после
+// "--" все аргументы считаются файлами. Возвращает флаги и список файлов.
+func parseArgs(args []string) (SortFlags, []string, error) {
+	var flags SortFlags
+	var files []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			files = append(files, args[i+1:]...)
+			i = len(args)
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			files = append(files, arg)
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			option, isShort := longOptions[name]
+			takesValue, isLongOnly := longOnlyOptions[name]
+			if !isShort && !isLongOnly {
+				return flags, nil, fmt.Errorf("unrecognized option '%s'", arg)
+			}
+			if isShort {
+				takesValue = strings.IndexByte(valueOptions, option) >= 0
+			}
+			if hasValue && !takesValue {
+				return flags, nil, fmt.Errorf("option '--%s' doesn't allow an argument", name)
+			}
+			if takesValue && !hasValue {
+				if i+1 == len(args) {
+					return flags, nil, fmt.Errorf("option '--%s' requires an argument", name)
+				}
+				i++
+				value = args[i]
+			}
+			var err error
+			if isShort {
+				err = flags.set(option, value)
+			} else {
+				err = flags.setLong(name, value)
+			}
+			if err != nil {
+				return flags, nil, err
+			}
+		default:
+			for j := 1; j < len(arg); j++ {
+				option := arg[j]
+				if strings.IndexByte(valueOptions, option) < 0 {
+					if err := flags.set(option, ""); err != nil {
+						return flags, nil, err
+					}
+					continue
+				}
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 == len(args) {
+						return flags, nil, fmt.Errorf("option requires an argument -- '%c'", option)
+					}
+					i++
+					value = args[i]
+				}
+				if err := flags.set(option, value); err != nil {
+					return flags, nil, err
+				}
+				break
+			}
+		}
+	}
+	if flags.Verify && !flags.Merge {
+		return flags, nil, fmt.Errorf("option '--verify' requires -m")
+	}
+	return flags, files, nil
+}
+
+// set применяет одну короткую опцию со значением value (если она его принимает).
+func (f *SortFlags) set(option byte, value string) error {
+	switch option {
+	case 'k':
+		key, err := parseKeySpec(value)
+		if err != nil {
+			return err
+		}
+		f.Keys = append(f.Keys, key)
+	case 't':
+		if utf8.RuneCountInString(value) != 1 {
+			return fmt.Errorf("multi-character tab '%s'", value)
+		}
+		f.Separator = value
+	case 'o':
+		f.Output = value
+	case 'S':
+		size, err := parseSize(value)
+		if err != nil {
+			return err
+		}
+		f.BufferSize = size
+	case 'T':
+		f.TempDir = value
+	case 'n':
+		f.Numeric = true
+	case 'g':
+		f.General = true
+	case 'r':
+		f.Reverse = true
+	case 'u':
+		f.Unique = true
+	case 'M':
+		f.Month = true
+	case 'b':
+		f.IgnoreBlanks = true
+	case 'f':
+		f.FoldCase = true
+	case 'd':
+		f.Dictionary = true
+	case 'i':
+		f.Nonprinting = true
+	case 'c':
+		f.Check = true
+	case 'm':
+		f.Merge = true
+	case 'h':
+		f.Human = true
+	case 'V':
+		f.Version = true
+	default:
+		return fmt.Errorf("invalid option -- '%c'", option)
+	}
+	return f.options().validate()
+}
+
+// setLong применяет длинную опцию без короткой формы.
+func (f *SortFlags) setLong(name, value string) error {
+	switch name {
+	case "help":
+		f.Help = true
+	case "locale":
+		if _, _, err := parseLocale(value); err != nil {
+			return err
+		}
+		f.Locale = value
+	case "natural-sort":
+		f.Natural = true
+	case "verify":
+		f.Verify = true
+	case "parallel":
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers < 1 {
+			return fmt.Errorf("invalid number of threads '%s'", value)
+		}
+		f.Parallel = workers
+	}
+	return f.options().validate()
+}
+
+// options возвращает глобальные флаги сравнения.
+func (f SortFlags) options() KeyOptions {
+	return KeyOptions{
+		Numeric:         f.Numeric,
+		General:         f.General,
+		Human:           f.Human,
+		Month:           f.Month,
+		Version:         f.Version,
+		Natural:         f.Natural,
+		Reverse:         f.Reverse,
+		IgnoreBlanks:    f.IgnoreBlanks,
+		IgnoreEndBlanks: f.IgnoreBlanks,
+		FoldCase:        f.FoldCase,
+		Dictionary:      f.Dictionary,
+		Nonprinting:     f.Nonprinting,
+	}
+}
+
+// keys возвращает ключи сортировки с учётом глобальных флагов. Без -k вся
+// строка считается одним ключом.
+func (f SortFlags) keys() []KeySpec {
+	if len(f.Keys) == 0 {
+		return []KeySpec{{Options: f.options()}}
+	}
+	keys := make([]KeySpec, len(f.Keys))
+	for i, key := range f.Keys {
+		if key.Options == (KeyOptions{}) {
+			key.Options = f.options()
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// parseKeySpec разбирает описание ключа вида "2", "2nr" или "2.3b,2.5nr".
+// Модификаторы после обеих позиций относятся ко всему ключу, кроме b,
+// который после POS2 пропускает пробелы только при поиске конца ключа.
+func parseKeySpec(value string) (KeySpec, error) {
+	var key KeySpec
+	startDef, endDef, hasEnd := strings.Cut(value, ",")
+
+	start, modifiers, err := parseKeyPosition(startDef, 1, value)
+	if err != nil {
+		return KeySpec{}, err
+	}
+	if start.Char == 0 {
+		return KeySpec{}, fmt.Errorf("invalid key '%s': character offset is zero", value)
+	}
+	key.Start = start
+	if err := key.Options.parseModifiers(modifiers, &key.Options.IgnoreBlanks, value); err != nil {
+		return KeySpec{}, err
+	}
+
+	if hasEnd {
+		end, modifiers, err := parseKeyPosition(endDef, 0, value)
+		if err != nil {
+			return KeySpec{}, err
+		}
+		key.End = end
+		if err := key.Options.parseModifiers(modifiers, &key.Options.IgnoreEndBlanks, value); err != nil {
+			return KeySpec{}, err
+		}
+	}
+	return key, key.Options.validate()
+}
+
+// parseKeyPosition разбирает позицию F[.C] в начале def и возвращает её
+// вместе с модификаторами, записанными после неё. Без .C номер символа
+// равен defaultChar.
+func parseKeyPosition(def string, defaultChar int, value string) (KeyPosition, string, error) {
+	field, rest := leadingNumber(def)
+	if field < 0 {
+		return KeyPosition{}, "", fmt.Errorf("invalid number at field start: invalid count at start of '%s'", value)
+	}
+	if field == 0 {
+		return KeyPosition{}, "", fmt.Errorf("invalid key '%s': field number is zero", value)
+	}
+
+	position := KeyPosition{Field: field, Char: defaultChar}
+	if strings.HasPrefix(rest, ".") {
+		position.Char, rest = leadingNumber(rest[1:])
+		if position.Char < 0 {
+			return KeyPosition{}, "", fmt.Errorf("invalid number after '.': invalid count at start of '%s'", value)
+		}
+	}
+	return position, rest, nil
+}
+
+// leadingNumber разбирает десятичное число в начале s и возвращает его и
+// остаток строки; если цифр нет, возвращает -1.
+func leadingNumber(s string) (int, string) {
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	num, err := strconv.Atoi(s[:digits])
+	if err != nil {
+		return -1, s
+	}
+	return num, s[digits:]
+}
+
+// parseModifiers применяет буквы модификаторов к ключу; b устанавливает
+// blanks — пропуск пробелов у начала или у конца ключа.
+func (o *KeyOptions) parseModifiers(modifiers string, blanks *bool, value string) error {
+	for _, modifier := range modifiers {
+		switch modifier {
+		case 'b':
+			*blanks = true
+		case 'd':
+			o.Dictionary = true
+		case 'f':
+			o.FoldCase = true
+		case 'i':
+			o.Nonprinting = true
+		case 'g':
+			o.General = true
+		case 'h':
+			o.Human = true
+		case 'M':
+			o.Month = true
+		case 'N':
+			o.Natural = true
+		case 'V':
+			o.Version = true
+		case 'n':
+			o.Numeric = true
+		case 'r':
+			o.Reverse = true
+		default:
+			return fmt.Errorf("invalid key '%s': stray character in field spec, expected one of %s", value, keyModifiers)
+		}
+	}
+	return nil
+}
+
+// validate проверяет, что выбрано не больше одного способа сравнения; -d и
+// -i вместе считаются одним способом, как в GNU sort.
+func (o KeyOptions) validate() error {
+	modes, count := "", 0
+	for _, mode := range []struct {
+		set    bool
+		letter string
+	}{{o.Dictionary, "d"}, {o.General, "g"}, {o.Human, "h"}, {o.Nonprinting, "i"}, {o.Month, "M"}, {o.Natural, "N"}, {o.Numeric, "n"}, {o.Version, "V"}} {
+		if mode.set {
+			modes += mode.letter
+			count++
+		}
+	}
+	if o.Dictionary && o.Nonprinting {
+		count--
+	}
+	if count > 1 {
+		return fmt.Errorf("options '-%s' are incompatible", modes)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+module SortUtility
+
+go 1.22.6
+
+require golang.org/x/text v0.22.0
@@ -1,323 +1,259 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
-	"unicode"
 )
 
-// sortString сортирует срез строк в лексикографическом порядке с использованием рекурсивного подхода.
-func sortString(str []string) []string {
-	if len(str) < 2 {
-		return str
-	}
-
-	pivot := str[0]
-	var left []string
-	var right []string
+// Коды возврата, как у GNU sort.
+const (
+	exitOK       = 0
+	exitDisorder = 1 // -c нашёл строку не на своём месте
+	exitTrouble  = 2 // ошибка в аргументах, чтении или записи
+)
 
-	for i := 1; i < len(str); i++ {
-		if str[i] < pivot {
-			left = append(left, str[i])
-		} else {
-			right = append(right, str[i])
+// run выполняет утилиту с аргументами args и возвращает код возврата.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags, files, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "sort: %v\nTry 'sort --help' for more information.\n", err)
+		return exitTrouble
+	}
+	if flags.Help {
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if flags.Locale == "" {
+		// Неизвестная локаль окружения, как и в setlocale, означает C.
+		if _, _, err := parseLocale(localeFromEnv()); err == nil {
+			flags.Locale = localeFromEnv()
 		}
 	}
 
-	return append(append(sortString(left), pivot), sortString(right)...)
-}
-
-// splitBySpaces разбивает строку на слова (колонки) по пробелам.
-func splitBySpaces(s string) []string {
-	return strings.Fields(s)
-}
-
-// convertStringsToInt конвертирует срез строк в срез целых чисел, возвращая ошибку, если конвертация не удалась.
-func convertStringsToInt(str []string) ([]int, error) {
-	var nums []int
-	for _, s := range str {
-		num, err := strconv.Atoi(s)
+	if flags.Check {
+		if len(files) > 1 {
+			fmt.Fprintf(stderr, "sort: extra operand '%s' not allowed with -c\n", files[1])
+			return exitTrouble
+		}
+		number, line, err := checkSorted(files[0], stdin, flags)
 		if err != nil {
-			return nil, err
+			fmt.Fprintf(stderr, "sort: %v\n", err)
+			return exitTrouble
+		}
+		if number > 0 {
+			fmt.Fprintf(stderr, "sort: %s:%d: disorder: %s\n", files[0], number, line)
+			return exitDisorder
 		}
-		nums = append(nums, num)
+		return exitOK
 	}
-	return nums, nil
-}
 
-// convertIntsToString конвертирует срез целых чисел в срез строк.
-func convertIntsToString(nums []int) []string {
-	var str []string
-	for _, num := range nums {
-		str = append(str, strconv.Itoa(num))
+	process := sortFiles
+	if flags.Merge {
+		process = mergeFiles
+	}
+	err = writeOutput(flags.Output, flags.TempDir, stdout, func(w io.Writer) error {
+		return process(files, stdin, w, flags)
+	})
+	var disorder *disorderError
+	switch {
+	case errors.As(err, &disorder):
+		fmt.Fprintf(stderr, "sort: %v\n", disorder)
+		return exitDisorder
+	case err != nil:
+		fmt.Fprintf(stderr, "sort: %v\n", err)
+		return exitTrouble
 	}
-	return str
+	return exitOK
 }
 
-// sortNumbers сортирует срез целых чисел в порядке возрастания с использованием рекурсивного подхода.
-func sortNumbers(str []int) []int {
-	if len(str) < 2 {
-		return str
+// checkSorted читает файл построчно и возвращает номер и текст первой
+// строки, нарушающей порядок, или 0, если файл отсортирован. С -u строки с
+// равными ключами тоже считаются нарушением.
+func checkSorted(name string, stdin io.Reader, flags SortFlags) (int, string, error) {
+	compare := lineComparator(flags)
+	if flags.Unique {
+		compare = keyComparator(flags)
 	}
 
-	pivot := str[0]
-	var left []int
-	var right []int
-
-	for i := 1; i < len(str); i++ {
-		if str[i] < pivot {
-			left = append(left, str[i])
-		} else {
-			right = append(right, str[i])
+	var previous string
+	number := 0
+	errDisorder := errors.New("disorder")
+	err := forEachLine([]string{name}, stdin, func(line string) error {
+		number++
+		result := 0
+		if number > 1 {
+			result = compare(previous, line)
+		}
+		previous = line
+		if result > 0 || number > 1 && flags.Unique && result == 0 {
+			return errDisorder
 		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errDisorder):
+		return number, previous, nil
+	case err != nil:
+		return 0, "", err
 	}
-
-	return append(append(sortNumbers(left), pivot), sortNumbers(right)...)
+	return 0, "", nil
 }
 
-// reverseString разворачивает порядок строк в срезе.
-func reverseString(str []string) []string {
-	result := make([]string, 0, len(str))
-	for i := len(str) - 1; i >= 0; i-- {
-		result = append(result, str[i])
+// forEachLine вызывает fn для каждой строки файлов по порядку; "-" означает
+// STDIN. Строки передаются без перевода строки, последняя строка без "\n"
+// тоже считается строкой. Ошибка fn прерывает чтение и возвращается как есть.
+func forEachLine(files []string, stdin io.Reader, fn func(line string) error) error {
+	for _, name := range files {
+		if name == "-" {
+			if err := scanLines(stdin, "standard input", fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = scanLines(file, name, fn)
+		file.Close()
+		if err != nil {
+			return err
+		}
 	}
-	return result
+	return nil
 }
 
-// sortUnique сортирует строки и удаляет дубликаты.
-func sortUnique(str []string) []string {
-	var unique []string
-	uniqueMap := map[string]struct{}{}
-
-	for _, item := range str {
-		if _, exist := uniqueMap[item]; !exist {
-			unique = append(unique, item)
-			uniqueMap[item] = struct{}{}
+// scanLines читает строки r и передаёт их fn; name нужен для сообщений об
+// ошибках чтения.
+func scanLines(r io.Reader, name string, fn func(line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if err := fn(strings.TrimSuffix(line, "\n")); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
 		}
 	}
-
-	return unique
 }
 
-// sortMonths сортирует строки, представляющие месяцы, в порядке их следования в календаре.
-func sortMonths(months []string) []string {
-	var monthOrder = map[string]int{
-		"January":   1,
-		"February":  2,
-		"March":     3,
-		"April":     4,
-		"May":       5,
-		"June":      6,
-		"July":      7,
-		"August":    8,
-		"September": 9,
-		"October":   10,
-		"November":  11,
-		"December":  12,
+// writeOutput передаёт write поток stdout или, если задан -o, файл path.
+// Символические ссылки в path сначала разрешаются. Обычный файл пишется во
+// временный рядом с ним и затем переименовывается, поэтому -o может указывать
+// на один из входных файлов, а при ошибке старое содержимое не теряется;
+// права файла переносятся на новый. Остальные цели, в том числе ещё не
+// существующие файлы, записываются на месте функцией writeInPlace.
+func writeOutput(path, tempDir string, stdout io.Writer, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(stdout)
 	}
 
-	if len(months) < 2 {
-		return months
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		path = resolved
+	} else if link, lerr := os.Lstat(path); lerr == nil && link.Mode()&os.ModeSymlink != 0 {
+		// Висячая ссылка: файл создаётся там, куда она указывает.
+		return writeInPlace(path, tempDir, write)
 	}
 
-	pivot := months[0]
-	var left, right []string
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return writeInPlace(path, tempDir, write)
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular() || !replaceable(info):
+		return writeInPlace(path, tempDir, write)
+	}
 
-	for i := 1; i < len(months); i++ {
-		if monthOrder[months[i]] < monthOrder[pivot] {
-			left = append(left, months[i])
-		} else {
-			right = append(right, months[i])
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sort-*")
+	if errors.Is(err, os.ErrPermission) {
+		// В каталог писать нельзя, а в сам файл, возможно, можно.
+		return writeInPlace(path, tempDir, write)
+	}
+	if err != nil {
+		// Ошибка называет файл -o, а не временный файл рядом с ним.
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
 		}
+		return &os.PathError{Op: "open", Path: path, Err: err}
 	}
+	defer os.Remove(tmp.Name())
 
-	return append(append(sortMonths(left), pivot), sortMonths(right)...)
-}
-
-// trimRightSpace удаляет пробелы в конце каждой строки в срезе.
-func trimRightSpace(str []string) []string {
-	trimmedSlice := make([]string, len(str))
-
-	for i, s := range str {
-		trimmedSlice[i] = strings.TrimRight(s, " ")
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
 	}
-	return trimmedSlice
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
-func isSorted(str []string) bool {
-	for i := 1; i < len(str); i++ {
-		if str[i] < str[i-1] {
-			return false
-		}
+// writeInPlace пишет вывод во временный файл в tempDir и, только когда весь
+// вход прочитан, копирует его в path, открытый с O_TRUNC. Так пишутся
+// устройства вроде /dev/null, файлы с жёсткими ссылками или чужим владельцем
+// и файлы в каталоге без права записи: файл остаётся тем же, с прежними
+// владельцем, правами и ссылками. Новый файл, как и в GNU sort, создаётся с
+// правами 0666 с учётом umask.
+func writeInPlace(path, tempDir string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(tempDir, "sort-*")
+	if err != nil {
+		return err
 	}
-	return true
-}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-// parseNumberWithSuffix разбирает числовые значения с суффиксами (K, M, B) и возвращает их в виде float64.
-func parseNumberWithSuffix(s string) (float64, error) {
-	suffixes := map[rune]float64{
-		'K': 1000,
-		'M': 1_000_000,
-		'B': 1_000_000_000,
+	if err := write(tmp); err != nil {
+		return err
 	}
-	s = strings.TrimSpace(s)
-	numStr := ""
-	suffix := ' '
-	for _, r := range s {
-		if unicode.IsDigit(r) || r == '.' {
-			numStr += string(r)
-		} else {
-			suffix = r
-			break
-		}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
 	}
-	num, err := strconv.ParseFloat(numStr, 64)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
 	if err != nil {
-		return 0, err
-	}
-	if scale, ok := suffixes[suffix]; ok {
-		return num * scale, nil
+		return err
 	}
-	return num, nil
-}
-
-// sortNumbersWithSuffix сортирует строки с числовыми значениями, учитывая суффиксы.
-func sortNumbersWithSuffix(str []string) ([]string, error) {
-	var nums []float64
-	for _, s := range str {
-		num, err := parseNumberWithSuffix(s)
-		if err != nil {
-			return nil, err
-		}
-		nums = append(nums, num)
+	if _, err := io.Copy(file, tmp); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", path, err)
 	}
-
-	// Сортировка чисел
-	for i := 0; i < len(nums); i++ {
-		for j := 0; j < len(nums)-1; j++ {
-			if nums[j] > nums[j+1] {
-				nums[j], nums[j+1] = nums[j+1], nums[j] // Меняем местами числа
-				str[j], str[j+1] = str[j+1], str[j]     // Меняем местами строки
-			}
-		}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
 	}
-
-	return str, nil
+	return nil
 }
 
-// sortLinesByKey сортирует строки на основе переданных ключей (параметров).
-// Поддерживаемые ключи:
-// -k (по указанной колонке),
-// -n (по числам),
-// -r (реверс),
-// -u (уникальные),
-// -M (по месяцам),
-// -b (обрезка пробелов),
-// -c (проверять отсортированы ли данные),
-// -h (по числам с суффиксами).
-func sortLinesByKey(str []string, keys ...string) []string {
-	if len(keys) == 0 {
-		return sortString(str)
-	}
-
-	result := str
-
-	for _, key := range keys {
-		switch key {
-		case "-k":
-			if len(keys) > 1 {
-				colIndex, err := strconv.Atoi(keys[1])
-				if err == nil && colIndex > 0 {
-					colIndex--
-					var columnValues []string
-					for _, line := range result {
-						cols := splitBySpaces(line)
-						if colIndex < len(cols) {
-							columnValues = append(columnValues, cols[colIndex])
-						}
-					}
-					result = sortString(columnValues)
-				}
-			}
-		case "-n":
-			nums, err := convertStringsToInt(result)
-			if err != nil {
-				fmt.Println(err)
-				return nil
-			}
-			resultNums := sortNumbers(nums)
-			result = convertIntsToString(resultNums)
-		case "-r":
-			if len(keys) == 1 {
-				result = reverseString(sortString(result))
-			} else {
-				result = reverseString(result)
-			}
-		case "-u":
-			result = sortUnique(result)
-		case "-M":
-			result = sortMonths(result)
-		case "-b":
-			result = trimRightSpace(result)
-		case "-c":
-			if isSorted(result) {
-				fmt.Println("The lines are sorted.")
-			} else {
-				fmt.Println("The lines are NOT sorted.")
-			}
-
-		case "-h":
-			sorted, err := sortNumbersWithSuffix(result)
-			if err != nil {
-				fmt.Println(err)
-				return nil
-			}
-			result = sorted
-
-		}
+// writeLines пишет каждую строку с переводом строки в конце.
+func writeLines(w io.Writer, lines []string) error {
+	writer := bufio.NewWriter(w)
+	for _, line := range lines {
+		writer.WriteString(line)
+		writer.WriteByte('\n')
 	}
-
-	return result
+	return writer.Flush()
 }
 
 func main() {
-	var line []string
-	var result []string
-
-	line = []string{"banana", "apple", "orange"}
-	result = sortLinesByKey(line)
-	fmt.Println(result)
-
-	line = []string{"apple banana", "orange apple", "banana orange"}
-	result = sortLinesByKey(line, "-k", "2")
-	fmt.Println(result)
-
-	line = []string{"5", "2", "13"}
-	result = sortLinesByKey(line, "-n")
-	fmt.Println(result)
-
-	line = []string{"5", "2", "13", "2", "10"}
-	result = sortLinesByKey(line, "-r")
-	fmt.Println(result)
-
-	line = []string{"5", "2", "13", "2", "10"}
-	result = sortLinesByKey(line, "-u")
-	fmt.Println(result)
-
-	line = []string{"March", "January", "February", "December", "July", "April"}
-	result = sortLinesByKey(line, "-M")
-	fmt.Println(result)
-
-	line = []string{"   leading space", "trailing space   ", "  both  "}
-	result = sortLinesByKey(line, "-b")
-	fmt.Println(result)
-
-	line = []string{"5K", "2M", "13B", "2", "10"}
-	result = sortLinesByKey(line, "-h")
-	fmt.Println(result)
-
-	line = []string{"banana", "apple", "orange"}
-	result = sortLinesByKey(line, "-c")
-	fmt.Println(result)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
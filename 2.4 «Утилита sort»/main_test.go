@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args          []string
+		expectedFlags SortFlags
+		expectedFiles []string
+		expectedError bool
+	}{
+		{nil, SortFlags{}, nil, false},
+		{[]string{"-nr", "a.txt"}, SortFlags{Numeric: true, Reverse: true}, []string{"a.txt"}, false},
+		{[]string{"-k2", "-t,"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{2, 1}}}, Separator: ","}, nil, false},
+		{[]string{"-rk", "3", "-"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{3, 1}}}, Reverse: true}, []string{"-"}, false},
+		{[]string{"a.txt", "-u", "b.txt"}, SortFlags{Unique: true}, []string{"a.txt", "b.txt"}, false},
+		{[]string{"-uoout.txt", "in.txt"}, SortFlags{Unique: true, Output: "out.txt"}, []string{"in.txt"}, false},
+		{[]string{"--key=2", "--field-separator", ";", "--reverse"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{2, 1}}}, Separator: ";", Reverse: true}, nil, false},
+		{[]string{"--", "-n"}, SortFlags{}, []string{"-n"}, false},
+		{[]string{"-bcM"}, SortFlags{IgnoreBlanks: true, Check: true, Month: true}, nil, false},
+		{[]string{"-k2nr", "-k", "1", "-f"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{2, 1}, Options: KeyOptions{Numeric: true, Reverse: true}}, {Start: KeyPosition{1, 1}}}, FoldCase: true}, nil, false},
+		{[]string{"--key=3Mb"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{3, 1}, Options: KeyOptions{Month: true, IgnoreBlanks: true}}}}, nil, false},
+		{[]string{"-k2.3b,2.5nr"}, SortFlags{Keys: []KeySpec{{KeyPosition{2, 3}, KeyPosition{2, 5}, KeyOptions{Numeric: true, Reverse: true, IgnoreBlanks: true}}}}, nil, false},
+		{[]string{"-k", "1,1b", "-k3.2,4"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{1, 1}, End: KeyPosition{1, 0}, Options: KeyOptions{IgnoreEndBlanks: true}}, {Start: KeyPosition{3, 2}, End: KeyPosition{4, 0}}}}, nil, false},
+		{[]string{"-S10M", "-T", "/var/tmp"}, SortFlags{BufferSize: 10 << 20, TempDir: "/var/tmp"}, nil, false},
+		{[]string{"--buffer-size=64K", "--temporary-directory=tmp"}, SortFlags{BufferSize: 64 << 10, TempDir: "tmp"}, nil, false},
+		{[]string{"--parallel=4", "--parallel", "2"}, SortFlags{Parallel: 2}, nil, false},
+		{[]string{"-mu", "--verify", "a", "b"}, SortFlags{Merge: true, Unique: true, Verify: true}, []string{"a", "b"}, false},
+		{[]string{"--merge", "--", "--verify"}, SortFlags{Merge: true}, []string{"--verify"}, false},
+		{[]string{"-Vr", "--natural-sort", "-k2N,2"}, SortFlags{}, nil, true},
+		{[]string{"-k2N,2", "--version-sort"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{2, 1}, End: KeyPosition{2, 0}, Options: KeyOptions{Natural: true}}}, Version: true}, nil, false},
+		{[]string{"-gk2g"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{2, 1}, Options: KeyOptions{General: true}}}, General: true}, nil, false},
+		{[]string{"-dfi", "--locale=ru_RU.UTF-8", "-k1di,1"}, SortFlags{Keys: []KeySpec{{Start: KeyPosition{1, 1}, End: KeyPosition{1, 0}, Options: KeyOptions{Dictionary: true, Nonprinting: true}}}, Dictionary: true, FoldCase: true, Nonprinting: true, Locale: "ru_RU.UTF-8"}, nil, false},
+		{[]string{"-x"}, SortFlags{}, nil, true},
+		{[]string{"-dn"}, SortFlags{}, nil, true},
+		{[]string{"--locale=not a locale"}, SortFlags{}, nil, true},
+		{[]string{"-gn"}, SortFlags{}, nil, true},
+		{[]string{"-k1Vn"}, SortFlags{}, nil, true},
+		{[]string{"--verify"}, SortFlags{}, nil, true},
+		{[]string{"--parallel=0"}, SortFlags{}, nil, true},
+		{[]string{"--parallel"}, SortFlags{}, nil, true},
+		{[]string{"--help=yes"}, SortFlags{}, nil, true},
+		{[]string{"-S", "lots"}, SortFlags{}, nil, true},
+		{[]string{"-k2q"}, SortFlags{}, nil, true},
+		{[]string{"-k0"}, SortFlags{}, nil, true},
+		{[]string{"-k2.0"}, SortFlags{}, nil, true},
+		{[]string{"-k2.x"}, SortFlags{}, nil, true},
+		{[]string{"-k2,"}, SortFlags{}, nil, true},
+		{[]string{"-k2,3.1.2"}, SortFlags{}, nil, true},
+		{[]string{"-k2nh"}, SortFlags{}, nil, true},
+		{[]string{"-nM"}, SortFlags{}, nil, true},
+		{[]string{"-k"}, SortFlags{}, nil, true},
+		{[]string{"-k0"}, SortFlags{}, nil, true},
+		{[]string{"-kx"}, SortFlags{}, nil, true},
+		{[]string{"-t::"}, SortFlags{}, nil, true},
+		{[]string{"--numeric"}, SortFlags{}, nil, true},
+		{[]string{"--reverse=yes"}, SortFlags{}, nil, true},
+	}
+
+	for _, test := range tests {
+		flags, files, err := parseArgs(test.args)
+		if (err != nil) != test.expectedError {
+			t.Errorf("For args %q, expected error %t, but got %v", test.args, test.expectedError, err)
+			continue
+		}
+		if err == nil && (!reflect.DeepEqual(flags, test.expectedFlags) || !reflect.DeepEqual(files, test.expectedFiles)) {
+			t.Errorf("For args %q, expected %+v %q, but got %+v %q", test.args, test.expectedFlags, test.expectedFiles, flags, files)
+		}
+	}
+}
+
+// keys разбирает описания ключей -k для таблиц тестов.
+func keys(defs ...string) []KeySpec {
+	result := make([]KeySpec, len(defs))
+	for i, def := range defs {
+		key, err := parseKeySpec(def)
+		if err != nil {
+			panic(err)
+		}
+		result[i] = key
+	}
+	return result
+}
+
+func TestKeySpecExtract(t *testing.T) {
+	tests := []struct {
+		key            string
+		separator      string
+		input          string
+		expectedOutput string
+	}{
+		{"2", "", "one  two three", "  two three"},
+		{"2b", "", "one  two three", "two three"},
+		{"2,2", "", "one  two three", "  two"},
+		{"2b,2", "", "one  two three", "two"},
+		{"2.2,2.3", "", "one two three", "tw"},
+		{"2.2b,2.3", "", "one  two three", ""},
+		{"2.2b,2.3b", "", "one  two three", "wo"},
+		{"2.2,2.3", "", "one  two three", " t"},
+		{"1.2,1.3", "", "Привет мир", "ри"},
+		{"3", "", "one two", ""},
+		{"2,3", ",", "a,b,c,d", "b,c"},
+		{"2.2,2.4", ",", "a,bcde,f", "cde"},
+		{"2", ",", "a,,c", ",c"},
+		{"4", ",", "a,b", ""},
+		{"2.9,2", "", "a bc", ""},
+		{"1.3,1.2", "", "abcdef", ""},
+	}
+
+	for _, test := range tests {
+		output := keys(test.key)[0].extract(test.input, test.separator)
+		if output != test.expectedOutput {
+			t.Errorf("For key %s and input %q, expected %q, but got %q", test.key, test.input, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestSortLinesByKey(t *testing.T) {
+	tests := []struct {
+		input          []string
+		flags          SortFlags
+		expectedOutput []string
+	}{
+		{[]string{"banana", "apple", "orange"}, SortFlags{}, []string{"apple", "banana", "orange"}},
+		{[]string{"apple banana", "orange apple", "banana orange"}, SortFlags{Keys: keys("2")}, []string{"orange apple", "apple banana", "banana orange"}},
+		{[]string{"5", "2", "13"}, SortFlags{Numeric: true}, []string{"2", "5", "13"}},
+		{[]string{"5", "2", "13", "2", "10"}, SortFlags{Numeric: true, Reverse: true}, []string{"13", "10", "5", "2", "2"}},
+		{[]string{"5", "2", "13", "2", "10"}, SortFlags{Numeric: true, Unique: true}, []string{"2", "5", "10", "13"}},
+		{[]string{"-1.5", "x", "3 apples", "-2"}, SortFlags{Numeric: true}, []string{"-2", "-1.5", "x", "3 apples"}},
+		{[]string{"March", "january", "February", "Smarch"}, SortFlags{Month: true}, []string{"Smarch", "january", "February", "March"}},
+		{[]string{"5K", "2M", "13G", "2", "10"}, SortFlags{Human: true}, []string{"2", "10", "5K", "2M", "13G"}},
+		{[]string{"b  2", "a 10", "c 1"}, SortFlags{Keys: keys("2"), Numeric: true, Reverse: true}, []string{"a 10", "b  2", "c 1"}},
+		{[]string{"x,3,b", "y,1,a", "z,2"}, SortFlags{Keys: keys("3"), Separator: ","}, []string{"z,2", "y,1,a", "x,3,b"}},
+		{[]string{"  b", "a", " c"}, SortFlags{IgnoreBlanks: true}, []string{"a", "  b", " c"}},
+		// Равные ключи сравниваются целиком, а -u оставляет первую строку группы.
+		{[]string{"b 1", "a 1", "c 0"}, SortFlags{Keys: keys("2"), Numeric: true}, []string{"c 0", "a 1", "b 1"}},
+		{[]string{"b 1", "a 1", "c 0"}, SortFlags{Keys: keys("2"), Numeric: true, Unique: true}, []string{"c 0", "b 1"}},
+		// С -u остаётся первая во входе строка из равных по ключу, как в GNU sort.
+		{[]string{"x 1 b", "x 1 a"}, SortFlags{Keys: keys("2,2"), Unique: true}, []string{"x 1 b"}},
+		{[]string{"b", "B"}, SortFlags{FoldCase: true, Unique: true}, []string{"b"}},
+		{[]string{"b", "A", "a", "B"}, SortFlags{FoldCase: true}, []string{"A", "a", "B", "b"}},
+		// Каждый ключ сравнивается со своими модификаторами, при равенстве — следующий.
+		{[]string{"x 2 b", "y 10 a", "z 2 a"}, SortFlags{Keys: keys("2,2nr", "3")}, []string{"y 10 a", "z 2 a", "x 2 b"}},
+		{[]string{"b 10", "a 9", "b 9"}, SortFlags{Keys: keys("1,1", "2n")}, []string{"a 9", "b 9", "b 10"}},
+		// Ключ без модификаторов берёт глобальные флаги, ключ с модификаторами — нет.
+		{[]string{"b 10", "a 9", "b 9"}, SortFlags{Keys: keys("1,1", "2n"), Reverse: true}, []string{"b 9", "b 10", "a 9"}},
+		{[]string{"Mar x", "jan y", "Feb x"}, SortFlags{Keys: keys("2,2", "1M")}, []string{"Feb x", "Mar x", "jan y"}},
+		// Строки без нужного поля сохраняются и идут первыми.
+		{[]string{"b 2", "a", "c 1"}, SortFlags{Keys: keys("2n")}, []string{"a", "c 1", "b 2"}},
+		{[]string{"id=07;x", "id=10;a", "id=09"}, SortFlags{Keys: keys("1.4,1.5n"), Separator: ";"}, []string{"id=07;x", "id=09", "id=10;a"}},
+		{nil, SortFlags{}, []string{}},
+	}
+
+	for _, test := range tests {
+		output := sortLinesByKey(test.input, test.flags)
+		if !reflect.DeepEqual(output, test.expectedOutput) {
+			t.Errorf("For input %q with %+v, expected %q, but got %q", test.input, test.flags, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	first := write("first.txt", "pear 3\napple 10\n")
+	second := write("second.txt", "fig 1\nkiwi 2")
+	sorted := write("sorted.txt", "1\n2\n2\n3\n")
+	a1, a2 := write("a1.txt", "a b\n"), write("a2.txt", "a a\n")
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedOutput string
+		expectedStderr string
+		expectedCode   int
+	}{
+		{nil, "b\na\n", "a\nb\n", "", exitOK},
+		{[]string{"-"}, "b\r\na", "a\nb\r\n", "", exitOK},
+		{[]string{"-nrk2", first, second}, "", "apple 10\npear 3\nkiwi 2\nfig 1\n", "", exitOK},
+		{[]string{first, "-", "-k", "2", "-n"}, "plum 5\n", "pear 3\nplum 5\napple 10\n", "", exitOK},
+		{[]string{"-c", sorted}, "", "", "", exitOK},
+		{[]string{"-cu", sorted}, "", "", "sorted.txt:3: disorder: 2", exitDisorder},
+		{[]string{"-c", first}, "", "", "first.txt:2: disorder: apple 10", exitDisorder},
+		{[]string{"-c", first, second}, "", "", "extra operand", exitTrouble},
+		{[]string{"-m", second, sorted, "-"}, "0\n", "0\n1\n2\n2\n3\nfig 1\nkiwi 2\n", "", exitOK},
+		{[]string{"-mnu", sorted, "-"}, "2\n4\n", "1\n2\n3\n4\n", "", exitOK},
+		{[]string{"-m", "-u", "-k1,1", a1, a2}, "", "a b\n", "", exitOK},
+		{[]string{"-m", "-u", "-k1,1", a2, a1}, "", "a a\n", "", exitOK},
+		{[]string{"-m", first, second}, "", "fig 1\nkiwi 2\npear 3\napple 10\n", "", exitOK},
+		{[]string{"-m", "--verify", first, second}, "", "", "first.txt:2: disorder: apple 10", exitDisorder},
+		{[]string{filepath.Join(dir, "missing.txt")}, "", "", "no such file", exitTrouble},
+		{[]string{"-q"}, "", "", "invalid option -- 'q'", exitTrouble},
+		{[]string{"--help"}, "", "Usage: sort", "", exitOK},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+		if code != test.expectedCode || !strings.HasPrefix(stdout.String(), test.expectedOutput) || !strings.Contains(stderr.String(), test.expectedStderr) {
+			t.Errorf("For args %q, expected code %d, output %q and error %q, but got code %d, output %q and error %q",
+				test.args, test.expectedCode, test.expectedOutput, test.expectedStderr, code, stdout.String(), stderr.String())
+		}
+	}
+}
+
+func TestRunOutputToInput(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("c\na\nb\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-o", path, "-r", path}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected code 0, but got %d: %s", code, stderr.String())
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "c\nb\na\n" || stdout.Len() != 0 {
+		t.Errorf("expected the file to hold %q, but got %q (stdout %q)", "c\nb\na\n", data, stdout.String())
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("expected mode 0640 to be kept, but got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestRunOutputThroughSymlink(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	dir := t.TempDir()
+	path, link := filepath.Join(dir, "data.txt"), filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(path, []byte("c\na\nb\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("data.txt", link); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-o", link, link}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected code 0, but got %d: %s", code, stderr.String())
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected %s to stay a symlink, but got %v, %v", link, info, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a\nb\nc\n" {
+		t.Errorf("expected the target to hold %q, but got %q", "a\nb\nc\n", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("expected mode 0640 to be kept, but got %v", info.Mode().Perm())
+	}
+}
+
+func TestRunOutputToHardlink(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	dir := t.TempDir()
+	path, other := filepath.Join(dir, "data.txt"), filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(path, []byte("c\na\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(path, other); err != nil {
+		t.Skip("hard links are not supported:", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-o", path, path}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected code 0, but got %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(other); string(data) != "a\nb\nc\n" {
+		t.Errorf("expected the other link to hold %q, but got %q", "a\nb\nc\n", data)
+	}
+}
+
+func TestRunOutputToNewFile(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sorted.txt")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-o", path}, strings.NewReader("b\na\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected code 0, but got %d: %s", code, stderr.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a\nb\n" {
+		t.Errorf("expected the file to hold %q, but got %q", "a\nb\n", data)
+	}
+	// Права нового файла, как у любого созданного с 0666, зависят от umask.
+	reference := filepath.Join(dir, "reference.txt")
+	file, err := os.OpenFile(reference, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	got, _ := os.Stat(path)
+	want, _ := os.Stat(reference)
+	if got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("expected mode %v, but got %v", want.Mode().Perm(), got.Mode().Perm())
+	}
+
+	missing := filepath.Join(dir, "missing", "sorted.txt")
+	stderr.Reset()
+	if code := run([]string{"-o", missing}, strings.NewReader("b\na\n"), &stdout, &stderr); code != exitTrouble || !strings.Contains(stderr.String(), missing) || strings.Contains(stderr.String(), ".sort-") {
+		t.Errorf("expected code 2 and an error naming %s, but got %d: %s", missing, code, stderr.String())
+	}
+}
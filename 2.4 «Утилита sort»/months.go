@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// monthNames перечисляет названия месяцев для -M по порядку: английские и
+// русские, для русских — также в родительном падеже, как в датах ("5 мая").
+var monthNames = [12][]string{
+	{"january", "январь", "января"},
+	{"february", "февраль", "февраля"},
+	{"march", "март", "марта"},
+	{"april", "апрель", "апреля"},
+	{"may", "май", "мая"},
+	{"june", "июнь", "июня"},
+	{"july", "июль", "июля"},
+	{"august", "август", "августа"},
+	{"september", "сентябрь", "сентября"},
+	{"october", "октябрь", "октября"},
+	{"november", "ноябрь", "ноября"},
+	{"december", "декабрь", "декабря"},
+}
+
+// minMonthPrefix — самое короткое сокращение месяца: "jan", "сен".
+const minMonthPrefix = 3
+
+// monthPrefixes сопоставляет названиям месяцев и их сокращениям от
+// minMonthPrefix букв ("sept", "нояб") номер месяца с 1.
+var monthPrefixes = buildMonthPrefixes()
+
+func buildMonthPrefixes() map[string]int {
+	prefixes := make(map[string]int)
+	ambiguous := make(map[string]bool)
+	for i, names := range monthNames {
+		for _, name := range names {
+			letters := []rune(name)
+			for n := minMonthPrefix; n <= len(letters); n++ {
+				prefix := string(letters[:n])
+				if month, ok := prefixes[prefix]; ok && month != i+1 {
+					ambiguous[prefix] = true
+				}
+				prefixes[prefix] = i + 1
+			}
+		}
+	}
+	for prefix := range ambiguous {
+		delete(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// compareMonths сравнивает названия месяцев в начале строк; строки без
+// месяца идут первыми.
+func compareMonths(a, b string) int {
+	return monthNumber(a) - monthNumber(b)
+}
+
+// monthNumber возвращает номер месяца, названного первым словом s после
+// пробелов, или 0. Регистр не учитывается, а слово может быть сокращением:
+// "Jan", "  MARCH", "янв." и "Января" распознаются.
+func monthNumber(s string) int {
+	s = strings.TrimLeft(s, blanks)
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(s)
+	}
+	return monthPrefixes[strings.ToLower(s[:end])]
+}
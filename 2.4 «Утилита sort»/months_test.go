@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMonthNumber(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput int
+	}{
+		{"January", 1},
+		{"jan", 1},
+		{"  MARCH 2024", 3},
+		{"\tSept.", 9},
+		{"Dec-31", 12},
+		{"may", 5},
+		{"Январь", 1},
+		{"янв.", 1},
+		{"ФЕВР", 2},
+		{"мая", 5},
+		{"нояб", 11},
+		{"сентября", 9},
+		{"ju", 0},
+		{"ию", 0},
+		{"Smarch", 0},
+		{"janvier", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		output := monthNumber(test.input)
+		if output != test.expectedOutput {
+			t.Errorf("For input %q, expected %d, but got %d", test.input, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestMonthSortWithKeys(t *testing.T) {
+	tests := []struct {
+		input          []string
+		flags          SortFlags
+		expectedOutput []string
+	}{
+		{[]string{"Mar", "  jan", "Декабрь", "FEB", "x"}, SortFlags{Month: true}, []string{"x", "  jan", "FEB", "Mar", "Декабрь"}},
+		{[]string{"12 мая 2024", "3 янв 2024", "7 Apr 2024", "1 Sep 2024"}, SortFlags{Keys: keys("2M")}, []string{"3 янв 2024", "7 Apr 2024", "12 мая 2024", "1 Sep 2024"}},
+		// Сначала год, затем месяц, затем день, как в сортировке дат.
+		{[]string{"05;Jun;2023", "17;mar;2024", "02;Jun;2023", "09;dec;2023"}, SortFlags{Keys: keys("3,3n", "2,2M", "1,1n"), Separator: ";"}, []string{"02;Jun;2023", "05;Jun;2023", "09;dec;2023", "17;mar;2024"}},
+		{[]string{"a Jan", "b Dec", "c Jul"}, SortFlags{Keys: keys("2Mr")}, []string{"b Dec", "c Jul", "a Jan"}},
+	}
+
+	for _, test := range tests {
+		output := sortLinesByKey(test.input, test.flags)
+		if !reflect.DeepEqual(output, test.expectedOutput) {
+			t.Errorf("For input %q with %+v, expected %q, but got %q", test.input, test.flags, test.expectedOutput, output)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// decimalPoint отделяет дробную часть числа для -n и -h.
+	decimalPoint = '.'
+	// thousandsSeparator разделяет группы цифр целой части для -n: "1,234"
+	// читается как 1234.
+	thousandsSeparator = ','
+)
+
+// decimal — число в начале строки для -n: знак и цифры целой и дробной
+// частей без ведущих нулей целой части и конечных нулей дробной.
+type decimal struct {
+	negative bool
+	integer  string
+	fraction string
+}
+
+// parseDecimal разбирает число в начале строки, как GNU sort -n: пробелы,
+// необязательный минус, цифры с разделителями групп и дробная часть после
+// точки. Остаток строки игнорируется; строка без числа равна нулю.
+func parseDecimal(s string) decimal {
+	s = strings.TrimLeft(s, blanks)
+	var d decimal
+	if strings.HasPrefix(s, "-") {
+		d.negative = true
+		s = s[1:]
+	}
+
+	end, grouped := 0, false
+	for end < len(s) {
+		if isDigit(s[end]) {
+			end++
+			continue
+		}
+		if s[end] == thousandsSeparator && end > 0 && end+1 < len(s) && isDigit(s[end+1]) {
+			end, grouped = end+1, true
+			continue
+		}
+		break
+	}
+	d.integer = s[:end]
+	if grouped {
+		d.integer = strings.ReplaceAll(d.integer, string(thousandsSeparator), "")
+	}
+	d.integer = strings.TrimLeft(d.integer, "0")
+
+	if end < len(s) && s[end] == decimalPoint {
+		fraction := s[end+1:]
+		digits := 0
+		for digits < len(fraction) && isDigit(fraction[digits]) {
+			digits++
+		}
+		d.fraction = strings.TrimRight(fraction[:digits], "0")
+	}
+
+	if d.integer == "" && d.fraction == "" {
+		d.negative = false
+	}
+	return d
+}
+
+// compareNumeric сравнивает числа в начале строк (-n) точно, без перевода в
+// float64, поэтому различаются и очень длинные числа.
+func compareNumeric(a, b string) int {
+	x, y := parseDecimal(a), parseDecimal(b)
+	if x.negative != y.negative {
+		if x.negative {
+			return -1
+		}
+		return 1
+	}
+
+	result := len(x.integer) - len(y.integer)
+	if result == 0 {
+		result = strings.Compare(x.integer, y.integer)
+	}
+	if result == 0 {
+		result = strings.Compare(x.fraction, y.fraction)
+	}
+	if x.negative {
+		return -result
+	}
+	return result
+}
+
+// Классы значений для -g в порядке сортировки.
+const (
+	generalNotNumber = iota
+	generalNaN
+	generalNumber
+)
+
+// parseGeneral разбирает самое длинное вещественное число в начале строки
+// (-g): экспоненциальная запись, шестнадцатеричные числа, inf и nan, как
+// strtod. Возвращает класс значения и само значение.
+func parseGeneral(s string) (int, float64) {
+	s = strings.TrimLeft(s, blanks)
+	end := floatPrefix(s)
+	if end == 0 {
+		return generalNotNumber, 0
+	}
+	num, err := parseFloat(s[:end])
+	switch {
+	case err != nil && !isRangeError(err):
+		return generalNotNumber, 0
+	case math.IsNaN(num):
+		return generalNaN, 0
+	}
+	return generalNumber, num
+}
+
+// floatPrefix возвращает длину самого длинного начала s, которое strtod
+// принимает за число, или 0. Строка просматривается один раз, поэтому длинные
+// нечисловые хвосты вроде "1xxxx…" не замедляют сравнение.
+func floatPrefix(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	rest := strings.ToLower(s[i:])
+	switch {
+	case strings.HasPrefix(rest, "infinity"):
+		return i + len("infinity")
+	case strings.HasPrefix(rest, "inf"), strings.HasPrefix(rest, "nan"):
+		return i + 3
+	case strings.HasPrefix(rest, "0x"):
+		if end := scanMantissa(s, i+2, isHexDigit); end > i+2 {
+			return scanExponent(s, end, 'p')
+		}
+	}
+	end := scanMantissa(s, i, isDigit)
+	if end == i {
+		return 0
+	}
+	return scanExponent(s, end, 'e')
+}
+
+// scanMantissa пропускает цифры с необязательной дробной частью, начиная с
+// pos, и возвращает pos, если цифр нет совсем.
+func scanMantissa(s string, pos int, digit func(byte) bool) int {
+	end, digits := pos, 0
+	for ; end < len(s) && digit(s[end]); end++ {
+		digits++
+	}
+	if end < len(s) && s[end] == decimalPoint {
+		for end++; end < len(s) && digit(s[end]); end++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return pos
+	}
+	return end
+}
+
+// scanExponent пропускает экспоненту с буквой marker, если после неё есть
+// цифры, и возвращает конец числа.
+func scanExponent(s string, pos int, marker byte) int {
+	if pos >= len(s) || s[pos]|0x20 != marker {
+		return pos
+	}
+	end := pos + 1
+	if end < len(s) && (s[end] == '+' || s[end] == '-') {
+		end++
+	}
+	digits := end
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	if end == digits {
+		return pos
+	}
+	return end
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c|0x20 >= 'a' && c|0x20 <= 'f'
+}
+
+// parseFloat разбирает вещественное число, как strconv.ParseFloat, но, как и
+// strtod, принимает шестнадцатеричные числа без двоичной экспоненты: "0x10".
+func parseFloat(s string) (float64, error) {
+	num, err := strconv.ParseFloat(s, 64)
+	unsigned := strings.ToLower(strings.TrimLeft(s, "+-"))
+	if err != nil && strings.HasPrefix(unsigned, "0x") && !strings.Contains(unsigned, "p") {
+		return strconv.ParseFloat(s+"p0", 64)
+	}
+	return num, err
+}
+
+// isRangeError сообщает, что число вышло за пределы float64; как и strtod,
+// такое число считается равным бесконечности или нулю.
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// compareGeneral сравнивает строки как вещественные числа (-g): строки без
+// числа идут первыми, затем NaN, затем числа от -inf до +inf.
+func compareGeneral(a, b string) int {
+	xClass, x := parseGeneral(a)
+	yClass, y := parseGeneral(b)
+	if xClass != yClass {
+		return xClass - yClass
+	}
+	return compareFloats(x, y)
+}
+
+// humanSuffixes — суффиксы -h в порядке возрастания степени; строчные буквы
+// равны заглавным.
+const humanSuffixes = "KMGTPEZYRQ"
+
+// humanNumber — число с суффиксом для -h: степень суффикса (0 без суффикса)
+// и значение с учётом множителя.
+type humanNumber struct {
+	power int
+	value float64
+}
+
+// parseHuman разбирает число с необязательным суффиксом: десятичным (1.5K,
+// 2M, 3g) с множителем 1000 или двоичным (1Ki, 4MiB) с множителем 1024.
+// После суффикса допускается B, а одно B означает байты. Строка без числа
+// равна нулю.
+func parseHuman(s string) humanNumber {
+	s = strings.TrimLeft(s, blanks)
+	end := 0
+	if strings.HasPrefix(s, "-") {
+		end++
+	}
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	if end < len(s) && s[end] == decimalPoint {
+		end++
+		for end < len(s) && isDigit(s[end]) {
+			end++
+		}
+	}
+	num, err := strconv.ParseFloat(strings.TrimSuffix(s[:end], string(decimalPoint)), 64)
+	if err != nil || num == 0 {
+		return humanNumber{}
+	}
+
+	suffix := s[end:]
+	if suffix == "" {
+		return humanNumber{value: num}
+	}
+	power := strings.IndexByte(humanSuffixes, upper(suffix[0])) + 1
+	if power == 0 {
+		return humanNumber{value: num}
+	}
+	base := 1000.0
+	if len(suffix) > 1 && suffix[1] == 'i' {
+		base = 1024
+	}
+	return humanNumber{power: power, value: num * math.Pow(base, float64(power))}
+}
+
+// upper переводит латинскую строчную букву в заглавную.
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// compareHuman сравнивает числа с суффиксами (-h), как GNU sort: сначала
+// знак, затем степень суффикса, и только потом значение, поэтому "1023K"
+// из du -h меньше "1.0M" при любом множителе.
+func compareHuman(a, b string) int {
+	x, y := parseHuman(a), parseHuman(b)
+	if result := humanRank(x) - humanRank(y); result != 0 {
+		return result
+	}
+	return compareFloats(x.value, y.value)
+}
+
+// humanRank упорядочивает числа по знаку и степени суффикса: у отрицательных
+// чисел большая степень означает меньшее число.
+func humanRank(n humanNumber) int {
+	switch {
+	case n.value < 0:
+		return -n.power - 1
+	case n.value > 0:
+		return n.power + 1
+	}
+	return 0
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
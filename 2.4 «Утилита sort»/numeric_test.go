@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareNumeric(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		expectedOutput int
+	}{
+		{"2", "10", -1},
+		{"  -1.5", "-1.25", -1},
+		{"1,234", "999", 1},
+		{"1,234.5", "1234.50", 0},
+		{"12,", "12", 0},
+		{"007", "7.0", 0},
+		{"-0", "x", 0},
+		{"-.5", "0", -1},
+		{"3 apples", "3.1", -1},
+		{"12345678901234567890", "12345678901234567891", -1},
+		{"0.10", "0.09", 1},
+	}
+
+	for _, test := range tests {
+		output := compareNumeric(test.a, test.b)
+		reverse := compareNumeric(test.b, test.a)
+		if sign(output) != test.expectedOutput || sign(reverse) != -test.expectedOutput {
+			t.Errorf("For input %q and %q, expected %d, but got %d (reversed %d)", test.a, test.b, test.expectedOutput, output, reverse)
+		}
+	}
+}
+
+func TestCompareGeneral(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		expectedOutput int
+	}{
+		{"1e3", "999", 1},
+		{"1.5E-3", "0.0015", 0},
+		{"-inf", "-1e308", -1},
+		{"Infinity", "1e308", 1},
+		{"NaN", "-inf", -1},
+		{"apple", "NaN", -1},
+		{"nan", "NAN", 0},
+		{"1e", "1", 0},
+		{"0x10", "16", 0},
+		{"1e999", "inf", 0},
+		{" +2.5x", "2.5", 0},
+		{"", "apple", 0},
+		{"0x1.8p1", "3", 0},
+		{"0x", "0", 0},
+		{"1.e2", "100", 0},
+		{".5", "0.5", 0},
+		{"-.", "apple", 0},
+		{"1e+", "1", 0},
+		{"+infinityx", "inf", 0},
+		{"1" + strings.Repeat("x", 100000), "2", -1},
+	}
+
+	for _, test := range tests {
+		output := compareGeneral(test.a, test.b)
+		reverse := compareGeneral(test.b, test.a)
+		if sign(output) != test.expectedOutput || sign(reverse) != -test.expectedOutput {
+			t.Errorf("For input %q and %q, expected %d, but got %d (reversed %d)", test.a, test.b, test.expectedOutput, output, reverse)
+		}
+	}
+}
+
+func TestCompareHuman(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		expectedOutput int
+	}{
+		{"1023K", "1.0M", -1},
+		{"2k", "1K", 1},
+		{"1Ki", "1K", 1},
+		{"1KiB", "1Ki", 0},
+		{"512B", "512", 0},
+		{"1T", "999G", 1},
+		{"1E", "1P", 1},
+		{"1Mi", "1000K", 1},
+		{"-1M", "-1K", -1},
+		{"-1K", "0", -1},
+		{"0K", "0", 0},
+		{"x", "0", 0},
+		{"1.5g", "1G", 1},
+	}
+
+	for _, test := range tests {
+		output := compareHuman(test.a, test.b)
+		reverse := compareHuman(test.b, test.a)
+		if sign(output) != test.expectedOutput || sign(reverse) != -test.expectedOutput {
+			t.Errorf("For input %q and %q, expected %d, but got %d (reversed %d)", test.a, test.b, test.expectedOutput, output, reverse)
+		}
+	}
+}
+
+func TestNumericModesWithKeys(t *testing.T) {
+	tests := []struct {
+		input          []string
+		flags          SortFlags
+		expectedOutput []string
+	}{
+		{[]string{"b 1,000", "a 20", "c -3.5", "d none"}, SortFlags{Keys: keys("2n")}, []string{"c -3.5", "d none", "a 20", "b 1,000"}},
+		{[]string{"x 1e2", "y nan", "z -inf", "w text"}, SortFlags{Keys: keys("2g")}, []string{"w text", "y nan", "z -inf", "x 1e2"}},
+		{[]string{"4.0K\tdocs", "1.1M\tsrc", "512\tREADME", "2.0G\t.git"}, SortFlags{Human: true, Reverse: true}, []string{"2.0G\t.git", "1.1M\tsrc", "4.0K\tdocs", "512\tREADME"}},
+	}
+
+	for _, test := range tests {
+		output := sortLinesByKey(test.input, test.flags)
+		if !reflect.DeepEqual(output, test.expectedOutput) {
+			t.Errorf("For input %q with %+v, expected %q, but got %q", test.input, test.flags, test.expectedOutput, output)
+		}
+	}
+}
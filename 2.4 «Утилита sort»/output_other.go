@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// replaceable сообщает, что файл можно заменить переименованием. Без
+// сведений о жёстких ссылках и владельце файл надёжнее записать на месте.
+func replaceable(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// replaceable сообщает, что файл можно заменить переименованием: у него одна
+// жёсткая ссылка, и он принадлежит текущему пользователю и его группе, так
+// что новый файл не разорвёт ссылки и не сменит владельца.
+func replaceable(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return !ok || stat.Nlink == 1 && int(stat.Uid) == os.Getuid() && int(stat.Gid) == os.Getgid()
+}
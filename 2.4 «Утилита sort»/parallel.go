@@ -0,0 +1,124 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+const (
+	// maxDefaultParallel ограничивает число потоков по умолчанию, как в GNU
+	// sort: дальше выигрыш упирается в память.
+	maxDefaultParallel = 8
+	// minPartSize — меньше строк на поток сортировать параллельно невыгодно.
+	minPartSize = 1 << 12
+)
+
+// defaultParallel возвращает число потоков без --parallel.
+func defaultParallel() int {
+	return min(runtime.GOMAXPROCS(0), maxDefaultParallel)
+}
+
+// sortStable устойчиво сортирует строки на месте в workers потоков: срез
+// делится на соседние части, каждая сортируется в своей горутине, затем
+// части попарно сливаются, тоже параллельно. При слиянии из равных строк
+// первой берётся строка из левой части, поэтому результат не зависит от
+// числа потоков и совпадает с sort.SliceStable.
+func sortStable(lines []string, compare compareFunc, workers int) {
+	buffer := make([]string, len(lines))
+	parts := max(1, min(workers, len(lines)/minPartSize))
+
+	bounds := make([]int, parts+1)
+	for i := range bounds {
+		bounds[i] = i * len(lines) / parts
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < parts; i++ {
+		part, partBuffer := lines[bounds[i]:bounds[i+1]], buffer[bounds[i]:bounds[i+1]]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mergeSort(part, partBuffer, compare)
+		}()
+	}
+	wg.Wait()
+
+	src, dst := lines, buffer
+	for len(bounds) > 2 {
+		next := []int{0}
+		for i := 0; i+1 < len(bounds); i += 2 {
+			if i+2 == len(bounds) {
+				// Непарная последняя часть переносится как есть.
+				copy(dst[bounds[i]:bounds[i+1]], src[bounds[i]:bounds[i+1]])
+				next = append(next, bounds[i+1])
+				continue
+			}
+			left, mid, right := bounds[i], bounds[i+1], bounds[i+2]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mergeSorted(dst[left:right], src[left:mid], src[mid:right], compare)
+			}()
+			next = append(next, right)
+		}
+		wg.Wait()
+		bounds = next
+		src, dst = dst, src
+	}
+	if len(lines) > 0 && &src[0] != &lines[0] {
+		copy(lines, src)
+	}
+}
+
+// insertionRun — длина отрезков, которые mergeSort сортирует вставками
+// перед слиянием.
+const insertionRun = 24
+
+// mergeSort устойчиво сортирует lines восходящим слиянием через buffer той
+// же длины: O(n log n) сравнений, что важно при дорогих ключах.
+func mergeSort(lines, buffer []string, compare compareFunc) {
+	for start := 0; start < len(lines); start += insertionRun {
+		run := lines[start:min(start+insertionRun, len(lines))]
+		for i := 1; i < len(run); i++ {
+			for j := i; j > 0 && compare(run[j], run[j-1]) < 0; j-- {
+				run[j], run[j-1] = run[j-1], run[j]
+			}
+		}
+	}
+
+	src, dst := lines, buffer
+	for width := insertionRun; width < len(lines); width *= 2 {
+		for left := 0; left < len(lines); left += 2 * width {
+			mid, right := min(left+width, len(lines)), min(left+2*width, len(lines))
+			mergeSorted(dst[left:right], src[left:mid], src[mid:right], compare)
+		}
+		src, dst = dst, src
+	}
+	if len(lines) > 0 && &src[0] != &lines[0] {
+		copy(lines, src)
+	}
+}
+
+// mergeSorted сливает отсортированные left и right в dst; при равенстве
+// первой идёт строка из left. Уже упорядоченные части копируются после
+// одного сравнения, поэтому отсортированный вход обрабатывается за O(n).
+func mergeSorted(dst, left, right []string, compare compareFunc) {
+	if len(left) == 0 || len(right) == 0 || compare(right[0], left[len(left)-1]) >= 0 {
+		copy(dst[copy(dst, left):], right)
+		return
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(left) && j < len(right) {
+		if compare(right[j], left[i]) < 0 {
+			dst[k] = right[j]
+			j++
+		} else {
+			dst[k] = left[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], left[i:])
+	copy(dst[k:], right[j:])
+}
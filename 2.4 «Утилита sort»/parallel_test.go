@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// comparePrefix сравнивает только первый символ, поэтому у многих строк
+// ключи равны и видно, сохраняется ли их исходный порядок.
+func comparePrefix(a, b string) int {
+	return strings.Compare(a[:1], b[:1])
+}
+
+func TestSortStableMatchesSliceStable(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	for _, size := range []int{0, 1, minPartSize - 1, minPartSize * 3, minPartSize*7 + 5} {
+		lines := make([]string, size)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%c%06d", 'a'+random.Intn(5), i)
+		}
+
+		expectedOutput := append([]string(nil), lines...)
+		sort.SliceStable(expectedOutput, func(i, j int) bool {
+			return comparePrefix(expectedOutput[i], expectedOutput[j]) < 0
+		})
+
+		for _, workers := range []int{1, 2, 3, 4, 8, 16} {
+			output := append([]string(nil), lines...)
+			sortStable(output, comparePrefix, workers)
+			if !reflect.DeepEqual(output, expectedOutput) {
+				t.Errorf("For %d lines in %d workers, the result differs from sort.SliceStable", size, workers)
+			}
+		}
+	}
+}
+
+func TestParallelFlag(t *testing.T) {
+	lines := generateLines(minPartSize*5, 4)
+	expectedOutput := sortLinesByKey(lines, SortFlags{Keys: keys("2,2n"), Parallel: 1})
+	for _, workers := range []int{2, 5, 8} {
+		output := sortLinesByKey(lines, SortFlags{Keys: keys("2,2n"), Parallel: workers})
+		if !reflect.DeepEqual(output, expectedOutput) {
+			t.Errorf("For --parallel=%d, the result differs from a single thread", workers)
+		}
+	}
+}
+
+// benchmarkInputs — наборы строк для бенчмарков: уже отсортированные,
+// в обратном порядке и случайные.
+func benchmarkInputs(count int) map[string][]string {
+	random := rand.New(rand.NewSource(5))
+	sorted := make([]string, count)
+	for i := range sorted {
+		sorted[i] = fmt.Sprintf("line %09d", i)
+	}
+	reversed := make([]string, count)
+	for i := range reversed {
+		reversed[i] = sorted[count-1-i]
+	}
+	shuffled := append([]string(nil), sorted...)
+	random.Shuffle(count, func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return map[string][]string{"sorted": sorted, "reversed": reversed, "random": shuffled}
+}
+
+func BenchmarkSort(b *testing.B) {
+	inputs := benchmarkInputs(200_000)
+	for _, order := range []string{"sorted", "reversed", "random"} {
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/parallel=%d", order, workers), func(b *testing.B) {
+				lines := make([]string, len(inputs[order]))
+				flags := SortFlags{Parallel: workers}
+				for i := 0; i < b.N; i++ {
+					copy(lines, inputs[order])
+					sortChunk(lines, flags)
+				}
+			})
+		}
+	}
+}
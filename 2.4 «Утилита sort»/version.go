@@ -0,0 +1,153 @@
+package main
+
+// compareVersions сравнивает строки как номера версий (-V) по алгоритму
+// filevercmp из GNU: числа внутри строк сравниваются по значению без учёта
+// ведущих нулей, буквы идут раньше прочих символов, а "~" — раньше всего,
+// даже конца строки, поэтому "1.0~rc1" меньше "1.0". Суффиксы файлов вроде
+// ".tar.gz" сравниваются, только если имена без них равны. Строки, равные по
+// версии ("1.01" и "1.1"), различает последнее побайтное сравнение.
+func compareVersions(a, b string) int {
+	switch {
+	case a == "" || b == "":
+		return len(a) - len(b)
+	case a[0] == '.' && b[0] != '.':
+		return -1
+	case a[0] != '.' && b[0] == '.':
+		return 1
+	case a[0] == '.':
+		// "." идёт первым, затем "..", затем остальные скрытые файлы.
+		if result := dotRank(b) - dotRank(a); result != 0 || dotRank(a) > 0 {
+			return result
+		}
+	}
+
+	aPrefix, bPrefix := versionPrefixLen(a), versionPrefixLen(b)
+	result := compareVersionParts(a[:aPrefix], b[:bPrefix])
+	if result != 0 || aPrefix == len(a) && bPrefix == len(b) {
+		return result
+	}
+	return compareVersionParts(a, b)
+}
+
+// dotRank возвращает 2 для ".", 1 для ".." и 0 для остальных строк.
+func dotRank(s string) int {
+	switch s {
+	case ".":
+		return 2
+	case "..":
+		return 1
+	}
+	return 0
+}
+
+// versionPrefixLen возвращает длину s без суффиксов файла — конечной
+// последовательности вида (\.[A-Za-z~][A-Za-z0-9~]*)*.
+func versionPrefixLen(s string) int {
+	prefix := 0
+	for i := 0; i < len(s); {
+		i++
+		prefix = i
+		for i+1 < len(s) && s[i] == '.' && (isLetter(s[i+1]) || s[i+1] == '~') {
+			for i += 2; i < len(s) && (isLetter(s[i]) || isDigit(s[i]) || s[i] == '~'); i++ {
+			}
+		}
+	}
+	return prefix
+}
+
+// compareVersionParts сравнивает строки, чередуя нечисловые части (по
+// versionOrder) и числа (по значению).
+func compareVersionParts(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isDigit(a[i]) || j < len(b) && !isDigit(b[j]) {
+			if x, y := versionOrder(a, i), versionOrder(b, j); x != y {
+				return x - y
+			}
+			i, j = i+1, j+1
+		}
+
+		var result int
+		if result, i, j = compareDigitRuns(a, b, i, j); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// versionOrder задаёт порядок символа на позиции pos для -V: "~" < конец
+// строки < цифра < буква < прочие символы.
+func versionOrder(s string, pos int) int {
+	if pos >= len(s) {
+		return -1
+	}
+	switch c := s[pos]; {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -2
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareNatural сравнивает строки в естественном порядке (--natural-sort):
+// последовательности цифр сравниваются как числа ("build-9" < "build-10"),
+// остальные символы — побайтно. Числа, равные по значению ("007" и "7"),
+// различает последнее побайтное сравнение.
+func compareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return int(a[i]) - int(b[j])
+			}
+			i, j = i+1, j+1
+			continue
+		}
+
+		var result int
+		if result, i, j = compareDigitRuns(a, b, i, j); result != 0 {
+			return result
+		}
+	}
+	return (len(a) - i) - (len(b) - j)
+}
+
+// compareDigitRuns сравнивает по значению числа, которые начинаются в a с i и
+// в b с j, и возвращает результат и позиции после чисел. Ведущие нули
+// пропускаются, поэтому длинное число больше короткого, а при равной длине
+// решает первая различающаяся цифра.
+func compareDigitRuns(a, b string, i, j int) (int, int, int) {
+	for i < len(a) && a[i] == '0' {
+		i++
+	}
+	for j < len(b) && b[j] == '0' {
+		j++
+	}
+
+	firstDiff := 0
+	for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+		if firstDiff == 0 {
+			firstDiff = int(a[i]) - int(b[j])
+		}
+		i, j = i+1, j+1
+	}
+	switch {
+	case i < len(a) && isDigit(a[i]):
+		return 1, i, j
+	case j < len(b) && isDigit(b[j]):
+		return -1, i, j
+	}
+	return firstDiff, i, j
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		expectedOutput int
+	}{
+		{"build-9", "build-10", -1},
+		{"v1.10.2", "v1.9.12", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.01", "1.1", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0-", -1},
+		{"hello-8.txt", "hello-8.2.txt", -1},
+		{"app-1.2.tar.gz", "app-1.2.zip", -1},
+		{"", "a", -1},
+		{".", "..", -1},
+		{"..", ".hidden", -1},
+		{".hidden", "a", -1},
+	}
+
+	for _, test := range tests {
+		output := compareVersions(test.a, test.b)
+		reverse := compareVersions(test.b, test.a)
+		if sign(output) != test.expectedOutput || sign(reverse) != -test.expectedOutput {
+			t.Errorf("For input %q and %q, expected %d, but got %d (reversed %d)", test.a, test.b, test.expectedOutput, output, reverse)
+		}
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		expectedOutput int
+	}{
+		{"build-9", "build-10", -1},
+		{"img12.png", "img2.png", 1},
+		{"007", "7", 0},
+		{"a007b", "a7c", -1},
+		{"x10", "x10y", -1},
+		{"Z1", "a1", -1},
+		{"1.0-rc1", "1.0", 1},
+		{"", "", 0},
+	}
+
+	for _, test := range tests {
+		output := compareNatural(test.a, test.b)
+		reverse := compareNatural(test.b, test.a)
+		if sign(output) != test.expectedOutput || sign(reverse) != -test.expectedOutput {
+			t.Errorf("For input %q and %q, expected %d, but got %d (reversed %d)", test.a, test.b, test.expectedOutput, output, reverse)
+		}
+	}
+}
+
+func TestVersionSortWithKeys(t *testing.T) {
+	tests := []struct {
+		input          []string
+		flags          SortFlags
+		expectedOutput []string
+	}{
+		{[]string{"v1.10.2", "v1.9.0", "v1.10.0~beta", "v1.10.0"}, SortFlags{Version: true}, []string{"v1.9.0", "v1.10.0~beta", "v1.10.0", "v1.10.2"}},
+		// Равные по версии строки упорядочивает побайтное сравнение, а -u оставляет
+		// первую во входе.
+		{[]string{"1.1", "1.01", "1.001"}, SortFlags{Version: true}, []string{"1.001", "1.01", "1.1"}},
+		{[]string{"1.1", "1.01"}, SortFlags{Version: true, Unique: true}, []string{"1.1"}},
+		{[]string{"b build-10", "a build-9", "c build-9"}, SortFlags{Keys: keys("2V", "1,1r")}, []string{"c build-9", "a build-9", "b build-10"}},
+		{[]string{"x;file10", "y;file2", "z;file1"}, SortFlags{Keys: keys("2N"), Separator: ";"}, []string{"z;file1", "y;file2", "x;file10"}},
+		{[]string{"build-10", "build-9", "build-100"}, SortFlags{Natural: true, Reverse: true}, []string{"build-100", "build-10", "build-9"}},
+	}
+
+	for _, test := range tests {
+		output := sortLinesByKey(test.input, test.flags)
+		if !reflect.DeepEqual(output, test.expectedOutput) {
+			t.Errorf("For input %q with %+v, expected %q, but got %q", test.input, test.flags, test.expectedOutput, output)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}